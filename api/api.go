@@ -0,0 +1,329 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/dbrouter"
+	"belajar-golang-gorm/pagination"
+	"belajar-golang-gorm/querydsl"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBadRequest = errors.New("bad request")
+	ErrConflict   = errors.New("conflict")
+)
+
+// ValidationError dikembalikan dengan status 422, Fields berisi pesan per kolom
+type ValidationError struct {
+	Message string
+	Fields  map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	var fields []string
+	for field, message := range e.Fields {
+		fields = append(fields, field+": "+message)
+	}
+	sort.Strings(fields)
+	return e.Message + " (" + strings.Join(fields, ", ") + ")"
+}
+
+type Server struct {
+	db        *gorm.DB
+	mux       *http.ServeMux
+	endpoints []Endpoint
+	document  *Document
+	hidden    map[string]map[string]bool
+}
+
+// Endpoint adalah satu resource REST, implementasinya Resource[T]
+type Endpoint interface {
+	Info() EndpointInfo
+	register(s *Server) error
+}
+
+func New(db *gorm.DB, endpoints ...Endpoint) (*Server, error) {
+	if len(endpoints) == 0 {
+		endpoints = DefaultEndpoints()
+	}
+
+	s := &Server{
+		db:        db,
+		mux:       http.NewServeMux(),
+		endpoints: endpoints,
+		hidden:    map[string]map[string]bool{},
+	}
+	for _, endpoint := range endpoints {
+		if err := endpoint.register(s); err != nil {
+			return nil, err
+		}
+	}
+
+	document, err := GenerateOpenAPI(db, endpoints, DocumentModels...)
+	if err != nil {
+		return nil, err
+	}
+	s.document = document
+	s.mux.HandleFunc("GET /openapi.json", s.openAPI)
+
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "route not found", nil)
+	})
+
+	return s, nil
+}
+
+// ServeHTTP menjadikan setiap request satu sesi dbrouter, setelah ada operasi tulis
+// query baca di request yang sama dibaca dari primary
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r.WithContext(dbrouter.Session(r.Context())))
+}
+
+func (s *Server) Endpoints() []Endpoint {
+	return s.endpoints
+}
+
+// Document adalah spesifikasi OpenAPI yang juga disajikan di GET /openapi.json
+func (s *Server) Document() *Document {
+	return s.document
+}
+
+// DefaultEndpoints berisi resource users, wallets, addresses, products dan todos
+func DefaultEndpoints() []Endpoint {
+	return []Endpoint{
+		&Resource[belajargolanggorm.User]{
+			Path:     "users",
+			Hidden:   []string{"password"},
+			ReadOnly: []string{"email_verified_at"},
+			// email diganti lewat ChangeUserEmail supaya verifikasi ikut direset
+			CreateOnly: []string{"email"},
+			Validate: func(user *belajargolanggorm.User) error {
+				if user.Email == nil {
+					return nil
+				}
+				email, err := belajargolanggorm.NormalizeEmail(*user.Email)
+				if err != nil {
+					return &ValidationError{Message: "invalid fields", Fields: map[string]string{"email": err.Error()}}
+				}
+				user.Email = &email
+				return nil
+			},
+			Actions: []Action[belajargolanggorm.User]{changeEmailAction},
+		},
+		// saldo hanya berubah lewat action yang memakai CreditWallet, DebitWallet dan TransferBalance
+		&Resource[belajargolanggorm.Wallet]{
+			Path:       "wallets",
+			ReadOnly:   []string{"balance"},
+			CreateOnly: []string{"user_id"},
+			Actions:    []Action[belajargolanggorm.Wallet]{creditAction, debitAction, transferAction},
+		},
+		&Resource[belajargolanggorm.Address]{Path: "addresses"},
+		// like_count dijaga oleh Likes, stock oleh Checkout, price lewat ChangeProductPrice
+		&Resource[belajargolanggorm.Product]{
+			Path:       "products",
+			ReadOnly:   []string{"like_count"},
+			CreateOnly: []string{"price", "stock"},
+			Actions:    []Action[belajargolanggorm.Product]{changePriceAction},
+		},
+		&Resource[belajargolanggorm.Todo]{Path: "todos"},
+	}
+}
+
+var changeEmailAction = Action[belajargolanggorm.User]{
+	Name:    "email",
+	Summary: "Change user email, email verification is reset",
+	Request: &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"email": {Type: "string", MaxLength: 100}},
+		Required:   []string{"email"},
+	},
+	Run: func(db *gorm.DB, user *belajargolanggorm.User, body []byte) error {
+		var input struct {
+			Email string `json:"email"`
+		}
+		if err := DecodeAction(body, &input); err != nil {
+			return err
+		}
+		return belajargolanggorm.ChangeUserEmail(db, user.ID, input.Email)
+	},
+}
+
+var changePriceAction = Action[belajargolanggorm.Product]{
+	Name:    "price",
+	Summary: "Change product price, effective_from in the future schedules the price",
+	Request: &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"price":          {Type: "integer", Format: "int64"},
+			"effective_from": {Type: "string", Format: "date-time"},
+		},
+		Required: []string{"price"},
+	},
+	Run: func(db *gorm.DB, product *belajargolanggorm.Product, body []byte) error {
+		var input struct {
+			Price         *int64     `json:"price"`
+			EffectiveFrom *time.Time `json:"effective_from"`
+		}
+		if err := DecodeAction(body, &input); err != nil {
+			return err
+		}
+		if input.Price == nil || *input.Price < 0 {
+			return &ValidationError{Message: "invalid fields", Fields: map[string]string{"price": "must be a non-negative integer"}}
+		}
+
+		effectiveFrom := time.Now()
+		if input.EffectiveFrom != nil {
+			effectiveFrom = *input.EffectiveFrom
+		}
+		return belajargolanggorm.ChangeProductPrice(db, product.ID, *input.Price, effectiveFrom)
+	},
+}
+
+var amountSchema = &Schema{
+	Type:       "object",
+	Properties: map[string]*Schema{"amount": {Type: "number", Format: "double"}},
+	Required:   []string{"amount"},
+}
+
+var creditAction = Action[belajargolanggorm.Wallet]{
+	Name:    "credit",
+	Summary: "Add amount to wallet balance",
+	Request: amountSchema,
+	Run: func(db *gorm.DB, wallet *belajargolanggorm.Wallet, body []byte) error {
+		amount, err := decodeAmount(body)
+		if err != nil {
+			return err
+		}
+		_, err = belajargolanggorm.CreditWallet(db, wallet.UserId, amount)
+		return err
+	},
+}
+
+var debitAction = Action[belajargolanggorm.Wallet]{
+	Name:    "debit",
+	Summary: "Subtract amount from wallet balance, fails when the balance is insufficient",
+	Request: amountSchema,
+	Run: func(db *gorm.DB, wallet *belajargolanggorm.Wallet, body []byte) error {
+		amount, err := decodeAmount(body)
+		if err != nil {
+			return err
+		}
+		_, err = belajargolanggorm.DebitWallet(db, wallet.UserId, amount)
+		return err
+	},
+}
+
+var transferAction = Action[belajargolanggorm.Wallet]{
+	Name:    "transfer",
+	Summary: "Move amount to the wallet of another user",
+	Request: &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"to_user_id": {Type: "integer", Format: "int64"},
+			"amount":     {Type: "number", Format: "double"},
+		},
+		Required: []string{"to_user_id", "amount"},
+	},
+	Run: func(db *gorm.DB, wallet *belajargolanggorm.Wallet, body []byte) error {
+		var input struct {
+			ToUserId *int    `json:"to_user_id"`
+			Amount   float64 `json:"amount"`
+		}
+		if err := DecodeAction(body, &input); err != nil {
+			return err
+		}
+		if input.ToUserId == nil {
+			return &ValidationError{Message: "invalid fields", Fields: map[string]string{"to_user_id": "is required"}}
+		}
+		if !(input.Amount > 0) {
+			return &ValidationError{Message: "invalid fields", Fields: map[string]string{"amount": belajargolanggorm.ErrInvalidAmount.Error()}}
+		}
+		if *input.ToUserId == wallet.UserId {
+			return &ValidationError{Message: "invalid fields", Fields: map[string]string{"to_user_id": belajargolanggorm.ErrSameWallet.Error()}}
+		}
+		_, _, err := belajargolanggorm.TransferBalance(db, wallet.UserId, *input.ToUserId, input.Amount)
+		return err
+	},
+}
+
+func decodeAmount(body []byte) (float64, error) {
+	var input struct {
+		Amount float64 `json:"amount"`
+	}
+	if err := DecodeAction(body, &input); err != nil {
+		return 0, err
+	}
+	if !(input.Amount > 0) {
+		return 0, &ValidationError{Message: "invalid fields", Fields: map[string]string{"amount": belajargolanggorm.ErrInvalidAmount.Error()}}
+	}
+	return input.Amount, nil
+}
+
+type envelope struct {
+	Data  interface{} `json:"data,omitempty"`
+	Meta  *meta       `json:"meta,omitempty"`
+	Error *errorBody  `json:"error,omitempty"`
+}
+
+type meta struct {
+	Page       int    `json:"page,omitempty"`
+	Size       int    `json:"size"`
+	Total      int64  `json:"total"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type errorBody struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string, fields map[string]string) {
+	writeJSON(w, status, envelope{Error: &errorBody{Code: code, Message: message, Fields: fields}})
+}
+
+// writeFailure memetakan error dari GORM dan package lain ke status HTTP
+func (s *Server) writeFailure(w http.ResponseWriter, err error) {
+	if translator, ok := s.db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+
+	var validation *ValidationError
+	switch {
+	case errors.As(err, &validation):
+		writeError(w, http.StatusUnprocessableEntity, "validation_failed", validation.Message, validation.Fields)
+	case errors.Is(err, belajargolanggorm.ErrInvalidEmail):
+		writeError(w, http.StatusUnprocessableEntity, "validation_failed", "invalid fields", map[string]string{"email": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		writeError(w, http.StatusNotFound, "not_found", "record not found", nil)
+	case errors.Is(err, belajargolanggorm.ErrWalletNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error(), nil)
+	case errors.Is(err, belajargolanggorm.ErrInsufficientBalance):
+		writeError(w, http.StatusConflict, "conflict", err.Error(), nil)
+	case errors.Is(err, gorm.ErrDuplicatedKey), errors.Is(err, gorm.ErrForeignKeyViolated), errors.Is(err, ErrConflict):
+		writeError(w, http.StatusConflict, "conflict", err.Error(), nil)
+	case errors.Is(err, ErrBadRequest), errors.Is(err, querydsl.ErrInvalidQuery), errors.Is(err, pagination.ErrInvalidCursor):
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error(), nil)
+	default:
+		writeError(w, http.StatusInternalServerError, "internal", "internal server error", nil)
+	}
+}
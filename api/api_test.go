@@ -0,0 +1,385 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/api"
+	"belajar-golang-gorm/dbrouter"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type response struct {
+	Status int
+	Data   json.RawMessage `json:"data"`
+	Meta   struct {
+		Page       int    `json:"page"`
+		Size       int    `json:"size"`
+		Total      int64  `json:"total"`
+		TotalPages int    `json:"total_pages"`
+		NextCursor string `json:"next_cursor"`
+		PrevCursor string `json:"prev_cursor"`
+	} `json:"meta"`
+	Error struct {
+		Code    string            `json:"code"`
+		Message string            `json:"message"`
+		Fields  map[string]string `json:"fields"`
+	} `json:"error"`
+}
+
+func newServer(t *testing.T) (*gorm.DB, http.Handler) {
+	db := testdb.Open(t)
+	server, err := api.New(db)
+	assert.Nil(t, err)
+	return db, server
+}
+
+func call(t *testing.T, handler http.Handler, method string, target string, body string) response {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	result := response{Status: recorder.Code}
+	if recorder.Body.Len() > 0 {
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		err := json.Unmarshal(recorder.Body.Bytes(), &result)
+		assert.Nil(t, err)
+	}
+	return result
+}
+
+func object(t *testing.T, raw json.RawMessage) map[string]interface{} {
+	var data map[string]interface{}
+	err := json.Unmarshal(raw, &data)
+	assert.Nil(t, err)
+	return data
+}
+
+func list(t *testing.T, raw json.RawMessage) []map[string]interface{} {
+	var data []map[string]interface{}
+	err := json.Unmarshal(raw, &data)
+	assert.Nil(t, err)
+	return data
+}
+
+func ids(items []map[string]interface{}) []int {
+	var result []int
+	for _, item := range items {
+		result = append(result, int(item["id"].(float64)))
+	}
+	return result
+}
+
+func createUsers(t *testing.T, db *gorm.DB, count int) {
+	var users []belajargolanggorm.User
+	for i := 1; i <= count; i++ {
+		users = append(users, belajargolanggorm.User{
+			ID:       i,
+			Password: "rahasia",
+			Name:     belajargolanggorm.Name{FirstName: "User " + strconv.Itoa(i)},
+		})
+	}
+	err := db.Create(&users).Error
+	assert.Nil(t, err)
+}
+
+func TestCreateAndGetUser(t *testing.T) {
+	_, server := newServer(t)
+
+	res := call(t, server, "POST", "/users", `{"id": 1, "first_name": "Eko", "last_name": "Khannedy", "password": "rahasia", "email": " Eko@Example.COM "}`)
+	assert.Equal(t, http.StatusCreated, res.Status)
+
+	user := object(t, res.Data)
+	assert.Equal(t, "Eko", user["first_name"])
+	assert.Equal(t, "eko@example.com", user["email"])
+	assert.NotContains(t, user, "password")
+	assert.NotContains(t, user, "information")
+	assert.NotContains(t, user, "wallet")
+
+	res = call(t, server, "GET", "/users/1", "")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "Khannedy", object(t, res.Data)["last_name"])
+}
+
+func TestErrorEnvelopes(t *testing.T) {
+	db, server := newServer(t)
+	createUsers(t, db, 1)
+
+	res := call(t, server, "GET", "/users/99", "")
+	assert.Equal(t, http.StatusNotFound, res.Status)
+	assert.Equal(t, "not_found", res.Error.Code)
+
+	res = call(t, server, "GET", "/orders", "")
+	assert.Equal(t, http.StatusNotFound, res.Status)
+	assert.Equal(t, "not_found", res.Error.Code)
+
+	res = call(t, server, "GET", "/users/satu", "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+	assert.Equal(t, "bad_request", res.Error.Code)
+
+	res = call(t, server, "POST", "/users", `{"id": 1, "first_name": "Duplikat"}`)
+	assert.Equal(t, http.StatusConflict, res.Status)
+	assert.Equal(t, "conflict", res.Error.Code)
+
+	res = call(t, server, "POST", "/users", `{"id": "dua", "nickname": "x", "created_at": "2025-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+	assert.Equal(t, "validation_failed", res.Error.Code)
+	assert.Equal(t, "must be an integer", res.Error.Fields["id"])
+	assert.Equal(t, "unknown field", res.Error.Fields["nickname"])
+	assert.Equal(t, "field is read-only", res.Error.Fields["created_at"])
+
+	res = call(t, server, "POST", "/users", `{"id": 2, "email": "bukan email"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+	assert.Contains(t, res.Error.Fields, "email")
+
+	// id punya tag <-:create, jadi tidak bisa diubah lewat PATCH
+	res = call(t, server, "PATCH", "/users/1", `{"id": 5}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+	assert.Equal(t, "field is read-only", res.Error.Fields["id"])
+
+	res = call(t, server, "POST", "/users", `[1, 2]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+}
+
+func TestListFilterSortPaginate(t *testing.T) {
+	db, server := newServer(t)
+	createUsers(t, db, 15)
+
+	res := call(t, server, "GET", "/users?filter[first_name][like]=User+1&sort=-id&size=3&page=2", "")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, int64(7), res.Meta.Total)
+	assert.Equal(t, 3, res.Meta.TotalPages)
+	assert.Equal(t, []int{12, 11, 10}, ids(list(t, res.Data)))
+
+	res = call(t, server, "GET", "/users?filter[password]=rahasia", "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+
+	// cursor kosong berarti halaman pertama keyset pagination
+	res = call(t, server, "GET", "/users?cursor=&size=10", "")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, 10, len(list(t, res.Data)))
+	assert.NotEmpty(t, res.Meta.NextCursor)
+
+	res = call(t, server, "GET", "/users?size=10&cursor="+res.Meta.NextCursor, "")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, []int{11, 12, 13, 14, 15}, ids(list(t, res.Data)))
+	assert.Empty(t, res.Meta.NextCursor)
+
+	res = call(t, server, "GET", "/users?cursor=rusak", "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+
+	res = call(t, server, "GET", "/users?page=dua", "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+}
+
+func TestInclude(t *testing.T) {
+	db, server := newServer(t)
+	createUsers(t, db, 2)
+	assert.Nil(t, db.Create(&belajargolanggorm.Wallet{ID: "w1", UserId: 1, Balance: 1000}).Error)
+	assert.Nil(t, db.Create(&[]belajargolanggorm.Address{
+		{UserId: 1, Address: "Jalan A"},
+		{UserId: 1, Address: "Jalan B"},
+	}).Error)
+
+	res := call(t, server, "GET", "/users/1?include=wallet,addresses", "")
+	assert.Equal(t, http.StatusOK, res.Status)
+	user := object(t, res.Data)
+	assert.Equal(t, float64(1000), user["wallet"].(map[string]interface{})["balance"])
+	assert.Equal(t, 2, len(user["addresses"].([]interface{})))
+
+	res = call(t, server, "GET", "/users?include=wallet&sort=id", "")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, int64(2), res.Meta.Total)
+	users := list(t, res.Data)
+	assert.Equal(t, "w1", users[0]["wallet"].(map[string]interface{})["id"])
+	assert.Nil(t, users[1]["wallet"])
+
+	// include bertingkat, password user tetap disembunyikan
+	res = call(t, server, "GET", "/addresses?include=user.wallet", "")
+	assert.Equal(t, http.StatusOK, res.Status)
+	owner := list(t, res.Data)[0]["user"].(map[string]interface{})
+	assert.Equal(t, "User 1", owner["first_name"])
+	assert.NotContains(t, owner, "password")
+	assert.Equal(t, "w1", owner["wallet"].(map[string]interface{})["id"])
+
+	res = call(t, server, "GET", "/users/1?include=password", "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	_, server := newServer(t)
+
+	res := call(t, server, "POST", "/products", `{"id": 1, "name": "Kopi", "price": 25000, "stock": 10}`)
+	assert.Equal(t, http.StatusCreated, res.Status)
+
+	// price dan stock hanya bisa diisi saat create
+	res = call(t, server, "PATCH", "/products/1", `{"price": 0, "stock": 99}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+	assert.Equal(t, "field is read-only", res.Error.Fields["price"])
+	assert.Equal(t, "field is read-only", res.Error.Fields["stock"])
+
+	// nilai 0 tetap disimpan
+	res = call(t, server, "PATCH", "/products/1", `{"name": ""}`)
+	assert.Equal(t, http.StatusOK, res.Status)
+	res = call(t, server, "GET", "/products/1", "")
+	product := object(t, res.Data)
+	assert.Equal(t, "", product["name"])
+	assert.Equal(t, float64(25000), product["price"])
+
+	res = call(t, server, "PATCH", "/products/1", `{"like_count": 100}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+
+	// saldo dan pemilik wallet tidak bisa diubah langsung
+	res = call(t, server, "POST", "/wallets", `{"id": "wallet-1", "user_id": 1, "balance": 500}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+	res = call(t, server, "POST", "/wallets", `{"id": "wallet-1", "user_id": 1}`)
+	assert.Equal(t, http.StatusCreated, res.Status)
+	res = call(t, server, "PATCH", "/wallets/wallet-1", `{"balance": -750.5}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+	res = call(t, server, "PATCH", "/wallets/wallet-1", `{"user_id": 2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+
+	res = call(t, server, "POST", "/todos", `{"user_id": 1, "title": "Belajar", "description": "GORM"}`)
+	assert.Equal(t, http.StatusCreated, res.Status)
+	todo := object(t, res.Data)
+	id := strconv.Itoa(int(todo["id"].(float64)))
+	assert.Nil(t, todo["deleted_at"])
+
+	res = call(t, server, "DELETE", "/todos/"+id, "")
+	assert.Equal(t, http.StatusNoContent, res.Status)
+	res = call(t, server, "GET", "/todos/"+id, "")
+	assert.Equal(t, http.StatusNotFound, res.Status)
+	res = call(t, server, "DELETE", "/todos/"+id, "")
+	assert.Equal(t, http.StatusNotFound, res.Status)
+}
+
+func TestChangeActions(t *testing.T) {
+	db, server := newServer(t)
+
+	res := call(t, server, "POST", "/users", `{"id": 1, "first_name": "Eko", "email": "eko@example.com"}`)
+	assert.Equal(t, http.StatusCreated, res.Status)
+	assert.Nil(t, db.Model(&belajargolanggorm.User{}).Where("id = ?", 1).Update("email_verified_at", time.Now()).Error)
+
+	res = call(t, server, "PATCH", "/users/1", `{"email": "baru@example.com"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+	assert.Equal(t, "field is read-only", res.Error.Fields["email"])
+
+	// ganti email lewat action, status verifikasi ikut direset
+	res = call(t, server, "POST", "/users/1/email", `{"email": " Baru@Example.com "}`)
+	assert.Equal(t, http.StatusOK, res.Status)
+	user := object(t, res.Data)
+	assert.Equal(t, "baru@example.com", user["email"])
+	assert.Nil(t, user["email_verified_at"])
+
+	res = call(t, server, "POST", "/users/1/email", `{"email": "bukan email"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+
+	res = call(t, server, "POST", "/products", `{"id": 1, "name": "Kopi", "price": 25000, "stock": 10}`)
+	assert.Equal(t, http.StatusCreated, res.Status)
+
+	res = call(t, server, "POST", "/products/1/price", `{"price": 30000}`)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, float64(30000), object(t, res.Data)["price"])
+
+	prices, err := belajargolanggorm.ProductPriceHistory(db, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(prices))
+
+	res = call(t, server, "POST", "/products/1/price", `{"harga": 1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+	res = call(t, server, "POST", "/products/9/price", `{"price": 1}`)
+	assert.Equal(t, http.StatusNotFound, res.Status)
+}
+
+func TestWalletActions(t *testing.T) {
+	db, server := newServer(t)
+	assert.Nil(t, db.Create(&[]belajargolanggorm.User{{ID: 1, Password: "rahasia"}, {ID: 2, Password: "rahasia"}}).Error)
+	assert.Nil(t, db.Create(&[]belajargolanggorm.Wallet{{ID: "w1", UserId: 1}, {ID: "w2", UserId: 2}}).Error)
+
+	res := call(t, server, "POST", "/wallets/w1/credit", `{"amount": 1000}`)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, float64(1000), object(t, res.Data)["balance"])
+
+	res = call(t, server, "POST", "/wallets/w1/debit", `{"amount": 250.5}`)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, 749.5, object(t, res.Data)["balance"])
+
+	res = call(t, server, "POST", "/wallets/w1/debit", `{"amount": 5000}`)
+	assert.Equal(t, http.StatusConflict, res.Status)
+	res = call(t, server, "POST", "/wallets/w1/credit", `{"amount": -10}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+
+	res = call(t, server, "POST", "/wallets/w1/transfer", `{"to_user_id": 2, "amount": 500}`)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, 249.5, object(t, res.Data)["balance"])
+	res = call(t, server, "GET", "/wallets/w2", "")
+	assert.Equal(t, float64(500), object(t, res.Data)["balance"])
+
+	res = call(t, server, "POST", "/wallets/w1/transfer", `{"to_user_id": 1, "amount": 1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Status)
+	res = call(t, server, "POST", "/wallets/w1/transfer", `{"to_user_id": 9, "amount": 1}`)
+	assert.Equal(t, http.StatusNotFound, res.Status)
+}
+
+func TestReadAfterWriteUsesPrimary(t *testing.T) {
+	db := testdb.Open(t)
+	email := "lama@example.com"
+	assert.Nil(t, db.Create(&belajargolanggorm.User{ID: 1, Password: "rahasia", Email: &email}).Error)
+
+	// replica tidak pernah menerima perubahan dari primary, jadi selalu tertinggal
+	path := filepath.Join(t.TempDir(), "replica.db")
+	replica, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.Nil(t, err)
+	assert.Nil(t, replica.AutoMigrate(testdb.Models...))
+	assert.Nil(t, replica.Create(&belajargolanggorm.User{ID: 1, Password: "rahasia", Email: &email}).Error)
+	t.Cleanup(func() {
+		sqlDB, _ := replica.DB()
+		_ = sqlDB.Close()
+	})
+
+	router := dbrouter.New(dbrouter.Options{}, sqlite.Open(path))
+	assert.Nil(t, db.Use(router))
+	t.Cleanup(func() { _ = router.Close() })
+
+	server, err := api.New(db)
+	assert.Nil(t, err)
+
+	// response dimuat ulang setelah update, di request yang sama harus dibaca dari primary
+	res := call(t, server, "POST", "/users/1/email", `{"email": "baru@example.com"}`)
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "baru@example.com", object(t, res.Data)["email"])
+
+	// request baru tanpa operasi tulis kembali membaca replica
+	res = call(t, server, "GET", "/users/1", "")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, "lama@example.com", object(t, res.Data)["email"])
+}
+
+func TestNamedScopeParameter(t *testing.T) {
+	db, server := newServer(t)
+	assert.Nil(t, db.Create(&[]belajargolanggorm.Wallet{
+		{ID: "w1", UserId: 1, Balance: 0},
+		{ID: "w2", UserId: 2, Balance: 5000000},
+	}).Error)
+
+	res := call(t, server, "GET", "/wallets?scope=sultan", "")
+	assert.Equal(t, http.StatusOK, res.Status)
+	wallets := list(t, res.Data)
+	assert.Equal(t, 1, len(wallets))
+	assert.Equal(t, "w2", wallets[0]["id"])
+
+	res = call(t, server, "GET", "/wallets?scope=kaya", "")
+	assert.Equal(t, http.StatusBadRequest, res.Status)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// JSON memakai nama kolom database (first_name, user_id, ...) supaya sama dengan
+// parameter filter dan sort di querydsl, field embedded seperti Name ikut diratakan
+
+func (s *Server) encode(ctx context.Context, sch *schema.Schema, value reflect.Value, includes []string) map[string]interface{} {
+	value = reflect.Indirect(value)
+	output := map[string]interface{}{}
+
+	hidden := s.hidden[sch.Table]
+	for _, field := range sch.Fields {
+		if !Exposed(field) || hidden[field.DBName] {
+			continue
+		}
+		fieldValue, _ := field.ValueOf(ctx, value)
+		output[field.DBName] = fieldValue
+	}
+
+	for name, nested := range groupIncludes(includes) {
+		relationship := relationByName(sch, name)
+		if relationship == nil {
+			continue
+		}
+
+		relationValue := reflect.Indirect(relationship.Field.ReflectValueOf(ctx, value))
+		switch {
+		case relationValue.Kind() == reflect.Slice:
+			items := make([]map[string]interface{}, 0, relationValue.Len())
+			for i := 0; i < relationValue.Len(); i++ {
+				items = append(items, s.encode(ctx, relationship.FieldSchema, relationValue.Index(i), nested))
+			}
+			output[name] = items
+		case relationValue.IsValid() && !relationValue.IsZero():
+			// relasi struct non-pointer (User.Wallet) yang tidak ditemukan bernilai zero
+			output[name] = s.encode(ctx, relationship.FieldSchema, relationValue, nested)
+		default:
+			output[name] = nil
+		}
+	}
+
+	return output
+}
+
+// Exposed menentukan field yang punya kolom dan boleh dibaca,
+// field dengan tag gorm:"-" seperti User.Information tidak ikut
+func Exposed(field *schema.Field) bool {
+	return field.DBName != "" && field.Readable
+}
+
+// Writable menentukan field yang boleh diisi client saat create atau update,
+// timestamp otomatis, soft delete dan field read-only seperti <-:create ditolak
+func Writable(field *schema.Field, create bool) bool {
+	if !Exposed(field) || field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 {
+		return false
+	}
+	if field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+		return false
+	}
+	if create {
+		return field.Creatable
+	}
+	return field.Updatable && !field.PrimaryKey
+}
+
+// decode mengisi model dari body JSON dan mengembalikan kolom yang dikirim client
+func (s *Server) decode(ctx context.Context, sch *schema.Schema, body []byte, model reflect.Value, create bool, readOnly map[string]bool) ([]string, error) {
+	var input map[string]json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if err := decoder.Decode(&input); err != nil || input == nil {
+		return nil, &ValidationError{Message: "request body must be a JSON object"}
+	}
+
+	validation := &ValidationError{Message: "invalid fields", Fields: map[string]string{}}
+	var columns []string
+	for key, raw := range input {
+		field := sch.FieldsByDBName[key]
+		if field == nil || !Exposed(field) {
+			validation.Fields[key] = "unknown field"
+			continue
+		}
+		if !Writable(field, create) || readOnly[key] {
+			validation.Fields[key] = "field is read-only"
+			continue
+		}
+
+		target := reflect.New(field.FieldType)
+		if err := json.Unmarshal(raw, target.Interface()); err != nil {
+			validation.Fields[key] = fmt.Sprintf("must be %s", jsonType(field.FieldType))
+			continue
+		}
+		if err := field.Set(ctx, model, target.Elem().Interface()); err != nil {
+			validation.Fields[key] = err.Error()
+			continue
+		}
+		columns = append(columns, key)
+	}
+
+	if len(validation.Fields) > 0 {
+		return nil, validation
+	}
+	sort.Strings(columns)
+	return columns, nil
+}
+
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	}
+	if t.Name() == "Time" || t == reflect.TypeOf(gorm.DeletedAt{}) {
+		return "an RFC 3339 timestamp"
+	}
+	return "a valid value"
+}
+
+// IncludeName adalah nama relasi di parameter include dan di JSON, misalnya LikeProducts jadi like_products
+func IncludeName(relationship *schema.Relationship) string {
+	return schema.NamingStrategy{}.ColumnName("", relationship.Name)
+}
+
+func relationByName(sch *schema.Schema, name string) *schema.Relationship {
+	for _, relationship := range sch.Relationships.Relations {
+		if IncludeName(relationship) == name {
+			return relationship
+		}
+	}
+	return nil
+}
+
+// groupIncludes mengubah ["user.wallet", "user"] menjadi {"user": ["wallet"]}
+func groupIncludes(includes []string) map[string][]string {
+	groups := map[string][]string{}
+	for _, include := range includes {
+		name, rest, nested := strings.Cut(include, ".")
+		if _, ok := groups[name]; !ok {
+			groups[name] = nil
+		}
+		if nested {
+			groups[name] = append(groups[name], rest)
+		}
+	}
+	return groups
+}
+
+// preloads memvalidasi parameter include dan mengubahnya menjadi nama Preload GORM,
+// misalnya "user.wallet" jadi "User.Wallet"
+func preloads(sch *schema.Schema, include string) ([]string, []string, error) {
+	var includes, names []string
+	for _, item := range strings.Split(include, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		current := sch
+		var path []string
+		for _, part := range strings.Split(item, ".") {
+			relationship := relationByName(current, part)
+			if relationship == nil {
+				return nil, nil, fmt.Errorf("%w: unknown include %q", ErrBadRequest, item)
+			}
+			path = append(path, relationship.Name)
+			current = relationship.FieldSchema
+		}
+
+		includes = append(includes, item)
+		names = append(names, strings.Join(path, "."))
+	}
+	return includes, names, nil
+}
@@ -0,0 +1,486 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/querydsl"
+	"belajar-golang-gorm/scopes"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DocumentModels adalah model tanpa endpoint REST yang tetap dimasukkan ke components OpenAPI
+var DocumentModels = []interface{}{
+	&belajargolanggorm.UserLog{},
+	&belajargolanggorm.GuestBook{},
+}
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Style       string  `json:"style,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	WriteOnly            bool               `json:"writeOnly,omitempty"`
+	MaxLength            int                `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func jsonContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
+
+type generator struct {
+	db         *gorm.DB
+	document   *Document
+	hidden     map[string]map[string]bool
+	readOnly   map[string]map[string]bool
+	createOnly map[string]map[string]bool
+}
+
+// GenerateOpenAPI membuat dokumen OpenAPI 3 dari schema GORM, aturan field-nya sama
+// dengan encode/decode REST API: tag gorm:"-" tidak muncul, <-:create hanya ada di request create
+func GenerateOpenAPI(db *gorm.DB, endpoints []Endpoint, models ...interface{}) (*Document, error) {
+	g := &generator{
+		db: db,
+		document: &Document{
+			OpenAPI:    "3.0.3",
+			Info:       Info{Title: "belajar-golang-gorm", Version: "1.0.0"},
+			Paths:      map[string]*PathItem{},
+			Components: Components{Schemas: map[string]*Schema{}},
+		},
+		hidden:     map[string]map[string]bool{},
+		readOnly:   map[string]map[string]bool{},
+		createOnly: map[string]map[string]bool{},
+	}
+	g.addCommonSchemas()
+
+	for _, endpoint := range endpoints {
+		info := endpoint.Info()
+		if info.Schema == nil {
+			return nil, fmt.Errorf("api: endpoint %s is not registered", info.Path)
+		}
+		g.hidden[info.Schema.Table] = toSet(info.Hidden)
+		g.readOnly[info.Schema.Table] = toSet(info.ReadOnly)
+		g.createOnly[info.Schema.Table] = toSet(info.CreateOnly)
+	}
+
+	for _, endpoint := range endpoints {
+		info := endpoint.Info()
+		g.addModel(info.Schema)
+		g.addRequestSchemas(info.Schema)
+		if err := g.addPaths(info); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		g.addModel(stmt.Schema)
+	}
+
+	return g.document, nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := map[string]bool{}
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+func (g *generator) addCommonSchemas() {
+	schemas := g.document.Components.Schemas
+	schemas["Meta"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"page":        {Type: "integer"},
+			"size":        {Type: "integer"},
+			"total":       {Type: "integer", Format: "int64"},
+			"total_pages": {Type: "integer"},
+			"next_cursor": {Type: "string"},
+			"prev_cursor": {Type: "string"},
+		},
+		Required: []string{"size", "total"},
+	}
+	schemas["Error"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"error": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"code":    {Type: "string", Enum: []string{"bad_request", "not_found", "conflict", "validation_failed", "internal"}},
+					"message": {Type: "string"},
+					"fields":  {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+				},
+				Required: []string{"code", "message"},
+			},
+		},
+		Required: []string{"error"},
+	}
+}
+
+// addModel menambahkan schema response model dan semua model relasinya
+func (g *generator) addModel(sch *schema.Schema) {
+	schemas := g.document.Components.Schemas
+	if _, ok := schemas[sch.Name]; ok {
+		return
+	}
+
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	schemas[sch.Name] = object
+
+	for _, field := range sch.Fields {
+		if !Exposed(field) || g.hidden[sch.Table][field.DBName] {
+			continue
+		}
+
+		property := fieldSchema(field)
+		if !Writable(field, true) && !Writable(field, false) || g.readOnly[sch.Table][field.DBName] {
+			property.ReadOnly = true
+		}
+		object.Properties[field.DBName] = property
+		if !property.Nullable {
+			object.Required = append(object.Required, field.DBName)
+		}
+	}
+	sort.Strings(object.Required)
+
+	for _, relationship := range sch.Relationships.Relations {
+		g.addModel(relationship.FieldSchema)
+
+		var property *Schema
+		if relationship.Field.IndirectFieldType.Kind() == reflect.Slice {
+			property = &Schema{Type: "array", Items: ref(relationship.FieldSchema.Name)}
+		} else {
+			property = ref(relationship.FieldSchema.Name)
+		}
+		// hanya muncul kalau diminta lewat parameter include
+		object.Properties[IncludeName(relationship)] = property
+	}
+}
+
+// addRequestSchemas membuat <Model>Create dan <Model>Update untuk request body
+func (g *generator) addRequestSchemas(sch *schema.Schema) {
+	for _, create := range []bool{true, false} {
+		name := sch.Name + "Update"
+		if create {
+			name = sch.Name + "Create"
+		}
+
+		object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for _, field := range sch.Fields {
+			if !Writable(field, create) || g.readOnly[sch.Table][field.DBName] {
+				continue
+			}
+			if !create && g.createOnly[sch.Table][field.DBName] {
+				continue
+			}
+
+			property := fieldSchema(field)
+			if g.hidden[sch.Table][field.DBName] {
+				property.WriteOnly = true
+			}
+			object.Properties[field.DBName] = property
+
+			// primary key yang bukan auto increment harus dikirim client
+			if create && field.PrimaryKey && !field.AutoIncrement {
+				object.Required = append(object.Required, field.DBName)
+			}
+		}
+		g.document.Components.Schemas[name] = object
+	}
+}
+
+func fieldSchema(field *schema.Field) *Schema {
+	property := &Schema{}
+	t := field.FieldType
+	if t.Kind() == reflect.Ptr {
+		property.Nullable = true
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		property.Type, property.Format = "string", "date-time"
+	case t == reflect.TypeOf(gorm.DeletedAt{}):
+		property.Type, property.Format, property.Nullable = "string", "date-time", true
+	case t.Kind() == reflect.Bool:
+		property.Type = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		property.Type, property.Format = "integer", "int64"
+		if t.Kind() <= reflect.Int32 && t.Kind() != reflect.Int {
+			property.Format = "int32"
+		}
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		minimum := 0.0
+		property.Type, property.Format, property.Minimum = "integer", "int64", &minimum
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		property.Type, property.Format = "number", "double"
+	case t.Kind() == reflect.String:
+		property.Type = "string"
+		property.MaxLength = field.Size
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		property.Type, property.Format = "string", "byte"
+	default:
+		property.Type = "object"
+	}
+
+	if field.HasDefaultValue && field.DefaultValue != "" && !field.AutoIncrement {
+		property.Description = "default: " + strings.Trim(field.DefaultValue, "'")
+	}
+	return property
+}
+
+func (g *generator) addPaths(info EndpointInfo) error {
+	sch := info.Schema
+	name := sch.Name
+	tags := []string{info.Path}
+
+	errorResponse := func(description string) *Response {
+		return &Response{Description: description, Content: jsonContent(ref("Error"))}
+	}
+	single := &Schema{Type: "object", Properties: map[string]*Schema{"data": ref(name)}, Required: []string{"data"}}
+	listSchema := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"data": {Type: "array", Items: ref(name)},
+			"meta": ref("Meta"),
+		},
+		Required: []string{"data", "meta"},
+	}
+
+	var includes []string
+	for _, relationship := range sch.Relationships.Relations {
+		includes = append(includes, IncludeName(relationship))
+	}
+	sort.Strings(includes)
+	includeParam := Parameter{
+		Name:        "include",
+		In:          "query",
+		Description: "relasi yang di-preload, dipisah koma, boleh bertingkat dengan titik: " + strings.Join(includes, ", "),
+		Schema:      &Schema{Type: "string"},
+	}
+	idParam := Parameter{Name: "id", In: "path", Required: true, Schema: fieldSchema(sch.PrioritizedPrimaryField)}
+	listParameters, err := g.listParameters(info)
+	if err != nil {
+		return err
+	}
+
+	g.document.Paths["/"+info.Path] = &PathItem{
+		Get: &Operation{
+			OperationID: "list" + name,
+			Summary:     "List " + info.Path,
+			Tags:        tags,
+			Parameters:  append(listParameters, includeParam),
+			Responses: map[string]*Response{
+				"200": {Description: "OK", Content: jsonContent(listSchema)},
+				"400": errorResponse("filter, sort, scope, include atau cursor tidak valid"),
+			},
+		},
+		Post: &Operation{
+			OperationID: "create" + name,
+			Summary:     "Create " + strings.ToLower(name),
+			Tags:        tags,
+			RequestBody: &RequestBody{Required: true, Content: jsonContent(ref(name + "Create"))},
+			Responses: map[string]*Response{
+				"201": {Description: "Created", Content: jsonContent(single)},
+				"409": errorResponse("Conflict"),
+				"422": errorResponse("Validation failed"),
+			},
+		},
+	}
+
+	g.document.Paths["/"+info.Path+"/{id}"] = &PathItem{
+		Get: &Operation{
+			OperationID: "get" + name,
+			Summary:     "Get " + strings.ToLower(name),
+			Tags:        tags,
+			Parameters:  []Parameter{idParam, includeParam},
+			Responses: map[string]*Response{
+				"200": {Description: "OK", Content: jsonContent(single)},
+				"400": errorResponse("Bad request"),
+				"404": errorResponse("Not found"),
+			},
+		},
+		Patch: &Operation{
+			OperationID: "update" + name,
+			Summary:     "Update " + strings.ToLower(name),
+			Tags:        tags,
+			Parameters:  []Parameter{idParam},
+			RequestBody: &RequestBody{Required: true, Content: jsonContent(ref(name + "Update"))},
+			Responses: map[string]*Response{
+				"200": {Description: "OK", Content: jsonContent(single)},
+				"404": errorResponse("Not found"),
+				"409": errorResponse("Conflict"),
+				"422": errorResponse("Validation failed"),
+			},
+		},
+		Delete: &Operation{
+			OperationID: "delete" + name,
+			Summary:     "Delete " + strings.ToLower(name),
+			Tags:        tags,
+			Parameters:  []Parameter{idParam},
+			Responses: map[string]*Response{
+				"204": {Description: "Deleted"},
+				"404": errorResponse("Not found"),
+			},
+		},
+	}
+
+	for _, action := range info.Actions {
+		g.document.Paths["/"+info.Path+"/{id}/"+action.Name] = &PathItem{
+			Post: &Operation{
+				OperationID: action.Name + name,
+				Summary:     action.Summary,
+				Tags:        tags,
+				Parameters:  []Parameter{idParam},
+				RequestBody: &RequestBody{Required: true, Content: jsonContent(action.Request)},
+				Responses: map[string]*Response{
+					"200": {Description: "OK", Content: jsonContent(single)},
+					"404": errorResponse("Not found"),
+					"409": errorResponse("Conflict"),
+					"422": errorResponse("Validation failed"),
+				},
+			},
+		}
+	}
+	return nil
+}
+
+func (g *generator) listParameters(info EndpointInfo) ([]Parameter, error) {
+	spec, err := querydsl.For(g.db, info.Model, querydsl.Exclude(info.Hidden...))
+	if err != nil {
+		return nil, fmt.Errorf("api: endpoint %s: %w", info.Path, err)
+	}
+
+	operators := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, operator := range []string{
+		querydsl.OpEq, querydsl.OpNe, querydsl.OpGt, querydsl.OpGte, querydsl.OpLt,
+		querydsl.OpLte, querydsl.OpLike, querydsl.OpIn, querydsl.OpNull,
+	} {
+		operators.Properties[operator] = &Schema{Type: "string"}
+	}
+	filter := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, column := range spec.Filterable() {
+		filter.Properties[column] = operators
+	}
+
+	var scopeNames []string
+	for _, definition := range scopes.Default.Describe(info.Model) {
+		description := definition.Name
+		for i, param := range definition.Params {
+			if i == 0 {
+				description += "("
+			} else {
+				description += ", "
+			}
+			description += param.Name + "=" + param.Default
+			if i == len(definition.Params)-1 {
+				description += ")"
+			}
+		}
+		if definition.Description != "" {
+			description += " - " + definition.Description
+		}
+		scopeNames = append(scopeNames, description)
+	}
+
+	explode := true
+	parameters := []Parameter{
+		{Name: "filter", In: "query", Style: "deepObject", Explode: &explode, Schema: filter,
+			Description: "filter[kolom][operator]=nilai, tanpa operator berarti eq"},
+		{Name: "sort", In: "query", Schema: &Schema{Type: "string"},
+			Description: "kolom dipisah koma, awalan - untuk descending: " + strings.Join(spec.Sortable(), ", ")},
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer"}},
+		{Name: "size", In: "query", Schema: &Schema{Type: "integer"}},
+		{Name: "cursor", In: "query", Schema: &Schema{Type: "string"},
+			Description: "keyset pagination, kosong berarti halaman pertama"},
+	}
+	if len(scopeNames) > 0 {
+		parameters = append(parameters, Parameter{Name: "scope", In: "query", Schema: &Schema{Type: "string"},
+			Description: "named scope, gabungkan dengan , (AND), | (OR) dan ! (NOT): " + strings.Join(scopeNames, "; ")})
+	}
+	return parameters, nil
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.document)
+}
@@ -0,0 +1,136 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"belajar-golang-gorm/api"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fetchDocument(t *testing.T) *api.Document {
+	_, server := newServer(t)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("GET", "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	document := &api.Document{}
+	err := json.Unmarshal(recorder.Body.Bytes(), document)
+	assert.Nil(t, err)
+	return document
+}
+
+func TestOpenAPIModels(t *testing.T) {
+	document := fetchDocument(t)
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+	schemas := document.Components.Schemas
+
+	for _, name := range []string{"User", "Wallet", "Address", "Product", "Todo", "UserLog", "GuestBook", "GuestBookModeration"} {
+		assert.Contains(t, schemas, name)
+	}
+
+	// gorm:"-" dan kolom hidden tidak muncul di response
+	user := schemas["User"]
+	assert.NotContains(t, user.Properties, "information")
+	assert.NotContains(t, user.Properties, "password")
+	assert.Equal(t, "string", user.Properties["first_name"].Type)
+	assert.True(t, user.Properties["created_at"].ReadOnly)
+	assert.Equal(t, "date-time", user.Properties["created_at"].Format)
+	assert.True(t, user.Properties["email"].Nullable)
+	assert.Equal(t, 100, user.Properties["email"].MaxLength)
+	assert.Equal(t, "#/components/schemas/Wallet", user.Properties["wallet"].Ref)
+	assert.Equal(t, "#/components/schemas/Address", user.Properties["addresses"].Items.Ref)
+	assert.Contains(t, user.Required, "first_name")
+	assert.NotContains(t, user.Required, "email")
+
+	// id dengan <-:create hanya bisa diisi saat create
+	assert.Contains(t, schemas["UserCreate"].Properties, "id")
+	assert.NotContains(t, schemas["UserUpdate"].Properties, "id")
+	assert.True(t, schemas["UserCreate"].Properties["password"].WriteOnly)
+	assert.NotContains(t, schemas["UserCreate"].Properties, "created_at")
+	assert.NotContains(t, schemas["UserCreate"].Properties, "email_verified_at")
+	assert.Equal(t, []string{"id"}, schemas["WalletCreate"].Required)
+
+	assert.True(t, schemas["Product"].Properties["like_count"].ReadOnly)
+	assert.NotContains(t, schemas["ProductUpdate"].Properties, "like_count")
+	assert.Contains(t, schemas["ProductCreate"].Properties, "price")
+	assert.NotContains(t, schemas["ProductUpdate"].Properties, "price")
+	assert.NotContains(t, schemas["UserUpdate"].Properties, "email")
+
+	assert.True(t, schemas["Todo"].Properties["deleted_at"].Nullable)
+	assert.True(t, schemas["Todo"].Properties["deleted_at"].ReadOnly)
+
+	assert.Equal(t, "integer", schemas["UserLog"].Properties["created_at"].Type)
+	assert.True(t, schemas["UserLog"].Properties["created_at"].ReadOnly)
+	assert.Equal(t, "default: pending", schemas["GuestBook"].Properties["status"].Description)
+	assert.Equal(t, "#/components/schemas/GuestBookModeration", schemas["GuestBook"].Properties["moderations"].Items.Ref)
+}
+
+func TestOpenAPIPaths(t *testing.T) {
+	document := fetchDocument(t)
+
+	for _, path := range []string{"users", "wallets", "addresses", "products", "todos"} {
+		assert.NotNil(t, document.Paths["/"+path].Get)
+		assert.NotNil(t, document.Paths["/"+path].Post)
+		assert.NotNil(t, document.Paths["/"+path+"/{id}"].Patch)
+		assert.NotNil(t, document.Paths["/"+path+"/{id}"].Delete)
+	}
+	assert.NotNil(t, document.Paths["/users/{id}/email"].Post)
+	assert.NotNil(t, document.Paths["/products/{id}/price"].Post)
+	assert.NotContains(t, document.Paths, "/user_logs")
+
+	users := document.Paths["/users"]
+	assert.Equal(t, "listUser", users.Get.OperationID)
+	assert.Equal(t, "#/components/schemas/UserCreate", users.Post.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Contains(t, users.Post.Responses, "422")
+
+	var filter *api.Schema
+	for _, parameter := range users.Get.Parameters {
+		if parameter.Name == "filter" {
+			filter = parameter.Schema
+		}
+	}
+	assert.NotNil(t, filter)
+	assert.Contains(t, filter.Properties, "first_name")
+	assert.NotContains(t, filter.Properties, "password")
+
+	var scope string
+	for _, parameter := range document.Paths["/wallets"].Get.Parameters {
+		if parameter.Name == "scope" {
+			scope = parameter.Description
+		}
+	}
+	assert.True(t, strings.Contains(scope, "sultan(min=1000000)"), scope)
+	assert.True(t, strings.Contains(scope, "broke"), scope)
+
+	wallet := document.Paths["/wallets/{id}"].Get
+	assert.Equal(t, "string", wallet.Parameters[0].Schema.Type)
+}
+
+// brokenEndpoint memakai endpoint yang sudah terdaftar tapi modelnya tidak bisa di-parse
+type brokenEndpoint struct {
+	api.Endpoint
+}
+
+func (e brokenEndpoint) Info() api.EndpointInfo {
+	info := e.Endpoint.Info()
+	info.Model = "bukan model"
+	return info
+}
+
+func TestOpenAPIInvalidModel(t *testing.T) {
+	db := testdb.Open(t)
+	endpoints := api.DefaultEndpoints()
+	_, err := api.New(db, endpoints...)
+	assert.Nil(t, err)
+
+	document, err := api.GenerateOpenAPI(db, []api.Endpoint{brokenEndpoint{endpoints[0]}})
+	assert.NotNil(t, err)
+	assert.Nil(t, document)
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"belajar-golang-gorm/pagination"
+	"belajar-golang-gorm/querydsl"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const maxBodySize = 1 << 20
+
+// Resource membuat endpoint CRUD untuk model T:
+//
+//	GET    /{path}?filter[..]=..&sort=..&scope=..&page=..&size=..&cursor=..&include=..
+//	POST   /{path}
+//	GET    /{path}/{id}?include=..
+//	PATCH  /{path}/{id}
+//	DELETE /{path}/{id}
+//	POST   /{path}/{id}/{action}
+type Resource[T any] struct {
+	Path       string
+	Hidden     []string // kolom yang tidak pernah dikirim ke client, misalnya password
+	ReadOnly   []string // kolom tambahan yang tidak boleh diisi client
+	CreateOnly []string // kolom yang hanya boleh diisi saat create, perubahannya lewat Actions
+	Validate   func(item *T) error
+	Actions    []Action[T]
+
+	server         *Server
+	schema         *schema.Schema
+	spec           *querydsl.Spec
+	readOnly       map[string]bool
+	updateReadOnly map[string]bool
+}
+
+// Action adalah endpoint POST /{path}/{id}/{name} untuk perubahan yang harus lewat
+// fungsi domain, misalnya ChangeProductPrice supaya riwayat harga ikut tercatat
+type Action[T any] struct {
+	Name    string
+	Summary string
+	Request *Schema // schema body untuk OpenAPI
+	Run     func(db *gorm.DB, item *T, body []byte) error
+}
+
+// EndpointInfo dipakai untuk dokumentasi seperti OpenAPI
+type EndpointInfo struct {
+	Path       string
+	Model      interface{}
+	Schema     *schema.Schema
+	Hidden     []string
+	ReadOnly   []string
+	CreateOnly []string
+	Actions    []ActionInfo
+}
+
+type ActionInfo struct {
+	Name    string
+	Summary string
+	Request *Schema
+}
+
+func (r *Resource[T]) Info() EndpointInfo {
+	info := EndpointInfo{Path: r.Path, Model: new(T), Schema: r.schema, Hidden: r.Hidden, ReadOnly: r.ReadOnly, CreateOnly: r.CreateOnly}
+	for _, action := range r.Actions {
+		info.Actions = append(info.Actions, ActionInfo{Name: action.Name, Summary: action.Summary, Request: action.Request})
+	}
+	return info
+}
+
+func (r *Resource[T]) register(s *Server) error {
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return err
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return fmt.Errorf("api: %s has no primary key", stmt.Schema.Name)
+	}
+
+	spec, err := querydsl.For(s.db, new(T), querydsl.Exclude(r.Hidden...))
+	if err != nil {
+		return err
+	}
+
+	r.server = s
+	r.schema = stmt.Schema
+	r.spec = spec
+	r.readOnly = map[string]bool{}
+	r.updateReadOnly = map[string]bool{}
+	for _, column := range r.ReadOnly {
+		r.readOnly[column] = true
+		r.updateReadOnly[column] = true
+	}
+	for _, column := range r.CreateOnly {
+		r.updateReadOnly[column] = true
+	}
+
+	hidden := map[string]bool{}
+	for _, column := range r.Hidden {
+		hidden[column] = true
+	}
+	s.hidden[r.schema.Table] = hidden
+
+	s.mux.HandleFunc("GET /"+r.Path, r.list)
+	s.mux.HandleFunc("POST /"+r.Path, r.create)
+	s.mux.HandleFunc("GET /"+r.Path+"/{id}", r.get)
+	s.mux.HandleFunc("PATCH /"+r.Path+"/{id}", r.update)
+	s.mux.HandleFunc("DELETE /"+r.Path+"/{id}", r.delete)
+	for _, action := range r.Actions {
+		s.mux.HandleFunc("POST /"+r.Path+"/{id}/"+action.Name, r.action(action))
+	}
+	return nil
+}
+
+func (r *Resource[T]) encode(req *http.Request, item *T, includes []string) map[string]interface{} {
+	return r.server.encode(req.Context(), r.schema, reflect.ValueOf(item), includes)
+}
+
+func (r *Resource[T]) withPreloads(db *gorm.DB, names []string) *gorm.DB {
+	for _, name := range names {
+		db = db.Preload(name)
+	}
+	return db
+}
+
+func (r *Resource[T]) list(w http.ResponseWriter, req *http.Request) {
+	values := req.URL.Query()
+	query, err := r.spec.Parse(values)
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+	includes, names, err := preloads(r.schema, values.Get("include"))
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+	page, err := intParam(values.Get("page"), "page")
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+	size, err := intParam(values.Get("size"), "size")
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+
+	db := r.withPreloads(r.server.db.WithContext(req.Context()), names).Scopes(query.FilterScope)
+
+	var result pagination.Page[T]
+	if values.Has("cursor") {
+		result, err = pagination.FindCursor[T](db, values.Get("cursor"), size, query.Sorts...)
+	} else {
+		sorts := query.Sorts
+		if len(sorts) == 0 {
+			sorts = []pagination.Sort{{Column: r.schema.PrioritizedPrimaryField.DBName}}
+		}
+		result, err = pagination.FindPage[T](db.Scopes(pagination.OrderBy(sorts...)), page, size)
+	}
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+
+	data := make([]map[string]interface{}, 0, len(result.Items))
+	for i := range result.Items {
+		data = append(data, r.encode(req, &result.Items[i], includes))
+	}
+	writeJSON(w, http.StatusOK, envelope{Data: data, Meta: &meta{
+		Page:       result.Page,
+		Size:       result.Size,
+		Total:      result.Total,
+		TotalPages: result.TotalPages,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+	}})
+}
+
+func (r *Resource[T]) find(req *http.Request, names []string) (*T, error) {
+	primaryKey := r.schema.PrioritizedPrimaryField
+	id, err := parseID(primaryKey, req.PathValue("id"))
+	if err != nil {
+		return nil, err
+	}
+
+	item := new(T)
+	err = r.withPreloads(r.server.db.WithContext(req.Context()), names).
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: primaryKey.DBName}, Value: id}).
+		Take(item).Error
+	return item, err
+}
+
+func (r *Resource[T]) get(w http.ResponseWriter, req *http.Request) {
+	includes, names, err := preloads(r.schema, req.URL.Query().Get("include"))
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+
+	item, err := r.find(req, names)
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, envelope{Data: r.encode(req, item, includes)})
+}
+
+func (r *Resource[T]) readBody(req *http.Request, item *T, create bool) ([]string, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadRequest, err)
+	}
+
+	readOnly := r.updateReadOnly
+	if create {
+		readOnly = r.readOnly
+	}
+	columns, err := r.server.decode(req.Context(), r.schema, body, reflect.ValueOf(item), create, readOnly)
+	if err != nil {
+		return nil, err
+	}
+	if r.Validate != nil {
+		if err := r.Validate(item); err != nil {
+			return nil, err
+		}
+	}
+	return columns, nil
+}
+
+func (r *Resource[T]) create(w http.ResponseWriter, req *http.Request) {
+	item := new(T)
+	if _, err := r.readBody(req, item, true); err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+
+	err := r.server.db.WithContext(req.Context()).Create(item).Error
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, envelope{Data: r.encode(req, item, nil)})
+}
+
+func (r *Resource[T]) update(w http.ResponseWriter, req *http.Request) {
+	item, err := r.find(req, nil)
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+
+	columns, err := r.readBody(req, item, false)
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+
+	if len(columns) > 0 {
+		// Select supaya nilai kosong (0, "", false) dari client tetap disimpan
+		err = r.server.db.WithContext(req.Context()).Model(item).Select(columns).Updates(item).Error
+		if err != nil {
+			r.server.writeFailure(w, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, envelope{Data: r.encode(req, item, nil)})
+}
+
+func (r *Resource[T]) delete(w http.ResponseWriter, req *http.Request) {
+	item, err := r.find(req, nil)
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+
+	err = r.server.db.WithContext(req.Context()).Delete(item).Error
+	if err != nil {
+		r.server.writeFailure(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Resource[T]) action(action Action[T]) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		item, err := r.find(req, nil)
+		if err != nil {
+			r.server.writeFailure(w, err)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize))
+		if err != nil {
+			r.server.writeFailure(w, fmt.Errorf("%w: %v", ErrBadRequest, err))
+			return
+		}
+
+		err = action.Run(r.server.db.WithContext(req.Context()), item, body)
+		if err != nil {
+			r.server.writeFailure(w, err)
+			return
+		}
+
+		// dimuat ulang karena fungsi domain bisa mengubah kolom lain
+		item, err = r.find(req, nil)
+		if err != nil {
+			r.server.writeFailure(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Data: r.encode(req, item, nil)})
+	}
+}
+
+// DecodeAction membaca body JSON action, field yang tidak dikenal ditolak
+func DecodeAction(body []byte, input interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(input); err != nil {
+		return &ValidationError{Message: "invalid request body: " + err.Error()}
+	}
+	return nil
+}
+
+func parseID(field *schema.Field, raw string) (interface{}, error) {
+	switch field.GORMDataType {
+	case schema.Int, schema.Uint:
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid id %q", ErrBadRequest, raw)
+		}
+		return id, nil
+	default:
+		return raw, nil
+	}
+}
+
+func intParam(raw string, name string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be an integer", ErrBadRequest, name)
+	}
+	return value, nil
+}
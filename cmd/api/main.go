@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/api"
+	"belajar-golang-gorm/dbmetrics"
+	"belajar-golang-gorm/dbrouter"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "alamat HTTP server")
+	flag.Parse()
+
+	config, err := belajargolanggorm.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := belajargolanggorm.Connect(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	metrics := dbmetrics.New(dbmetrics.Options{})
+	if err := db.Use(metrics); err != nil {
+		log.Fatal(err)
+	}
+	// pool replica dicatat terpisah dengan label db=replica-N
+	if router, ok := db.Config.Plugins["dbrouter"].(*dbrouter.Router); ok {
+		for _, replica := range router.Replicas() {
+			metrics.CollectPool(replica.Name, replica.DB())
+		}
+	}
+
+	server, err := api.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		health := belajargolanggorm.HealthCheck(r.Context(), db)
+		w.Header().Set("Content-Type", "application/json")
+		if health.Status == belajargolanggorm.HealthDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(health)
+	})
+	mux.Handle("/", server)
+
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
@@ -0,0 +1,584 @@
+// gormctl adalah CLI untuk operasi database:
+//
+//	gormctl [-driver mysql|sqlite] [-dsn DSN] [-verbose] <command> ...
+//
+//	migrate up [-steps N] | down [-steps N] | status
+//	seed
+//	users list [-query "filter[first_name][like]=Eko&sort=-id"] [-limit N]
+//	users create -id N -first-name S [-middle-name S] [-last-name S] [-email S] -password S
+//	users delete <id>
+//	wallet list [-scope "broke|sultan(min=5000000)"]
+//	wallet credit <user_id> <amount> | debit <user_id> <amount> | transfer <from_user_id> <to_user_id> <amount>
+//	todos trash <id> | restore <id> | purge [-older-than 720h]
+//	schema check
+//	export users|wallets|user_logs|... [-format csv|ndjson] [-columns id,first_name] [-batch N]
+//	import users|wallets|user_logs|... -file PATH [-format csv|ndjson] [-batch N] [-on-conflict id] [-update a,b] [-dry-run] [-report PATH]
+//
+// Koneksi dibaca dari environment (lihat belajargolanggorm.ConfigFromEnv) lalu ditimpa flag.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/export"
+	"belajar-golang-gorm/importer"
+	"belajar-golang-gorm/querydsl"
+	"belajar-golang-gorm/scopes"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	config, err := belajargolanggorm.ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	flags := flag.NewFlagSet("gormctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&config.Driver, "driver", config.Driver, "database driver: mysql atau sqlite")
+	flags.StringVar(&config.DSN, "dsn", config.DSN, "data source name")
+	verbose := flags.Bool("verbose", false, "tampilkan semua query")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	config.LogLevel = logger.Silent
+	if *verbose {
+		config.LogLevel = logger.Info
+	}
+
+	rest := flags.Args()
+	if len(rest) < 1 {
+		fmt.Fprintln(stderr, "usage: gormctl [flags] migrate|seed|users|wallet|todos|schema|export|import ...")
+		return 2
+	}
+
+	db, err := belajargolanggorm.Connect(config)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	cli := &cli{db: db, stdout: stdout, stderr: stderr}
+	commands := map[string]func(args []string) error{
+		"migrate": cli.migrate,
+		"seed":    cli.seed,
+		"users":   cli.users,
+		"wallet":  cli.wallet,
+		"todos":   cli.todos,
+		"schema":  cli.schema,
+		"export":  cli.export,
+		"import":  cli.importRows,
+	}
+
+	command, ok := commands[rest[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", rest[0])
+		return 2
+	}
+
+	err = command(rest[1:])
+	var failed checkFailed
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		fmt.Fprintln(stderr, err)
+		return 2
+	case errors.As(err, &failed):
+		return 1
+	default:
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+}
+
+type cli struct {
+	db     *gorm.DB
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// checkFailed dipakai schema check, hasilnya sudah dicetak jadi tidak perlu pesan error lagi
+type checkFailed struct{}
+
+func (checkFailed) Error() string {
+	return "check failed"
+}
+
+func usage(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{errUsage}, args...)...)
+}
+
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+func (c *cli) table() *tabwriter.Writer {
+	return tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+}
+
+func intArg(args []string, index int, name string) (int, error) {
+	if len(args) <= index {
+		return 0, usage("missing %s", name)
+	}
+	value, err := strconv.Atoi(args[index])
+	if err != nil {
+		return 0, usage("%s must be an integer", name)
+	}
+	return value, nil
+}
+
+func floatArg(args []string, index int, name string) (float64, error) {
+	if len(args) <= index {
+		return 0, usage("missing %s", name)
+	}
+	value, err := strconv.ParseFloat(args[index], 64)
+	if err != nil {
+		return 0, usage("%s must be a number", name)
+	}
+	return value, nil
+}
+
+func (c *cli) migrate(args []string) error {
+	if len(args) < 1 {
+		return usage("migrate up|down|status")
+	}
+
+	flags := c.flagSet("migrate " + args[0])
+	steps := flags.Int("steps", 0, "jumlah migration, 0 berarti semua (up) atau satu (down)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := belajargolanggorm.MigrateUp(c.db, *steps)
+		for _, id := range done {
+			fmt.Fprintln(c.stdout, "applied", id)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(c.stdout, "nothing to migrate")
+		}
+		return err
+	case "down":
+		done, err := belajargolanggorm.MigrateDown(c.db, *steps)
+		for _, id := range done {
+			fmt.Fprintln(c.stdout, "reverted", id)
+		}
+		return err
+	case "status":
+		states, err := belajargolanggorm.MigrationStatus(c.db)
+		table := c.table()
+		fmt.Fprintln(table, "ID\tSTATUS\tAPPLIED AT")
+		for _, state := range states {
+			if state.Applied {
+				fmt.Fprintf(table, "%s\tapplied\t%s\n", state.ID, state.AppliedAt.Format(time.RFC3339))
+			} else {
+				fmt.Fprintf(table, "%s\tpending\t-\n", state.ID)
+			}
+		}
+		table.Flush()
+		return err
+	default:
+		return usage("migrate up|down|status")
+	}
+}
+
+func (c *cli) seed(args []string) error {
+	if err := belajargolanggorm.Seed(c.db); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, "seeded")
+	return nil
+}
+
+func (c *cli) users(args []string) error {
+	if len(args) < 1 {
+		return usage("users list|create|delete")
+	}
+
+	switch args[0] {
+	case "list":
+		flags := c.flagSet("users list")
+		rawQuery := flags.String("query", "", "filter dan sort dengan format querydsl")
+		limit := flags.Int("limit", 50, "jumlah maksimal user")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		values, err := url.ParseQuery(*rawQuery)
+		if err != nil {
+			return usage("invalid -query: %v", err)
+		}
+		spec, err := querydsl.For(c.db, &belajargolanggorm.User{}, querydsl.Exclude("password"))
+		if err != nil {
+			return err
+		}
+		query, err := spec.Parse(values)
+		if err != nil {
+			return err
+		}
+
+		var users []belajargolanggorm.User
+		db := c.db.Scopes(query.Scope)
+		if len(query.Sorts) == 0 {
+			db = db.Order("id")
+		}
+		if err := db.Limit(*limit).Find(&users).Error; err != nil {
+			return err
+		}
+
+		table := c.table()
+		fmt.Fprintln(table, "ID\tNAME\tEMAIL\tCREATED AT")
+		for _, user := range users {
+			email := "-"
+			if user.Email != nil {
+				email = *user.Email
+			}
+			name := user.Name.FirstName
+			for _, part := range []string{user.Name.MiddleName, user.Name.LastName} {
+				if part != "" {
+					name += " " + part
+				}
+			}
+			fmt.Fprintf(table, "%d\t%s\t%s\t%s\n", user.ID, name, email, user.CreatedAt.Format(time.RFC3339))
+		}
+		return table.Flush()
+
+	case "create":
+		flags := c.flagSet("users create")
+		user := belajargolanggorm.User{}
+		flags.IntVar(&user.ID, "id", 0, "id user")
+		flags.StringVar(&user.Name.FirstName, "first-name", "", "nama depan")
+		flags.StringVar(&user.Name.MiddleName, "middle-name", "", "nama tengah")
+		flags.StringVar(&user.Name.LastName, "last-name", "", "nama belakang")
+		flags.StringVar(&user.Password, "password", "", "password")
+		email := flags.String("email", "", "email")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if user.ID == 0 || user.Name.FirstName == "" || user.Password == "" {
+			return usage("users create -id N -first-name S -password S")
+		}
+		if *email != "" {
+			user.Email = email
+		}
+
+		if err := c.db.Create(&user).Error; err != nil {
+			return err
+		}
+		fmt.Fprintln(c.stdout, "created user", user.ID)
+		return nil
+
+	case "delete":
+		id, err := intArg(args, 1, "user id")
+		if err != nil {
+			return err
+		}
+		result := c.db.Delete(&belajargolanggorm.User{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		fmt.Fprintln(c.stdout, "deleted user", id)
+		return nil
+
+	default:
+		return usage("users list|create|delete")
+	}
+}
+
+func (c *cli) printWallets(wallets ...*belajargolanggorm.Wallet) error {
+	table := c.table()
+	fmt.Fprintln(table, "ID\tUSER ID\tBALANCE")
+	for _, wallet := range wallets {
+		fmt.Fprintf(table, "%s\t%d\t%.2f\n", wallet.ID, wallet.UserId, wallet.Balance)
+	}
+	return table.Flush()
+}
+
+func (c *cli) wallet(args []string) error {
+	if len(args) < 1 {
+		return usage("wallet list|credit|debit|transfer")
+	}
+
+	switch args[0] {
+	case "list":
+		flags := c.flagSet("wallet list")
+		expression := flags.String("scope", "", "named scope, misalnya broke|sultan")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		db := c.db.Order("user_id")
+		if *expression != "" {
+			scope, err := scopes.Parse(&belajargolanggorm.Wallet{}, *expression)
+			if err != nil {
+				return err
+			}
+			db = db.Scopes(scope)
+		}
+
+		var wallets []*belajargolanggorm.Wallet
+		if err := db.Find(&wallets).Error; err != nil {
+			return err
+		}
+		return c.printWallets(wallets...)
+
+	case "credit", "debit":
+		userId, err := intArg(args, 1, "user id")
+		if err != nil {
+			return err
+		}
+		amount, err := floatArg(args, 2, "amount")
+		if err != nil {
+			return err
+		}
+
+		change := belajargolanggorm.CreditWallet
+		if args[0] == "debit" {
+			change = belajargolanggorm.DebitWallet
+		}
+		wallet, err := change(c.db, userId, amount)
+		if err != nil {
+			return err
+		}
+		return c.printWallets(wallet)
+
+	case "transfer":
+		from, err := intArg(args, 1, "from user id")
+		if err != nil {
+			return err
+		}
+		to, err := intArg(args, 2, "to user id")
+		if err != nil {
+			return err
+		}
+		amount, err := floatArg(args, 3, "amount")
+		if err != nil {
+			return err
+		}
+
+		fromWallet, toWallet, err := belajargolanggorm.TransferBalance(c.db, from, to, amount)
+		if err != nil {
+			return err
+		}
+		return c.printWallets(fromWallet, toWallet)
+
+	default:
+		return usage("wallet list|credit|debit|transfer")
+	}
+}
+
+func (c *cli) todos(args []string) error {
+	if len(args) < 1 {
+		return usage("todos trash|restore|purge")
+	}
+
+	switch args[0] {
+	case "trash", "restore":
+		id, err := intArg(args, 1, "todo id")
+		if err != nil {
+			return err
+		}
+		if args[0] == "trash" {
+			err = belajargolanggorm.TrashTodo(c.db, uint(id))
+		} else {
+			err = belajargolanggorm.RestoreTodo(c.db, uint(id))
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "%s todo %d\n", map[string]string{"trash": "trashed", "restore": "restored"}[args[0]], id)
+		return nil
+
+	case "purge":
+		flags := c.flagSet("todos purge")
+		olderThan := flags.Duration("older-than", 30*24*time.Hour, "hapus permanen todo yang di-trash lebih lama dari durasi ini")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		count, err := belajargolanggorm.PurgeTodos(c.db, time.Now().Add(-*olderThan))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "purged %d todos\n", count)
+		return nil
+
+	default:
+		return usage("todos trash|restore|purge")
+	}
+}
+
+func (c *cli) schema(args []string) error {
+	if len(args) < 1 || args[0] != "check" {
+		return usage("schema check")
+	}
+
+	issues, err := belajargolanggorm.SchemaCheck(c.db)
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		fmt.Fprintln(c.stdout, "schema ok")
+		return nil
+	}
+
+	table := c.table()
+	fmt.Fprintln(table, "TABLE\tCOLUMN\tPROBLEM")
+	for _, issue := range issues {
+		column := issue.Column
+		if column == "" {
+			column = "-"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", issue.Table, column, issue.Problem)
+	}
+	table.Flush()
+	return checkFailed{}
+}
+
+// exportModels adalah tabel yang bisa diekspor dan di-import lewat gormctl export dan import
+var exportModels = map[string]interface{}{
+	"users":       &belajargolanggorm.User{},
+	"wallets":     &belajargolanggorm.Wallet{},
+	"addresses":   &belajargolanggorm.Address{},
+	"products":    &belajargolanggorm.Product{},
+	"todos":       &belajargolanggorm.Todo{},
+	"user_logs":   &belajargolanggorm.UserLog{},
+	"guest_books": &belajargolanggorm.GuestBook{},
+}
+
+func exportModel(command string, args []string) (interface{}, error) {
+	tables := make([]string, 0, len(exportModels))
+	for table := range exportModels {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	if len(args) < 1 {
+		return nil, usage("%s %s", command, strings.Join(tables, "|"))
+	}
+	model, ok := exportModels[args[0]]
+	if !ok {
+		return nil, usage("%s %s", command, strings.Join(tables, "|"))
+	}
+	return model, nil
+}
+
+func (c *cli) export(args []string) error {
+	model, err := exportModel("export", args)
+	if err != nil {
+		return err
+	}
+
+	flags := c.flagSet("export " + args[0])
+	format := flags.String("format", export.FormatCSV, "format output: csv atau ndjson")
+	columns := flags.String("columns", "", "kolom yang diekspor dipisah koma, kosong berarti semua kolom")
+	batch := flags.Int("batch", export.DefaultBatchSize, "jumlah baris per query")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	options := export.Options{Format: *format, BatchSize: *batch}
+	if *columns != "" {
+		options.Columns = strings.Split(*columns, ",")
+	}
+
+	count, err := export.Export(c.db, model, c.stdout, options)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "exported %d rows\n", count)
+	return nil
+}
+
+func (c *cli) importRows(args []string) error {
+	model, err := exportModel("import", args)
+	if err != nil {
+		return err
+	}
+
+	flags := c.flagSet("import " + args[0])
+	file := flags.String("file", "", "file CSV atau NDJSON yang di-import")
+	format := flags.String("format", importer.FormatCSV, "format input: csv atau ndjson")
+	batch := flags.Int("batch", importer.DefaultBatchSize, "jumlah baris per insert")
+	onConflict := flags.String("on-conflict", "", "kolom unik untuk upsert dipisah koma, kosong berarti insert biasa")
+	update := flags.String("update", "", "kolom yang di-update saat upsert, kosong berarti semua kolom")
+	dryRun := flags.Bool("dry-run", false, "validasi dan insert di dalam transaction lalu rollback")
+	reportPath := flags.String("report", "", "file CSV untuk baris yang ditolak")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *file == "" {
+		return usage("import %s -file PATH", args[0])
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	options := importer.Options{Format: *format, BatchSize: *batch, DryRun: *dryRun}
+	if *onConflict != "" {
+		options.ConflictColumns = strings.Split(*onConflict, ",")
+	}
+	if *update != "" {
+		options.UpdateColumns = strings.Split(*update, ",")
+	}
+	if *reportPath != "" {
+		report, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+		defer report.Close()
+		options.Report = report
+	}
+
+	result, err := importer.Import(c.db, model, input, options)
+	if err != nil {
+		return err
+	}
+
+	prefix := ""
+	if *dryRun {
+		prefix = "dry run: "
+	}
+	fmt.Fprintf(c.stdout, "%sread %d, imported %d, rejected %d\n", prefix, result.Read, result.Imported, len(result.Rejections))
+	for _, rejection := range result.Rejections {
+		fmt.Fprintf(c.stderr, "line %d: %s\n", rejection.Line, rejection.Reason)
+	}
+	if len(result.Rejections) > 0 {
+		return checkFailed{}
+	}
+	return nil
+}
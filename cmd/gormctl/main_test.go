@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type gormctl struct {
+	t   *testing.T
+	dsn string
+}
+
+func newGormctl(t *testing.T) *gormctl {
+	return &gormctl{t: t, dsn: filepath.Join(t.TempDir(), "gormctl.db")}
+}
+
+func (g *gormctl) run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(append([]string{"-driver", "sqlite", "-dsn", g.dsn}, args...), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func (g *gormctl) mustRun(args ...string) string {
+	code, stdout, stderr := g.run(args...)
+	assert.Equal(g.t, 0, code, stderr)
+	return stdout
+}
+
+func TestMigrate(t *testing.T) {
+	g := newGormctl(t)
+
+	output := g.mustRun("migrate", "status")
+	assert.Contains(t, output, "0001_core")
+	assert.Contains(t, output, "pending")
+
+	output = g.mustRun("migrate", "up", "-steps", "2")
+	assert.Equal(t, "applied 0001_core\napplied 0002_product_prices\n", output)
+
+	code, output, _ := g.run("schema", "check")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, output, "orders")
+	assert.Contains(t, output, "missing table")
+
+	g.mustRun("migrate", "up")
+	assert.Equal(t, "schema ok\n", g.mustRun("schema", "check"))
+	assert.Equal(t, "nothing to migrate\n", g.mustRun("migrate", "up"))
+
+	output = g.mustRun("migrate", "down", "-steps", "2")
+	assert.Equal(t, "reverted 0005_email_verifications\nreverted 0004_guest_book_moderations\n", output)
+	assert.Contains(t, g.mustRun("migrate", "status"), "0004_guest_book_moderations  pending")
+
+	output = g.mustRun("migrate", "down", "-steps", "10")
+	assert.Contains(t, output, "reverted 0001_core")
+	code, _, _ = g.run("schema", "check")
+	assert.Equal(t, 1, code)
+}
+
+func TestUsers(t *testing.T) {
+	g := newGormctl(t)
+	g.mustRun("migrate", "up")
+	g.mustRun("seed")
+	// seed aman dijalankan ulang
+	g.mustRun("seed")
+
+	output := g.mustRun("users", "create", "-id", "1", "-first-name", "Salman", "-password", "rahasia", "-email", "Salman@Example.com")
+	assert.Equal(t, "created user 1\n", output)
+
+	output = g.mustRun("users", "list")
+	assert.Contains(t, output, "salman@example.com")
+	assert.Contains(t, output, "Eko Kurniawan Khannedy")
+	assert.Equal(t, 5, len(strings.Split(strings.TrimSpace(output), "\n")))
+
+	output = g.mustRun("users", "list", "-query", "filter[first_name][like]=Bud&sort=-id")
+	assert.Contains(t, output, "Budi Nugraha")
+	assert.NotContains(t, output, "Salman")
+
+	code, _, stderr := g.run("users", "list", "-query", "filter[password]=rahasia")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "not filterable")
+
+	assert.Equal(t, "deleted user 1\n", g.mustRun("users", "delete", "1"))
+	code, _, _ = g.run("users", "delete", "1")
+	assert.Equal(t, 1, code)
+
+	code, _, _ = g.run("users", "create", "-id", "2")
+	assert.Equal(t, 2, code)
+}
+
+func TestWallet(t *testing.T) {
+	g := newGormctl(t)
+	g.mustRun("migrate", "up")
+	g.mustRun("seed")
+
+	output := g.mustRun("wallet", "list", "-scope", "sultan")
+	assert.Contains(t, output, "seed-1003")
+	assert.NotContains(t, output, "seed-1002")
+
+	output = g.mustRun("wallet", "credit", "1002", "500")
+	assert.Contains(t, output, "500.00")
+
+	output = g.mustRun("wallet", "transfer", "1001", "1002", "1000")
+	assert.Contains(t, output, "999000.00")
+	assert.Contains(t, output, "1500.00")
+
+	code, _, stderr := g.run("wallet", "debit", "1002", "2000")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "insufficient")
+
+	code, _, _ = g.run("wallet", "credit", "1002", "banyak")
+	assert.Equal(t, 2, code)
+
+	code, _, _ = g.run("wallet", "list", "-scope", "kaya")
+	assert.Equal(t, 1, code)
+}
+
+func TestTodos(t *testing.T) {
+	g := newGormctl(t)
+	g.mustRun("migrate", "up")
+	g.mustRun("seed")
+
+	assert.Equal(t, "trashed todo 1001\n", g.mustRun("todos", "trash", "1001"))
+	assert.Equal(t, "restored todo 1001\n", g.mustRun("todos", "restore", "1001"))
+
+	code, _, _ := g.run("todos", "restore", "1001")
+	assert.Equal(t, 1, code)
+
+	g.mustRun("todos", "trash", "1001")
+	assert.Equal(t, "purged 0 todos\n", g.mustRun("todos", "purge"))
+	assert.Equal(t, "purged 1 todos\n", g.mustRun("todos", "purge", "-older-than", "0s"))
+
+	code, _, _ = g.run("todos", "restore", "1001")
+	assert.Equal(t, 1, code)
+}
+
+func TestExport(t *testing.T) {
+	g := newGormctl(t)
+	g.mustRun("migrate", "up")
+	g.mustRun("seed")
+
+	output := g.mustRun("export", "users", "-columns", "id,first_name,last_name", "-batch", "2")
+	assert.Equal(t, "id,first_name,last_name\n1001,Eko,Khannedy\n", output[:len("id,first_name,last_name\n1001,Eko,Khannedy\n")])
+	assert.Equal(t, 4, strings.Count(output, "\n"))
+	assert.NotContains(t, g.mustRun("export", "users"), "rahasia")
+
+	output = g.mustRun("export", "wallets", "-format", "ndjson", "-columns", "id,balance")
+	assert.Contains(t, output, `{"id":"seed-1001","balance":1000000}`+"\n")
+
+	code, _, stderr := g.run("export", "passwords")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "user_logs")
+
+	code, _, stderr = g.run("export", "users", "-format", "xml")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "unknown export format")
+}
+
+func TestImport(t *testing.T) {
+	g := newGormctl(t)
+	g.mustRun("migrate", "up")
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "users.csv")
+	report := filepath.Join(dir, "rejected.csv")
+	err := os.WriteFile(input, []byte("id,first_name,password\n1,Eko,rahasia\nx,Salah,rahasia\n2,Budi,rahasia\n"), 0o644)
+	assert.Nil(t, err)
+
+	code, output, stderr := g.run("import", "users", "-file", input, "-dry-run")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "dry run: read 3, imported 2, rejected 1\n", output)
+	assert.Equal(t, "line 3: invalid value for id: x\n", stderr)
+	assert.Equal(t, 1, strings.Count(g.mustRun("export", "users", "-columns", "id"), "\n"))
+
+	code, output, _ = g.run("import", "users", "-file", input, "-report", report)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "read 3, imported 2, rejected 1\n", output)
+	content, err := os.ReadFile(report)
+	assert.Nil(t, err)
+	assert.Equal(t, "line,reason,record\n3,invalid value for id: x,\"x,Salah,rahasia\"\n", string(content))
+
+	err = os.WriteFile(input, []byte("id,first_name,password\n1,Eko Baru,rahasia\n"), 0o644)
+	assert.Nil(t, err)
+	assert.Equal(t, "read 1, imported 1, rejected 0\n", g.mustRun("import", "users", "-file", input, "-on-conflict", "id", "-update", "first_name"))
+	assert.Contains(t, g.mustRun("export", "users", "-columns", "id,first_name"), "1,Eko Baru\n")
+
+	code, _, _ = g.run("import", "users")
+	assert.Equal(t, 2, code)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	g := newGormctl(t)
+	code, _, stderr := g.run("deploy")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "unknown command")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/grpcapi"
+
+	"google.golang.org/grpc"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "alamat gRPC server")
+	flag.Parse()
+
+	config, err := belajargolanggorm.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := belajargolanggorm.Connect(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(grpcapi.SessionInterceptor))
+	grpcapi.Register(server, db)
+
+	log.Printf("listening on %s", *addr)
+	log.Fatal(server.Serve(listener))
+}
@@ -0,0 +1,243 @@
+package belajargolanggorm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"belajar-golang-gorm/dblog"
+	"belajar-golang-gorm/dbretry"
+	"belajar-golang-gorm/dbrouter"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Config adalah konfigurasi koneksi database, dipakai oleh test, CLI dan server
+type Config struct {
+	Driver          string
+	DSN             string
+	LogLevel        logger.LogLevel
+	LogFormat       string        // text memakai logger bawaan GORM, json memakai dblog
+	SlowThreshold   time.Duration // hanya untuk LogFormatJSON, 0 berarti dblog.DefaultSlowThreshold
+	PrepareStmt     bool
+	MaxIdleConns    int
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ReplicaDSNs memakai driver yang sama dengan primary, query baca diarahkan ke sini lewat dbrouter
+	ReplicaDSNs                []string
+	ReplicaHealthCheckInterval time.Duration
+
+	// ConnectRetry dipakai saat membuka koneksi, DeadlockRetry untuk query baca yang kena deadlock
+	ConnectRetry  dbretry.Policy
+	DeadlockRetry dbretry.Policy
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Driver:          DriverMySQL,
+		DSN:             "root:@tcp(127.0.0.1:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local",
+		LogLevel:        logger.Info,
+		LogFormat:       LogFormatText,
+		PrepareStmt:     true,
+		MaxIdleConns:    10,
+		MaxOpenConns:    100,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 10 * time.Minute,
+
+		ReplicaHealthCheckInterval: 30 * time.Second,
+
+		ConnectRetry:  dbretry.DefaultPolicy,
+		DeadlockRetry: dbretry.DeadlockPolicy,
+	}
+}
+
+// ConfigFromEnv membaca DB_DRIVER, DB_DSN (atau DB_USER, DB_PASSWORD, DB_HOST, DB_PORT, DB_NAME),
+// DB_LOG_LEVEL, DB_LOG_FORMAT, DB_SLOW_THRESHOLD, DB_MAX_IDLE_CONNS, DB_MAX_OPEN_CONNS,
+// DB_REPLICA_DSNS (dipisah koma) dan DB_CONNECT_ATTEMPTS, yang kosong memakai DefaultConfig
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+
+	if driver := os.Getenv("DB_DRIVER"); driver != "" {
+		config.Driver = driver
+	}
+
+	if dsn := os.Getenv("DB_DSN"); dsn != "" {
+		config.DSN = dsn
+	} else if os.Getenv("DB_HOST") != "" || os.Getenv("DB_NAME") != "" || os.Getenv("DB_USER") != "" {
+		config.DSN = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			envOr("DB_USER", "root"), os.Getenv("DB_PASSWORD"), envOr("DB_HOST", "127.0.0.1"),
+			envOr("DB_PORT", "3306"), envOr("DB_NAME", "belajar_golang_gorm"))
+	}
+
+	if level := os.Getenv("DB_LOG_LEVEL"); level != "" {
+		levels := map[string]logger.LogLevel{"silent": logger.Silent, "error": logger.Error, "warn": logger.Warn, "info": logger.Info}
+		value, ok := levels[level]
+		if !ok {
+			return config, fmt.Errorf("invalid DB_LOG_LEVEL %q", level)
+		}
+		config.LogLevel = value
+	}
+
+	if format := os.Getenv("DB_LOG_FORMAT"); format != "" {
+		if format != LogFormatText && format != LogFormatJSON {
+			return config, fmt.Errorf("invalid DB_LOG_FORMAT %q", format)
+		}
+		config.LogFormat = format
+	}
+
+	if threshold := os.Getenv("DB_SLOW_THRESHOLD"); threshold != "" {
+		value, err := time.ParseDuration(threshold)
+		if err != nil {
+			return config, fmt.Errorf("invalid DB_SLOW_THRESHOLD %q", threshold)
+		}
+		config.SlowThreshold = value
+	}
+
+	if replicas := os.Getenv("DB_REPLICA_DSNS"); replicas != "" {
+		for _, dsn := range strings.Split(replicas, ",") {
+			if dsn = strings.TrimSpace(dsn); dsn != "" {
+				config.ReplicaDSNs = append(config.ReplicaDSNs, dsn)
+			}
+		}
+	}
+
+	var err error
+	if config.MaxIdleConns, err = envInt("DB_MAX_IDLE_CONNS", config.MaxIdleConns); err != nil {
+		return config, err
+	}
+	if config.MaxOpenConns, err = envInt("DB_MAX_OPEN_CONNS", config.MaxOpenConns); err != nil {
+		return config, err
+	}
+	if config.ConnectRetry.Attempts, err = envInt("DB_CONNECT_ATTEMPTS", config.ConnectRetry.Attempts); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+func envOr(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return number, nil
+}
+
+func (c Config) Dialector() (gorm.Dialector, error) {
+	return c.dialector(c.DSN)
+}
+
+func (c Config) dialector(dsn string) (gorm.Dialector, error) {
+	switch c.Driver {
+	case DriverMySQL:
+		return mysql.Open(dsn), nil
+	case DriverSQLite:
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported driver %q", c.Driver)
+	}
+}
+
+// Connect membuka koneksi dan mengatur connection pool sesuai config,
+// kalau database belum siap koneksi dicoba ulang sesuai config.ConnectRetry
+func Connect(config Config) (*gorm.DB, error) {
+	dialector, err := config.Dialector()
+	if err != nil {
+		return nil, err
+	}
+	replicas := make([]gorm.Dialector, len(config.ReplicaDSNs))
+	for i, dsn := range config.ReplicaDSNs {
+		replicas[i], err = config.dialector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("replica-%d: %w", i+1, err)
+		}
+	}
+
+	var log logger.Interface = logger.Default.LogMode(config.LogLevel)
+	if config.LogFormat == LogFormatJSON {
+		log = dblog.New(slog.New(slog.NewJSONHandler(os.Stdout, nil)), dblog.Options{
+			LogLevel:      config.LogLevel,
+			SlowThreshold: config.SlowThreshold,
+		})
+	}
+
+	var db *gorm.DB
+	err = dbretry.Do(context.Background(), config.ConnectRetry, dbretry.IsConnectionError, func() error {
+		var err error
+		db, err = gorm.Open(dialector, &gorm.Config{
+			Logger:                 log,
+			SkipDefaultTransaction: true, // untuk menghindari auto transaction
+			PrepareStmt:            config.PrepareStmt,
+		})
+		if err != nil && db != nil {
+			// gorm.Open tidak menutup pool kalau ping gagal
+			if sqlDB, dbErr := db.DB(); dbErr == nil {
+				_ = sqlDB.Close()
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DeadlockRetry.Attempts > 1 {
+		if err := db.Use(dbretry.NewReads(config.DeadlockRetry)); err != nil {
+			return nil, err
+		}
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	pools := []*sql.DB{sqlDB}
+
+	if len(replicas) > 0 {
+		router := dbrouter.New(dbrouter.Options{HealthCheckInterval: config.ReplicaHealthCheckInterval}, replicas...)
+		if err := db.Use(router); err != nil {
+			return nil, err
+		}
+		for _, replica := range router.Replicas() {
+			pools = append(pools, replica.DB())
+		}
+	}
+
+	for _, pool := range pools {
+		pool.SetMaxIdleConns(config.MaxIdleConns)
+		pool.SetMaxOpenConns(config.MaxOpenConns)
+		pool.SetConnMaxLifetime(config.ConnMaxLifetime)
+		pool.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,85 @@
+package belajargolanggorm
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"belajar-golang-gorm/dbretry"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/logger"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	for _, key := range []string{"DB_DRIVER", "DB_DSN", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_LOG_LEVEL", "DB_LOG_FORMAT", "DB_SLOW_THRESHOLD", "DB_MAX_IDLE_CONNS", "DB_MAX_OPEN_CONNS", "DB_REPLICA_DSNS", "DB_CONNECT_ATTEMPTS"} {
+		t.Setenv(key, "")
+	}
+
+	config, err := ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultConfig(), config)
+
+	t.Setenv("DB_HOST", "db.internal")
+	t.Setenv("DB_USER", "app")
+	t.Setenv("DB_PASSWORD", "rahasia")
+	t.Setenv("DB_LOG_LEVEL", "warn")
+	t.Setenv("DB_MAX_OPEN_CONNS", "20")
+	t.Setenv("DB_LOG_FORMAT", LogFormatJSON)
+	t.Setenv("DB_SLOW_THRESHOLD", "500ms")
+	config, err = ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, "app:rahasia@tcp(db.internal:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local", config.DSN)
+	assert.Equal(t, logger.Warn, config.LogLevel)
+	assert.Equal(t, 20, config.MaxOpenConns)
+	assert.Equal(t, LogFormatJSON, config.LogFormat)
+	assert.Equal(t, 500*time.Millisecond, config.SlowThreshold)
+
+	t.Setenv("DB_DSN", "file.db")
+	t.Setenv("DB_DRIVER", DriverSQLite)
+	config, err = ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, "file.db", config.DSN)
+	assert.Equal(t, DriverSQLite, config.Driver)
+
+	t.Setenv("DB_REPLICA_DSNS", "replica-1.db, replica-2.db")
+	config, err = ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"replica-1.db", "replica-2.db"}, config.ReplicaDSNs)
+
+	t.Setenv("DB_CONNECT_ATTEMPTS", "10")
+	config, err = ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, 10, config.ConnectRetry.Attempts)
+
+	t.Setenv("DB_MAX_OPEN_CONNS", "banyak")
+	_, err = ConfigFromEnv()
+	assert.NotNil(t, err)
+
+	_, err = Connect(Config{Driver: "postgres"})
+	assert.NotNil(t, err)
+}
+
+func TestConnectRetry(t *testing.T) {
+	attempts := 0
+	config := DefaultConfig()
+	config.DSN = "root:@tcp(127.0.0.1:1)/belajar_golang_gorm?timeout=1s"
+	config.LogLevel = logger.Silent
+	config.ConnectRetry = dbretry.Policy{Attempts: 3, Initial: time.Millisecond}
+	config.ConnectRetry.OnRetry = func(attempt int, err error, delay time.Duration) {
+		attempts = attempt
+	}
+
+	// koneksi ditolak dicoba ulang
+	_, err := Connect(config)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, attempts)
+
+	// file database yang tidak bisa dibuka tidak dicoba ulang
+	attempts = 0
+	config.Driver = DriverSQLite
+	config.DSN = filepath.Join(t.TempDir(), "tidak-ada", "test.db")
+	_, err = Connect(config)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, attempts)
+}
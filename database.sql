@@ -75,4 +75,120 @@ create table user_like_product(
     primary key (user_id, product_id),
     foreign key (user_id) references users(id) on delete cascade,
     foreign key (product_id) references products(id) on delete cascade
-) engine=InnoDB default charset=utf8mb4;
\ No newline at end of file
+) engine=InnoDB default charset=utf8mb4;
+
+create table product_prices(
+    id bigint not null auto_increment,
+    product_id bigint not null,
+    price bigint not null,
+    effective_from datetime(3) not null,
+    effective_to datetime(3),
+    created_at timestamp not null default current_timestamp,
+    primary key (id),
+    index (product_id, effective_from),
+    foreign key (product_id) references products(id) on delete cascade
+) engine=InnoDB default charset=utf8mb4;
+
+insert into product_prices (product_id, price, effective_from)
+select id, price, created_at from products;
+
+
+alter table products
+    add column stock int not null default 0 after price;
+
+create table orders(
+    id bigint not null auto_increment,
+    user_id int not null,
+    wallet_id varchar(100) not null,
+    address_id bigint not null,
+    shipping_address varchar(100) not null,
+    status varchar(20) not null,
+    total bigint not null,
+    created_at timestamp not null default current_timestamp,
+    updated_at timestamp not null default current_timestamp on update current_timestamp,
+    primary key (id),
+    foreign key (user_id) references users(id),
+    foreign key (wallet_id) references wallets(id),
+    foreign key (address_id) references addresses(id)
+) engine=InnoDB default charset=utf8mb4;
+
+create table order_items(
+    id bigint not null auto_increment,
+    order_id bigint not null,
+    product_id bigint not null,
+    product_name varchar(100) not null,
+    price bigint not null,
+    quantity int not null,
+    subtotal bigint not null,
+    created_at timestamp not null default current_timestamp,
+    primary key (id),
+    foreign key (order_id) references orders(id) on delete cascade,
+    foreign key (product_id) references products(id)
+) engine=InnoDB default charset=utf8mb4;
+
+
+alter table products
+    add column like_count bigint not null default 0 after stock;
+
+alter table user_like_product
+    add column liked_at timestamp not null default current_timestamp;
+
+update products set like_count = (
+    select count(*) from user_like_product where user_like_product.product_id = products.id
+);
+
+
+create fulltext index idx_products_fulltext on products (name);
+create fulltext index idx_users_fulltext on users (first_name, middle_name, last_name);
+create fulltext index idx_guest_books_fulltext on guest_books (message);
+
+
+alter table guest_books
+    add column status varchar(20) not null default 'pending' after message,
+    add column moderated_by varchar(100) after status,
+    add column moderated_at datetime(3) after moderated_by,
+    add index (status);
+
+-- guest book lama sudah tampil di publik sebelum ada moderasi
+update guest_books set status = 'approved';
+
+create table guest_book_moderations(
+    id bigint not null auto_increment,
+    guest_book_id bigint not null,
+    moderator varchar(100) not null,
+    from_status varchar(20) not null,
+    to_status varchar(20) not null,
+    reason text,
+    created_at timestamp not null default current_timestamp,
+    primary key (id),
+    index (guest_book_id)
+) engine=InnoDB default charset=utf8mb4;
+
+
+alter table guest_books
+    add column ip varchar(45) after message,
+    add column message_hash char(64) after ip,
+    add column spam_score double not null default 0 after message_hash,
+    add column spam_reason varchar(255) after spam_score,
+    add index (message_hash),
+    add index idx_guest_books_email_created (email, created_at),
+    add index idx_guest_books_ip_created (ip, created_at);
+
+
+alter table users
+    add column email varchar(100) after last_name,
+    add column email_verified_at datetime(3) after email,
+    add unique index (email);
+
+create table email_verifications(
+    id bigint not null auto_increment,
+    user_id int not null,
+    email varchar(100) not null,
+    token char(64) not null,
+    expires_at datetime(3) not null,
+    confirmed_at datetime(3),
+    created_at timestamp not null default current_timestamp,
+    primary key (id),
+    unique index (token),
+    index (user_id)
+) engine=InnoDB default charset=utf8mb4;
@@ -0,0 +1,350 @@
+package dbcache
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"belajar-golang-gorm/internal/txhook"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultTTL = time.Minute
+
+	ttlKey    = "dbcache:ttl"
+	tablesKey = "dbcache:tables"
+
+	// key versi di Backend
+	globalVersionKey = "dbcache:version"
+	versionKey       = "dbcache:version:"
+)
+
+// Backend menyimpan hasil query yang sudah di-encode, LRU dipakai kalau tidak diisi
+type Backend interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+type Options struct {
+	Backend Backend
+	// DefaultTTL dipakai scope Cached(0), 0 berarti DefaultTTL
+	DefaultTTL time.Duration
+}
+
+type Stats struct {
+	Hits          uint64
+	Misses        uint64
+	Invalidations uint64
+}
+
+// Cache adalah plugin GORM yang menyimpan hasil query yang memakai scope Cached.
+// Setiap tabel punya versi yang diganti oleh create, update dan delete,
+// versi ikut menjadi bagian dari key sehingga cache lama otomatis tidak terpakai.
+// Versi disimpan di Backend, jadi beberapa instance yang memakai backend yang sama
+// (misalnya Redis) ikut melihat invalidasi dari instance lain
+type Cache struct {
+	backend    Backend
+	defaultTTL time.Duration
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+func New(options Options) *Cache {
+	if options.Backend == nil {
+		options.Backend = NewLRU(DefaultCapacity)
+	}
+	if options.DefaultTTL <= 0 {
+		options.DefaultTTL = DefaultTTL
+	}
+	return &Cache{backend: options.Backend, defaultTTL: options.DefaultTTL}
+}
+
+func (c *Cache) Name() string {
+	return "dbcache"
+}
+
+func (c *Cache) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	next := callback.Query().Get("gorm:query")
+	if next == nil {
+		return errors.New("dbcache: gorm:query callback not found")
+	}
+	// invalidasi dari write di dalam transaksi ditunda sampai commit
+	txhook.Register(db)
+
+	return errors.Join(
+		callback.Query().Replace("gorm:query", c.query(next)),
+		callback.Create().After("gorm:create").Register("dbcache:invalidate_create", c.invalidate),
+		callback.Update().After("gorm:update").Register("dbcache:invalidate_update", c.invalidate),
+		callback.Delete().After("gorm:delete").Register("dbcache:invalidate_delete", c.invalidate),
+		callback.Raw().After("gorm:raw").Register("dbcache:invalidate_raw", c.invalidateRaw),
+	)
+}
+
+// Cached adalah scope untuk mengaktifkan cache pada satu query, ttl 0 berarti Options.DefaultTTL.
+// Tabel dari SQL mentah, join tanpa relasi dan subquery tidak bisa dilacak, query seperti itu
+// hanya di-cache kalau semua tabel yang dibaca disebutkan di tables
+func Cached(ttl time.Duration, tables ...string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Set(ttlKey, ttl).Set(tablesKey, tables)
+	}
+}
+
+func (c *Cache) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load(), Invalidations: c.invalidations.Load()}
+}
+
+// Invalidate mengganti versi tabel, tanpa argumen semua cache tidak berlaku lagi
+func (c *Cache) Invalidate(tables ...string) {
+	if len(tables) == 0 {
+		c.backend.Set(globalVersionKey, newVersion(), 0)
+	}
+	for _, table := range tables {
+		c.backend.Set(versionKey+table, newVersion(), 0)
+	}
+	c.invalidations.Add(1)
+}
+
+// version membaca versi dari backend, versi yang belum ada atau sudah dibuang
+// backend diganti dengan versi baru supaya key lama tidak terpakai lagi
+func (c *Cache) version(key string) string {
+	if value, ok := c.backend.Get(key); ok {
+		return string(value)
+	}
+	version := newVersion()
+	c.backend.Set(key, version, 0)
+	return string(version)
+}
+
+func newVersion() []byte {
+	version := make([]byte, 8)
+	rand.Read(version)
+	return []byte(hex.EncodeToString(version))
+}
+
+func (c *Cache) query(next func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.Get(ttlKey)
+		if !ok || db.Error != nil {
+			next(db)
+			return
+		}
+		// di dalam transaction data bisa belum di-commit, jadi tidak di-cache
+		if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+			next(db)
+			return
+		}
+		if _, ok := db.Statement.Clauses["FOR"]; ok {
+			next(db)
+			return
+		}
+
+		raw := db.Statement.SQL.Len() > 0
+		callbacks.BuildQuerySQL(db)
+		if db.Error != nil || db.Statement.SQL.Len() == 0 {
+			next(db)
+			return
+		}
+
+		tables, tracked := queryTables(db.Statement)
+		declared, _ := db.Get(tablesKey)
+		if extra, _ := declared.([]string); len(extra) > 0 {
+			tables = sortedTables(append(tables, extra...))
+		} else if raw || !tracked {
+			next(db)
+			return
+		}
+
+		key, err := c.key(db.Statement, tables)
+		if err != nil {
+			next(db)
+			return
+		}
+
+		if cached, ok := c.backend.Get(key); ok {
+			var entry entry
+			if json.Unmarshal(cached, &entry) == nil && json.Unmarshal(entry.Dest, db.Statement.Dest) == nil {
+				c.hits.Add(1)
+				db.Statement.RowsAffected = entry.RowsAffected
+				return
+			}
+		}
+
+		c.misses.Add(1)
+		next(db)
+		if db.Error != nil {
+			return
+		}
+
+		dest, err := json.Marshal(db.Statement.Dest)
+		if err != nil {
+			return
+		}
+		encoded, err := json.Marshal(entry{Dest: dest, RowsAffected: db.Statement.RowsAffected})
+		if err != nil {
+			return
+		}
+
+		ttl, _ := value.(time.Duration)
+		if ttl <= 0 {
+			ttl = c.defaultTTL
+		}
+		c.backend.Set(key, encoded, ttl)
+	}
+}
+
+type entry struct {
+	Dest         json.RawMessage `json:"d"`
+	RowsAffected int64           `json:"r"`
+}
+
+var spacePattern = regexp.MustCompile(`\s+`)
+
+// key dibentuk dari SQL yang dinormalisasi, parameter dan versi tabel yang dibaca
+func (c *Cache) key(stmt *gorm.Statement, tables []string) (string, error) {
+	vars, err := json.Marshal(stmt.Vars)
+	if err != nil {
+		return "", err
+	}
+
+	versions := make([]string, len(tables))
+	for i, table := range tables {
+		versions[i] = table + ":" + c.version(versionKey+table)
+	}
+	global := c.version(globalVersionKey)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%s\x00%s\x00%T",
+		stmt.Dialector.Name(), strings.TrimSpace(spacePattern.ReplaceAllString(stmt.SQL.String(), " ")),
+		vars, global, strings.Join(versions, ","), stmt.Dest)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// queryTables mengembalikan tabel yang dibaca query, tracked false kalau ada
+// join tanpa relasi atau subquery yang tabelnya tidak diketahui
+func queryTables(stmt *gorm.Statement) (tables []string, tracked bool) {
+	tracked = stmt.TableExpr == nil || !hasSubquery(reflect.ValueOf(stmt.TableExpr))
+	if stmt.Table != "" {
+		tables = append(tables, stmt.Table)
+	}
+	for _, join := range stmt.Joins {
+		if stmt.Schema == nil {
+			tracked = false
+			continue
+		}
+		relationships := &stmt.Schema.Relationships
+		for _, name := range strings.Split(join.Name, ".") {
+			relation, ok := relationships.Relations[name]
+			if !ok {
+				tracked = false
+				break
+			}
+			tables = append(tables, relation.FieldSchema.Table)
+			relationships = &relation.FieldSchema.Relationships
+		}
+	}
+	for _, c := range stmt.Clauses {
+		if hasSubquery(reflect.ValueOf(c.Expression)) {
+			tracked = false
+		}
+	}
+	return sortedTables(tables), tracked
+}
+
+func sortedTables(tables []string) []string {
+	unique := map[string]bool{}
+	result := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if !unique[table] {
+			unique[table] = true
+			result = append(result, table)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+var (
+	dbType        = reflect.TypeOf(&gorm.DB{})
+	selectPattern = regexp.MustCompile(`(?i)\bselect\b`)
+)
+
+// hasSubquery mencari subquery di dalam expression clause, baik *gorm.DB seperti
+// Where("id IN (?)", subquery) maupun SELECT yang ditulis langsung di SQL
+func hasSubquery(value reflect.Value) bool {
+	if !value.IsValid() {
+		return false
+	}
+	if value.Type() == dbType {
+		return true
+	}
+
+	switch value.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		return !value.IsNil() && hasSubquery(value.Elem())
+	case reflect.Slice, reflect.Array:
+		switch value.Type().Elem().Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Struct, reflect.Slice:
+			for i := 0; i < value.Len(); i++ {
+				if hasSubquery(value.Index(i)) {
+					return true
+				}
+			}
+		}
+	case reflect.Struct:
+		// nilai biasa seperti time.Time tidak perlu diperiksa
+		if value.Type().PkgPath() != clausePkgPath {
+			return false
+		}
+		// SQL mentah seperti Where("id IN (SELECT ...)") membaca tabel yang tidak diketahui
+		if sql := value.FieldByName("SQL"); sql.Kind() == reflect.String && selectPattern.MatchString(sql.String()) {
+			return true
+		}
+		for i := 0; i < value.NumField(); i++ {
+			if hasSubquery(value.Field(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var clausePkgPath = reflect.TypeOf(clause.Expr{}).PkgPath()
+
+func (c *Cache) invalidate(db *gorm.DB) {
+	table := db.Statement.Table
+	if table == "" && db.Statement.Schema != nil {
+		table = db.Statement.Schema.Table
+	}
+	txhook.AfterCommit(db, func() {
+		if table != "" {
+			c.Invalidate(table)
+		} else {
+			c.Invalidate()
+		}
+	})
+}
+
+// Exec dengan SQL bebas tidak diketahui tabelnya, jadi semua cache tidak berlaku
+func (c *Cache) invalidateRaw(db *gorm.DB) {
+	sql := strings.ToUpper(strings.TrimSpace(db.Statement.SQL.String()))
+	if strings.HasPrefix(sql, "SELECT") {
+		return
+	}
+	txhook.AfterCommit(db, func() { c.Invalidate() })
+}
@@ -0,0 +1,235 @@
+package dbcache_test
+
+import (
+	"testing"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/dbcache"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type User = belajargolanggorm.User
+type Product = belajargolanggorm.Product
+
+func setup(t *testing.T) (*gorm.DB, *dbcache.Cache) {
+	db := testdb.Open(t)
+	assert.Nil(t, db.Create(&[]User{
+		{ID: 1, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Eko"}},
+		{ID: 3, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Budi"}},
+	}).Error)
+	assert.Nil(t, db.Create(&[]Product{{ID: 1, Name: "Kopi", Price: 10000}, {ID: 2, Name: "Teh", Price: 5000}}).Error)
+
+	cache := dbcache.New(dbcache.Options{})
+	assert.Nil(t, db.Use(cache))
+	return db, cache
+}
+
+// rename mengubah data tanpa lewat callback GORM, cache tidak tahu ada perubahan
+func rename(t *testing.T, db *gorm.DB, id int, name string) {
+	sqlDB, err := db.DB()
+	assert.Nil(t, err)
+	_, err = sqlDB.Exec("UPDATE users SET first_name = ? WHERE id = ?", name, id)
+	assert.Nil(t, err)
+}
+
+func take(t *testing.T, db *gorm.DB, id int) User {
+	var user User
+	assert.Nil(t, db.Scopes(dbcache.Cached(0)).Take(&user, "id = ?", id).Error)
+	return user
+}
+
+func TestCachedTake(t *testing.T) {
+	db, cache := setup(t)
+
+	assert.Equal(t, "Budi", take(t, db, 3).Name.FirstName)
+	rename(t, db, 3, "Joko")
+
+	user := take(t, db, 3)
+	assert.Equal(t, "Budi", user.Name.FirstName)
+	assert.Equal(t, 3, user.ID)
+	assert.Equal(t, dbcache.Stats{Hits: 1, Misses: 1}, cache.Stats())
+
+	// parameter berbeda adalah key berbeda
+	assert.Equal(t, "Eko", take(t, db, 1).Name.FirstName)
+
+	// tanpa scope Cached query selalu ke database
+	var fresh User
+	assert.Nil(t, db.Take(&fresh, "id = ?", 3).Error)
+	assert.Equal(t, "Joko", fresh.Name.FirstName)
+}
+
+func TestInvalidateOnWrite(t *testing.T) {
+	db, cache := setup(t)
+
+	assert.Equal(t, "Budi", take(t, db, 3).Name.FirstName)
+	assert.Nil(t, db.Model(&User{}).Where("id = ?", 3).Update("first_name", "Joko").Error)
+	assert.Equal(t, "Joko", take(t, db, 3).Name.FirstName)
+
+	assert.Nil(t, db.Create(&User{ID: 4, Password: "rahasia"}).Error)
+	var users []User
+	assert.Nil(t, db.Scopes(dbcache.Cached(0)).Find(&users).Error)
+	assert.Equal(t, 3, len(users))
+
+	assert.Nil(t, db.Delete(&User{}, 4).Error)
+	assert.Nil(t, db.Scopes(dbcache.Cached(0)).Find(&users).Error)
+	assert.Equal(t, 2, len(users))
+
+	// perubahan tabel lain tidak membuang cache users
+	take(t, db, 3)
+	stats := cache.Stats()
+	assert.Nil(t, db.Model(&Product{}).Where("id = ?", 1).Update("price", 12000).Error)
+	take(t, db, 3)
+	assert.Equal(t, stats.Hits+1, cache.Stats().Hits)
+
+	// Exec bebas membuang semua cache
+	assert.Nil(t, db.Exec("UPDATE users SET first_name = ? WHERE id = ?", "Agus", 3).Error)
+	assert.Equal(t, "Agus", take(t, db, 3).Name.FirstName)
+
+	rename(t, db, 3, "Andi")
+	cache.Invalidate("users")
+	assert.Equal(t, "Andi", take(t, db, 3).Name.FirstName)
+}
+
+func TestCachedListing(t *testing.T) {
+	db, cache := setup(t)
+
+	list := func() []Product {
+		var products []Product
+		err := db.Scopes(dbcache.Cached(time.Minute)).Where("price > ?", 1000).Order("price desc").Find(&products).Error
+		assert.Nil(t, err)
+		return products
+	}
+
+	assert.Equal(t, 1, list()[0].ID)
+	products := list()
+	assert.Equal(t, 2, len(products))
+	assert.Equal(t, "Kopi", products[0].Name)
+	assert.Equal(t, int64(10000), products[0].Price)
+	assert.Equal(t, uint64(1), cache.Stats().Hits)
+
+	// record not found tidak di-cache
+	var user User
+	assert.Equal(t, gorm.ErrRecordNotFound, db.Scopes(dbcache.Cached(0)).Take(&user, "id = ?", 99).Error)
+	assert.Equal(t, gorm.ErrRecordNotFound, db.Scopes(dbcache.Cached(0)).Take(&user, "id = ?", 99).Error)
+	assert.Equal(t, uint64(1), cache.Stats().Hits)
+}
+
+func TestTransactionNotCached(t *testing.T) {
+	db, cache := setup(t)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		take(t, tx, 3)
+		take(t, tx, 3)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, dbcache.Stats{}, cache.Stats())
+}
+
+func TestUntrackedTablesNotCached(t *testing.T) {
+	db, cache := setup(t)
+	assert.Nil(t, db.Create(&belajargolanggorm.Wallet{ID: "1", UserId: 3, Balance: 1000}).Error)
+
+	richUsers := func() []User {
+		var users []User
+		err := db.Scopes(dbcache.Cached(0)).Joins("JOIN wallets ON wallets.user_id = users.id").
+			Where("wallets.balance > ?", 500).Find(&users).Error
+		assert.Nil(t, err)
+		return users
+	}
+	assert.Equal(t, 1, len(richUsers()))
+	assert.Nil(t, db.Model(&belajargolanggorm.Wallet{}).Where("id = ?", "1").Update("balance", 100).Error)
+	assert.Equal(t, 0, len(richUsers()))
+
+	var users []User
+	subquery := db.Model(&belajargolanggorm.Wallet{}).Select("user_id")
+	assert.Nil(t, db.Scopes(dbcache.Cached(0)).Where("id IN (?)", subquery).Find(&users).Error)
+	assert.Nil(t, db.Scopes(dbcache.Cached(0)).Raw("SELECT * FROM users").Find(&users).Error)
+	assert.Nil(t, db.Scopes(dbcache.Cached(0)).Where("id IN (SELECT user_id FROM wallets)").Find(&users).Error)
+	assert.Equal(t, dbcache.Stats{Invalidations: 2}, cache.Stats())
+
+	// tabel yang disebutkan ikut menjadi bagian dari key
+	declared := func() []User {
+		var users []User
+		err := db.Scopes(dbcache.Cached(0, "wallets")).Where("id IN (?)", subquery).Find(&users).Error
+		assert.Nil(t, err)
+		return users
+	}
+	assert.Equal(t, 1, len(declared()))
+	assert.Equal(t, 1, len(declared()))
+	assert.Equal(t, uint64(1), cache.Stats().Hits)
+	assert.Nil(t, db.Delete(&belajargolanggorm.Wallet{}, "id = ?", "1").Error)
+	assert.Equal(t, 0, len(declared()))
+}
+
+func TestSharedBackend(t *testing.T) {
+	db, _ := setup(t)
+	backend := dbcache.NewLRU(0)
+
+	// dua instance aplikasi memakai database dan backend yang sama
+	open := func() *gorm.DB {
+		instance, err := gorm.Open(db.Dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		assert.Nil(t, err)
+		assert.Nil(t, instance.Use(dbcache.New(dbcache.Options{Backend: backend})))
+		return instance
+	}
+	first, second := open(), open()
+
+	assert.Equal(t, "Budi", take(t, first, 3).Name.FirstName)
+	assert.Nil(t, second.Model(&User{}).Where("id = ?", 3).Update("first_name", "Joko").Error)
+	assert.Equal(t, "Joko", take(t, first, 3).Name.FirstName)
+}
+
+func TestInvalidateAfterCommit(t *testing.T) {
+	db, cache := setup(t)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", 3).Update("first_name", "Joko").Error; err != nil {
+			return err
+		}
+		// koneksi lain masih membaca data lama selama transaksi belum di-commit
+		assert.Equal(t, "Budi", take(t, db, 3).Name.FirstName)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "Joko", take(t, db, 3).Name.FirstName)
+
+	// transaksi yang di-rollback tidak membuang cache
+	stats := cache.Stats()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		tx.Model(&User{}).Where("id = ?", 3).Update("first_name", "Agus")
+		return gorm.ErrInvalidData
+	})
+	assert.Equal(t, gorm.ErrInvalidData, err)
+	assert.Equal(t, "Joko", take(t, db, 3).Name.FirstName)
+	assert.Equal(t, stats.Hits+1, cache.Stats().Hits)
+}
+
+func TestLRU(t *testing.T) {
+	lru := dbcache.NewLRU(2)
+	lru.Set("a", []byte("1"), 0)
+	lru.Set("b", []byte("2"), 0)
+	_, ok := lru.Get("a")
+	assert.True(t, ok)
+
+	lru.Set("c", []byte("3"), 0)
+	_, ok = lru.Get("b")
+	assert.False(t, ok)
+	value, ok := lru.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, 2, lru.Len())
+
+	lru.Set("d", []byte("4"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok = lru.Get("d")
+	assert.False(t, ok)
+
+	lru.Delete("a")
+	assert.Equal(t, 0, lru.Len())
+}
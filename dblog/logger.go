@@ -0,0 +1,155 @@
+package dblog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+const (
+	DefaultSlowThreshold = 200 * time.Millisecond
+	Redacted             = "[REDACTED]"
+)
+
+// DefaultRedactColumns adalah kolom yang nilainya tidak pernah ditulis ke log
+var DefaultRedactColumns = []string{"password", "token"}
+
+type Options struct {
+	// LogLevel 0 berarti logger.Info, sama dengan OpenConnection
+	LogLevel logger.LogLevel
+	// SlowThreshold 0 berarti DefaultSlowThreshold, negatif berarti tidak ada slow query
+	SlowThreshold time.Duration
+	// SampleRate antara 0 dan 1 untuk query normal, slow query dan error selalu ditulis.
+	// 0 atau >= 1 berarti semua query ditulis
+	SampleRate float64
+	// Random dipakai untuk sampling, bisa diganti di test
+	Random func() float64
+	// RedactColumns nil berarti DefaultRedactColumns
+	RedactColumns []string
+	// HideParams menulis SQL dengan placeholder ? tanpa nilai parameter sama sekali
+	HideParams                bool
+	IgnoreRecordNotFoundError bool
+}
+
+// Logger mengimplementasikan logger.Interface GORM dan menulis log terstruktur lewat slog
+type Logger struct {
+	slog    *slog.Logger
+	options Options
+	redact  map[string]bool
+}
+
+func New(log *slog.Logger, options Options) *Logger {
+	if options.LogLevel == 0 {
+		options.LogLevel = logger.Info
+	}
+	if options.SlowThreshold == 0 {
+		options.SlowThreshold = DefaultSlowThreshold
+	}
+	if options.Random == nil {
+		options.Random = rand.Float64
+	}
+	if options.RedactColumns == nil {
+		options.RedactColumns = DefaultRedactColumns
+	}
+
+	redact := map[string]bool{}
+	for _, column := range options.RedactColumns {
+		redact[strings.ToLower(column)] = true
+	}
+	return &Logger{slog: log, options: options, redact: redact}
+}
+
+func (l *Logger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.options.LogLevel = level
+	return &clone
+}
+
+func (l *Logger) Info(ctx context.Context, message string, data ...interface{}) {
+	if l.options.LogLevel >= logger.Info {
+		l.slog.InfoContext(ctx, fmt.Sprintf(message, data...), "caller", utils.FileWithLineNum())
+	}
+}
+
+func (l *Logger) Warn(ctx context.Context, message string, data ...interface{}) {
+	if l.options.LogLevel >= logger.Warn {
+		l.slog.WarnContext(ctx, fmt.Sprintf(message, data...), "caller", utils.FileWithLineNum())
+	}
+}
+
+func (l *Logger) Error(ctx context.Context, message string, data ...interface{}) {
+	if l.options.LogLevel >= logger.Error {
+		l.slog.ErrorContext(ctx, fmt.Sprintf(message, data...), "caller", utils.FileWithLineNum())
+	}
+}
+
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.options.LogLevel <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	slow := l.options.SlowThreshold > 0 && elapsed > l.options.SlowThreshold
+
+	var level slog.Level
+	switch {
+	case err != nil && l.options.LogLevel >= logger.Error &&
+		!(l.options.IgnoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound)):
+		level = slog.LevelError
+	case slow && l.options.LogLevel >= logger.Warn:
+		level = slog.LevelWarn
+	case l.options.LogLevel >= logger.Info:
+		if rate := l.options.SampleRate; rate > 0 && rate < 1 && l.options.Random() >= rate {
+			return
+		}
+		level = slog.LevelInfo
+	default:
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Nanoseconds())/1e6),
+		slog.String("caller", utils.FileWithLineNum()),
+	}
+	if slow {
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.slog.LogAttrs(ctx, level, "query", attrs...)
+}
+
+// ParamsFilter dipanggil GORM sebelum SQL dirangkai dengan parameter untuk log,
+// nilai untuk kolom seperti password diganti dengan [REDACTED]
+func (l *Logger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.options.HideParams {
+		return sql, nil
+	}
+
+	columns := placeholderColumns(sql)
+	var filtered []interface{}
+	for i := range params {
+		if i < len(columns) && l.redact[columns[i]] {
+			if filtered == nil {
+				filtered = append([]interface{}{}, params...)
+			}
+			filtered[i] = Redacted
+		}
+	}
+	if filtered == nil {
+		return sql, params
+	}
+	return sql, filtered
+}
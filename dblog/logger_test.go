@@ -0,0 +1,194 @@
+package dblog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/dblog"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type User = belajargolanggorm.User
+
+func withLogger(t *testing.T, l logger.Interface) *gorm.DB {
+	return testdb.Open(t).Session(&gorm.Session{Logger: l})
+}
+
+func TestJSONOutput(t *testing.T) {
+	var buffer bytes.Buffer
+	db := withLogger(t, dblog.New(slog.New(slog.NewJSONHandler(&buffer, nil)), dblog.Options{}))
+
+	err := db.Create(&User{ID: 1, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Eko"}}).Error
+	assert.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equal(t, 1, len(lines))
+
+	var record map[string]interface{}
+	err = json.Unmarshal([]byte(lines[0]), &record)
+	assert.Nil(t, err)
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "query", record["msg"])
+	assert.Equal(t, float64(1), record["rows"])
+	assert.Contains(t, record, "duration_ms")
+	assert.Contains(t, record["caller"], "logger_test.go")
+	assert.Contains(t, record["sql"], "INSERT INTO `users`")
+	assert.Contains(t, record["sql"], "[REDACTED]")
+	assert.NotContains(t, record["sql"], "rahasia")
+	assert.Contains(t, record["sql"], "Eko")
+}
+
+func TestRedactUpdateAndWhere(t *testing.T) {
+	l, sink := dblog.NewMemory(dblog.Options{})
+	db := withLogger(t, l)
+
+	assert.Nil(t, db.Create(&User{ID: 1, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Eko"}}).Error)
+	assert.Nil(t, db.Model(&User{ID: 1}).Updates(map[string]interface{}{"first_name": "Budi", "password": "baru"}).Error)
+	var users []User
+	assert.Nil(t, db.Where("first_name = ? AND password = ?", "Budi", "baru").Find(&users).Error)
+	assert.Equal(t, 1, len(users))
+
+	assert.Nil(t, db.Create(&belajargolanggorm.EmailVerification{UserId: 1, Email: "eko@example.com", TokenHash: "token-rahasia", ExpiresAt: time.Now()}).Error)
+
+	for _, query := range sink.Queries() {
+		assert.NotContains(t, query, "rahasia")
+		assert.NotContains(t, query, "baru")
+	}
+	assert.Contains(t, sink.Find("UPDATE")[0].SQL, "Budi")
+	assert.Contains(t, sink.Find("SELECT")[0].SQL, `password = "[REDACTED]"`)
+}
+
+func TestParamsFilter(t *testing.T) {
+	l := dblog.New(slog.New(dblog.NewSink()), dblog.Options{RedactColumns: []string{"password", "secret"}})
+	ctx := context.Background()
+
+	cases := []struct {
+		sql      string
+		params   []interface{}
+		expected []interface{}
+	}{
+		{
+			"INSERT INTO `users` (`id`,`password`,`first_name`) VALUES (?,?,?),(?,?,?) ON CONFLICT DO NOTHING",
+			[]interface{}{1, "a", "Eko", 2, "b", "Budi"},
+			[]interface{}{1, dblog.Redacted, "Eko", 2, dblog.Redacted, "Budi"},
+		},
+		{
+			"UPDATE `users` SET `password`=?,`updated_at`=? WHERE `id` = ?",
+			[]interface{}{"x", "now", 1},
+			[]interface{}{dblog.Redacted, "now", 1},
+		},
+		{
+			"SELECT * FROM users WHERE users.secret IN (?,?) AND name LIKE ? LIMIT ?",
+			[]interface{}{"a", "b", "%e%", 1},
+			[]interface{}{dblog.Redacted, dblog.Redacted, "%e%", 1},
+		},
+		{
+			"SELECT * FROM users WHERE note = 'password = ?' AND id = ?",
+			[]interface{}{5},
+			[]interface{}{5},
+		},
+	}
+
+	for _, c := range cases {
+		_, params := l.ParamsFilter(ctx, c.sql, c.params...)
+		assert.Equal(t, c.expected, params, c.sql)
+	}
+
+	hidden := dblog.New(slog.New(dblog.NewSink()), dblog.Options{HideParams: true})
+	sql, params := hidden.ParamsFilter(ctx, "SELECT * FROM users WHERE id = ?", 1)
+	assert.Equal(t, "SELECT * FROM users WHERE id = ?", sql)
+	assert.Nil(t, params)
+}
+
+func TestSlowQueryAndLevel(t *testing.T) {
+	l, sink := dblog.NewMemory(dblog.Options{LogLevel: logger.Warn, SlowThreshold: time.Nanosecond})
+	db := withLogger(t, l)
+
+	var users []User
+	assert.Nil(t, db.Find(&users).Error)
+
+	entries := sink.Entries()
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, slog.LevelWarn, entries[0].Level)
+	assert.True(t, entries[0].Slow)
+
+	// tanpa slow threshold, level Warn tidak menulis query normal
+	l, sink = dblog.NewMemory(dblog.Options{LogLevel: logger.Warn, SlowThreshold: -1})
+	assert.Nil(t, withLogger(t, l).Find(&users).Error)
+	assert.Empty(t, sink.Entries())
+}
+
+func TestErrorLogging(t *testing.T) {
+	l, sink := dblog.NewMemory(dblog.Options{})
+	db := withLogger(t, l)
+
+	var user User
+	err := db.Take(&user, "id = ?", 99).Error
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+	entries := sink.Entries()
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, slog.LevelError, entries[0].Level)
+	assert.Equal(t, "record not found", entries[0].Error)
+
+	l, sink = dblog.NewMemory(dblog.Options{IgnoreRecordNotFoundError: true})
+	_ = withLogger(t, l).Take(&user, "id = ?", 99).Error
+	assert.Equal(t, slog.LevelInfo, sink.Entries()[0].Level)
+
+	// Silent mematikan semua log
+	l, sink = dblog.NewMemory(dblog.Options{})
+	_ = withLogger(t, l.LogMode(logger.Silent)).Take(&user, "id = ?", 99).Error
+	assert.Empty(t, sink.Entries())
+}
+
+func TestSampling(t *testing.T) {
+	values := []float64{0.1, 0.9, 0.2, 0.8}
+	next := 0
+	l, sink := dblog.NewMemory(dblog.Options{
+		SampleRate: 0.5,
+		Random: func() float64 {
+			value := values[next%len(values)]
+			next++
+			return value
+		},
+	})
+	db := withLogger(t, l)
+
+	var users []User
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, db.Find(&users).Error)
+	}
+	assert.Equal(t, 2, len(sink.Entries()))
+
+	// error tidak ikut sampling
+	sink.Reset()
+	for i := 0; i < 4; i++ {
+		_ = db.Take(&User{}, "id = ?", 99).Error
+	}
+	assert.Equal(t, 4, len(sink.Entries()))
+}
+
+func TestSinkWithAttrs(t *testing.T) {
+	sink := dblog.NewSink()
+	log := slog.New(sink).With("request_id", "abc")
+	l := dblog.New(log, dblog.Options{})
+	db := withLogger(t, l)
+
+	var users []User
+	assert.Nil(t, db.Find(&users).Error)
+
+	entries := sink.Entries()
+	assert.Equal(t, "abc", entries[0].Attrs["request_id"])
+	assert.Equal(t, "SELECT * FROM `users`", entries[0].SQL)
+	assert.Equal(t, int64(0), entries[0].Rows)
+}
@@ -0,0 +1,176 @@
+package dblog
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenPlaceholder
+	tokenOpen
+	tokenClose
+	tokenComma
+	tokenOperator
+	tokenOther
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func tokenize(sql string) []token {
+	var tokens []token
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '`' || c == '"':
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				end = len(sql) - i - 1
+			}
+			tokens = append(tokens, token{tokenIdent, strings.ToLower(sql[i+1 : i+1+end])})
+			i += end + 2
+		case c == '\'':
+			// string literal, '' di dalam literal adalah escape
+			j := i + 1
+			for j < len(sql) {
+				if sql[j] == '\'' {
+					if j+1 < len(sql) && sql[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, token{tokenOther, ""})
+			i = j + 1
+		case c == '?':
+			tokens = append(tokens, token{tokenPlaceholder, "?"})
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenOpen, "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenClose, ")"})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokenComma, ","})
+			i++
+		case c == '=' || c == '<' || c == '>' || c == '!':
+			tokens = append(tokens, token{tokenOperator, string(c)})
+			i++
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(sql) && (sql[j] == '_' || sql[j] >= 'a' && sql[j] <= 'z' || sql[j] >= 'A' && sql[j] <= 'Z' || sql[j] >= '0' && sql[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, token{tokenIdent, strings.ToLower(sql[i:j])})
+			i = j
+		default:
+			tokens = append(tokens, token{tokenOther, string(c)})
+			i++
+		}
+	}
+	return tokens
+}
+
+// kata kunci yang boleh ada di antara nama kolom dan placeholder, misalnya `name` NOT LIKE ?
+var comparisonKeywords = map[string]bool{"like": true, "in": true, "not": true, "is": true, "between": true}
+
+// placeholderColumns menebak kolom untuk setiap placeholder ? di SQL buatan GORM:
+// daftar kolom INSERT dipetakan ke VALUES, selain itu dipakai identifier sebelum operator
+func placeholderColumns(sql string) []string {
+	tokens := tokenize(sql)
+
+	var columns []string
+	var insertColumns []string
+	inValues := false
+	depth, position := 0, 0
+
+	for i, t := range tokens {
+		if t.kind == tokenIdent && t.value == "insert" {
+			insertColumns = nil
+			inValues = false
+		}
+		if t.kind == tokenIdent && t.value == "into" && insertColumns == nil {
+			// INSERT INTO `table` (`a`,`b`) VALUES
+			for j := i + 1; j < len(tokens); j++ {
+				if tokens[j].kind == tokenOpen {
+					for k := j + 1; k < len(tokens) && tokens[k].kind != tokenClose; k++ {
+						if tokens[k].kind == tokenIdent {
+							insertColumns = append(insertColumns, tokens[k].value)
+						}
+					}
+					break
+				}
+				if tokens[j].kind != tokenIdent && tokens[j].value != "." {
+					break
+				}
+			}
+		}
+		if t.kind == tokenIdent && t.value == "values" && insertColumns != nil {
+			inValues = true
+			depth, position = 0, 0
+			continue
+		}
+
+		if inValues {
+			switch t.kind {
+			case tokenOpen:
+				depth++
+				if depth == 1 {
+					position = 0
+				}
+			case tokenClose:
+				depth--
+			case tokenComma:
+				if depth == 1 {
+					position++
+				}
+			case tokenIdent:
+				// ON DUPLICATE KEY / ON CONFLICT setelah VALUES
+				if depth == 0 {
+					inValues = false
+				}
+			}
+			if t.kind == tokenPlaceholder {
+				column := ""
+				if depth == 1 && position < len(insertColumns) {
+					column = insertColumns[position]
+				}
+				columns = append(columns, column)
+			}
+			if inValues {
+				continue
+			}
+		}
+
+		if t.kind == tokenPlaceholder {
+			columns = append(columns, columnBefore(tokens, i))
+		}
+	}
+	return columns
+}
+
+func columnBefore(tokens []token, index int) string {
+	for i := index - 1; i >= 0; i-- {
+		t := tokens[i]
+		switch {
+		case t.kind == tokenOperator, t.kind == tokenOpen, t.kind == tokenComma, t.kind == tokenPlaceholder:
+			continue
+		case t.kind == tokenIdent && comparisonKeywords[t.value]:
+			continue
+		case t.kind == tokenIdent:
+			return t.value
+		default:
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package dblog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry adalah satu record log yang ditangkap Sink
+type Entry struct {
+	Time     time.Time
+	Level    slog.Level
+	Message  string
+	SQL      string
+	Rows     int64
+	Duration time.Duration
+	Caller   string
+	Slow     bool
+	Error    string
+	Attrs    map[string]interface{}
+}
+
+type sinkStore struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Sink adalah slog.Handler yang menyimpan log di memory, dipakai di test
+// untuk memeriksa query yang dijalankan
+type Sink struct {
+	store *sinkStore
+	attrs []slog.Attr
+	level slog.Leveler
+}
+
+func NewSink() *Sink {
+	return &Sink{store: &sinkStore{}, level: slog.LevelDebug}
+}
+
+// NewMemory membuat Logger yang menulis ke Sink baru
+func NewMemory(options Options) (*Logger, *Sink) {
+	sink := NewSink()
+	return New(slog.New(sink), options), sink
+}
+
+func (s *Sink) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= s.level.Level()
+}
+
+func (s *Sink) Handle(ctx context.Context, record slog.Record) error {
+	entry := Entry{
+		Time:    record.Time,
+		Level:   record.Level,
+		Message: record.Message,
+		Attrs:   map[string]interface{}{},
+	}
+
+	collect := func(attr slog.Attr) bool {
+		value := attr.Value.Resolve()
+		entry.Attrs[attr.Key] = value.Any()
+		switch attr.Key {
+		case "sql":
+			entry.SQL = value.String()
+		case "rows":
+			entry.Rows = value.Int64()
+		case "duration_ms":
+			entry.Duration = time.Duration(value.Float64() * float64(time.Millisecond))
+		case "caller":
+			entry.Caller = value.String()
+		case "slow":
+			entry.Slow = value.Bool()
+		case "error":
+			entry.Error = value.String()
+		}
+		return true
+	}
+	for _, attr := range s.attrs {
+		collect(attr)
+	}
+	record.Attrs(collect)
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	s.store.entries = append(s.store.entries, entry)
+	return nil
+}
+
+func (s *Sink) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Sink{store: s.store, attrs: append(append([]slog.Attr{}, s.attrs...), attrs...), level: s.level}
+}
+
+// WithGroup tidak membuat nested key, Sink hanya untuk test
+func (s *Sink) WithGroup(name string) slog.Handler {
+	return s
+}
+
+func (s *Sink) Entries() []Entry {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	return append([]Entry{}, s.store.entries...)
+}
+
+// Queries mengembalikan SQL dari semua entry query
+func (s *Sink) Queries() []string {
+	var queries []string
+	for _, entry := range s.Entries() {
+		if entry.SQL != "" {
+			queries = append(queries, entry.SQL)
+		}
+	}
+	return queries
+}
+
+// Find mengembalikan entry yang SQL-nya mengandung substring
+func (s *Sink) Find(substring string) []Entry {
+	var entries []Entry
+	for _, entry := range s.Entries() {
+		if entry.SQL != "" && strings.Contains(entry.SQL, substring) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (s *Sink) Reset() {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	s.store.entries = nil
+}
@@ -0,0 +1,157 @@
+package dbmetrics
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler menyajikan metrics dengan format teks Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		m.WriteTo(w)
+	})
+}
+
+func (m *Metrics) sortedKeys(values map[key]bool) []key {
+	keys := make([]key, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].table != keys[j].table {
+			return keys[i].table < keys[j].table
+		}
+		return keys[i].operation < keys[j].operation
+	})
+	return keys
+}
+
+func labels(pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+escapeLabel(pairs[i+1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// WriteTo menulis semua metrics, urutannya tetap supaya mudah dibandingkan di test
+func (m *Metrics) WriteTo(writer io.Writer) (int64, error) {
+	w := &countingWriter{writer: bufio.NewWriter(writer)}
+
+	m.mu.Lock()
+	histogramKeys := map[key]bool{}
+	for k := range m.histograms {
+		histogramKeys[k] = true
+	}
+
+	w.printf("# HELP gorm_query_duration_seconds Latency eksekusi SQL per tabel dan operasi.\n")
+	w.printf("# TYPE gorm_query_duration_seconds histogram\n")
+	for _, k := range m.sortedKeys(histogramKeys) {
+		h := m.histograms[k]
+		var cumulative uint64
+		for i, bound := range m.buckets {
+			cumulative += h.counts[i]
+			w.printf("gorm_query_duration_seconds_bucket%s %d\n",
+				labels("table", k.table, "operation", k.operation, "le", formatFloat(bound)), cumulative)
+		}
+		w.printf("gorm_query_duration_seconds_bucket%s %d\n",
+			labels("table", k.table, "operation", k.operation, "le", "+Inf"), h.count)
+		w.printf("gorm_query_duration_seconds_sum%s %s\n", labels("table", k.table, "operation", k.operation), formatFloat(h.sum))
+		w.printf("gorm_query_duration_seconds_count%s %d\n", labels("table", k.table, "operation", k.operation), h.count)
+	}
+
+	// counter error dan rows ditulis untuk semua kombinasi yang pernah terlihat, termasuk yang 0
+	w.printf("# HELP gorm_query_errors_total Jumlah operasi yang gagal, record not found tidak dihitung.\n")
+	w.printf("# TYPE gorm_query_errors_total counter\n")
+	for _, k := range m.sortedKeys(histogramKeys) {
+		w.printf("gorm_query_errors_total%s %d\n", labels("table", k.table, "operation", k.operation), m.errors[k])
+	}
+
+	w.printf("# HELP gorm_rows_affected_total Jumlah baris yang dibaca atau diubah.\n")
+	w.printf("# TYPE gorm_rows_affected_total counter\n")
+	for _, k := range m.sortedKeys(histogramKeys) {
+		w.printf("gorm_rows_affected_total%s %d\n", labels("table", k.table, "operation", k.operation), m.rows[k])
+	}
+
+	pools := make([]string, 0, len(m.pools))
+	for name := range m.pools {
+		pools = append(pools, name)
+	}
+	sort.Strings(pools)
+	poolDBs := m.pools
+	m.mu.Unlock()
+
+	type poolMetric struct {
+		name  string
+		kind  string
+		help  string
+		value func(stats sql.DBStats) float64
+	}
+	poolMetrics := []poolMetric{
+		{"gorm_db_max_open_connections", "gauge", "Batas maksimal koneksi terbuka (SetMaxOpenConns).", func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"gorm_db_open_connections", "gauge", "Jumlah koneksi terbuka.", func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"gorm_db_in_use_connections", "gauge", "Jumlah koneksi yang sedang dipakai.", func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"gorm_db_idle_connections", "gauge", "Jumlah koneksi idle.", func(s sql.DBStats) float64 { return float64(s.Idle) }},
+		{"gorm_db_wait_count_total", "counter", "Jumlah permintaan koneksi yang harus menunggu.", func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"gorm_db_wait_duration_seconds_total", "counter", "Total waktu menunggu koneksi.", func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"gorm_db_max_idle_closed_total", "counter", "Koneksi ditutup karena SetMaxIdleConns.", func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"gorm_db_max_idle_time_closed_total", "counter", "Koneksi ditutup karena SetConnMaxIdleTime.", func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+		{"gorm_db_max_lifetime_closed_total", "counter", "Koneksi ditutup karena SetConnMaxLifetime.", func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+
+	stats := map[string]sql.DBStats{}
+	for _, name := range pools {
+		stats[name] = poolDBs[name].Stats()
+	}
+	for _, metric := range poolMetrics {
+		w.printf("# HELP %s %s\n", metric.name, metric.help)
+		w.printf("# TYPE %s %s\n", metric.name, metric.kind)
+		for _, name := range pools {
+			w.printf("%s%s %s\n", metric.name, labels("db", name), formatFloat(metric.value(stats[name])))
+		}
+	}
+
+	err := w.writer.(*bufio.Writer).Flush()
+	if w.err == nil {
+		w.err = err
+	}
+	return w.n, w.err
+}
+
+type countingWriter struct {
+	writer io.Writer
+	n      int64
+	err    error
+}
+
+func (w *countingWriter) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.writer, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
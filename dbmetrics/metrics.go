@@ -0,0 +1,151 @@
+package dbmetrics
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultBuckets sama dengan bucket default client Prometheus, dalam detik
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+const startKey = "dbmetrics:start"
+
+type Options struct {
+	// Buckets histogram latency dalam detik, nil berarti DefaultBuckets
+	Buckets []float64
+}
+
+type key struct {
+	table     string
+	operation string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, belum kumulatif
+	count  uint64
+	sum    float64
+}
+
+// Metrics adalah plugin GORM yang mencatat latency, error dan rows affected per tabel dan operasi,
+// dipasang dengan db.Use(metrics) lalu dibaca lewat Handler
+type Metrics struct {
+	buckets []float64
+
+	mu         sync.Mutex
+	histograms map[key]*histogram
+	errors     map[key]uint64
+	rows       map[key]uint64
+	pools      map[string]*sql.DB
+}
+
+func New(options Options) *Metrics {
+	buckets := options.Buckets
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	return &Metrics{
+		buckets:    buckets,
+		histograms: map[key]*histogram{},
+		errors:     map[key]uint64{},
+		rows:       map[key]uint64{},
+		pools:      map[string]*sql.DB{},
+	}
+}
+
+func (m *Metrics) Name() string {
+	return "dbmetrics"
+}
+
+// Initialize mendaftarkan callback di sekitar eksekusi SQL setiap operasi GORM,
+// connection pool db juga otomatis dicatat dengan nama "default"
+func (m *Metrics) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	registers := []error{
+		callback.Create().Before("gorm:create").Register("dbmetrics:before_create", m.before),
+		callback.Create().After("gorm:create").Register("dbmetrics:after_create", m.after("create")),
+		callback.Query().Before("gorm:query").Register("dbmetrics:before_query", m.before),
+		callback.Query().After("gorm:query").Register("dbmetrics:after_query", m.after("query")),
+		callback.Update().Before("gorm:update").Register("dbmetrics:before_update", m.before),
+		callback.Update().After("gorm:update").Register("dbmetrics:after_update", m.after("update")),
+		callback.Delete().Before("gorm:delete").Register("dbmetrics:before_delete", m.before),
+		callback.Delete().After("gorm:delete").Register("dbmetrics:after_delete", m.after("delete")),
+		callback.Row().Before("gorm:row").Register("dbmetrics:before_row", m.before),
+		callback.Row().After("gorm:row").Register("dbmetrics:after_row", m.after("row")),
+		callback.Raw().Before("gorm:raw").Register("dbmetrics:before_raw", m.before),
+		callback.Raw().After("gorm:raw").Register("dbmetrics:after_raw", m.after("raw")),
+	}
+	if err := errors.Join(registers...); err != nil {
+		return err
+	}
+
+	if sqlDB, err := db.DB(); err == nil {
+		m.CollectPool("default", sqlDB)
+	}
+	return nil
+}
+
+// CollectPool menambahkan sql.DBStats dari connection pool lain, misalnya replica
+func (m *Metrics) CollectPool(name string, db *sql.DB) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pools[name] = db
+}
+
+func (m *Metrics) before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (m *Metrics) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start := value.(time.Time)
+
+		table := db.Statement.Table
+		if table == "" && db.Statement.Schema != nil {
+			table = db.Statement.Schema.Table
+		}
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+		m.Observe(table, operation, time.Since(start), db.Statement.RowsAffected, failed)
+	}
+}
+
+// Observe mencatat satu operasi, dipakai callback dan bisa dipanggil manual
+func (m *Metrics) Observe(table string, operation string, duration time.Duration, rows int64, failed bool) {
+	k := key{table: table, operation: operation}
+	seconds := duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := m.histograms[k]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.histograms[k] = h
+	}
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+
+	if rows > 0 {
+		m.rows[k] += uint64(rows)
+	}
+	if failed {
+		m.errors[k]++
+	}
+}
@@ -0,0 +1,102 @@
+package dbmetrics_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/dbmetrics"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type User = belajargolanggorm.User
+
+func scrape(t *testing.T, metrics *dbmetrics.Metrics) string {
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	body, err := io.ReadAll(recorder.Body)
+	assert.Nil(t, err)
+	return string(body)
+}
+
+func TestQueryMetrics(t *testing.T) {
+	db := testdb.Open(t)
+	metrics := dbmetrics.New(dbmetrics.Options{})
+	assert.Nil(t, db.Use(metrics))
+
+	assert.Nil(t, db.Create(&[]User{{ID: 1, Password: "rahasia"}, {ID: 2, Password: "rahasia"}}).Error)
+	var users []User
+	assert.Nil(t, db.Find(&users).Error)
+	assert.Nil(t, db.Model(&User{}).Where("id = ?", 1).Update("password", "baru").Error)
+	assert.Nil(t, db.Delete(&User{}, 2).Error)
+	assert.NotNil(t, db.Take(&User{}, 99).Error)
+	assert.NotNil(t, db.Exec("SELECT * FROM tabel_tidak_ada").Error)
+
+	body := scrape(t, metrics)
+	assert.Contains(t, body, "# TYPE gorm_query_duration_seconds histogram\n")
+	assert.Contains(t, body, `gorm_query_duration_seconds_count{table="users",operation="create"} 1`+"\n")
+	assert.Contains(t, body, `gorm_query_duration_seconds_count{table="users",operation="query"} 2`+"\n")
+	assert.Contains(t, body, `gorm_query_duration_seconds_bucket{table="users",operation="update",le="+Inf"} 1`+"\n")
+	assert.Contains(t, body, `gorm_rows_affected_total{table="users",operation="create"} 2`+"\n")
+	assert.Contains(t, body, `gorm_rows_affected_total{table="users",operation="query"} 2`+"\n")
+	assert.Contains(t, body, `gorm_rows_affected_total{table="users",operation="delete"} 1`+"\n")
+
+	// record not found bukan error, query ke tabel yang tidak ada adalah error
+	assert.Contains(t, body, `gorm_query_errors_total{table="users",operation="query"} 0`+"\n")
+	assert.Contains(t, body, `gorm_query_errors_total{table="",operation="raw"} 1`+"\n")
+
+	assert.Contains(t, body, "# TYPE gorm_db_open_connections gauge\n")
+	assert.Contains(t, body, `gorm_db_open_connections{db="default"} `)
+	assert.Contains(t, body, "# TYPE gorm_db_wait_count_total counter\n")
+	assert.Contains(t, body, `gorm_db_max_lifetime_closed_total{db="default"} 0`+"\n")
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	metrics := dbmetrics.New(dbmetrics.Options{Buckets: []float64{1, 0.1}})
+	metrics.Observe("orders", "query", 50*time.Millisecond, 3, false)
+	metrics.Observe("orders", "query", 500*time.Millisecond, 0, false)
+	metrics.Observe("orders", "query", 2*time.Second, 0, true)
+
+	body := scrape(t, metrics)
+	expected := strings.Join([]string{
+		`gorm_query_duration_seconds_bucket{table="orders",operation="query",le="0.1"} 1`,
+		`gorm_query_duration_seconds_bucket{table="orders",operation="query",le="1"} 2`,
+		`gorm_query_duration_seconds_bucket{table="orders",operation="query",le="+Inf"} 3`,
+		`gorm_query_duration_seconds_sum{table="orders",operation="query"} 2.55`,
+		`gorm_query_duration_seconds_count{table="orders",operation="query"} 3`,
+	}, "\n")
+	assert.Contains(t, body, expected)
+	assert.Contains(t, body, `gorm_query_errors_total{table="orders",operation="query"} 1`)
+	assert.Contains(t, body, `gorm_rows_affected_total{table="orders",operation="query"} 3`)
+	assert.NotContains(t, body, `db="default"`)
+}
+
+func TestLabelEscaping(t *testing.T) {
+	metrics := dbmetrics.New(dbmetrics.Options{})
+	metrics.Observe("a\"b\\c\nd", "query", time.Millisecond, 0, false)
+
+	body := scrape(t, metrics)
+	assert.Contains(t, body, `gorm_query_duration_seconds_count{table="a\"b\\c\nd",operation="query"} 1`)
+}
+
+func TestCollectPool(t *testing.T) {
+	db := testdb.Open(t)
+	sqlDB, err := db.DB()
+	assert.Nil(t, err)
+	sqlDB.SetMaxOpenConns(100)
+
+	metrics := dbmetrics.New(dbmetrics.Options{})
+	metrics.CollectPool("replica", sqlDB)
+
+	body := scrape(t, metrics)
+	assert.Contains(t, body, `gorm_db_max_open_connections{db="replica"} 100`+"\n")
+	assert.Contains(t, body, `gorm_db_wait_duration_seconds_total{db="replica"} 0`+"\n")
+}
@@ -0,0 +1,202 @@
+package dbretry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+const (
+	MySQLDeadlock        = 1213 // ER_LOCK_DEADLOCK
+	MySQLLockWaitTimeout = 1205 // ER_LOCK_WAIT_TIMEOUT
+
+	MySQLDBAccessDenied         = 1044 // ER_DBACCESS_DENIED_ERROR
+	MySQLAccessDenied           = 1045 // ER_ACCESS_DENIED_ERROR
+	MySQLBadDB                  = 1049 // ER_BAD_DB_ERROR
+	MySQLAccessDeniedNoPassword = 1698 // ER_ACCESS_DENIED_NO_PASSWORD_ERROR
+)
+
+// Policy mengatur exponential backoff, delay percobaan ke-n adalah
+// Initial * Multiplier^(n-1), maksimal Max, lalu diacak sebesar +/- Jitter
+type Policy struct {
+	Attempts   int
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64 // 0.2 berarti delay diacak antara 80% sampai 120%
+
+	// OnRetry dipanggil sebelum menunggu percobaan berikutnya, misalnya untuk logging
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultPolicy dipakai saat membuka koneksi, total menunggu sekitar 3 detik
+var DefaultPolicy = Policy{
+	Attempts:   5,
+	Initial:    200 * time.Millisecond,
+	Max:        5 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// DeadlockPolicy dipakai untuk mengulang query dan transaction yang kena deadlock
+var DeadlockPolicy = Policy{
+	Attempts:   3,
+	Initial:    50 * time.Millisecond,
+	Max:        time.Second,
+	Multiplier: 2,
+	Jitter:     0.5,
+}
+
+// Delay menghitung waktu tunggu setelah percobaan ke-attempt (mulai dari 1) gagal
+func (p Policy) Delay(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if p.Max > 0 && delay > float64(p.Max) {
+		delay = float64(p.Max)
+	}
+	if p.Jitter > 0 {
+		delay = delay * (1 - p.Jitter + 2*p.Jitter*rand.Float64())
+	}
+	return time.Duration(delay)
+}
+
+// Do menjalankan fn sampai berhasil, error tidak bisa di-retry, atau percobaan habis,
+// error yang dikembalikan adalah error dari percobaan terakhir
+func Do(ctx context.Context, policy Policy, retryable func(error) bool, fn func() error) error {
+	attempts := policy.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn()
+		if err == nil || !retryable(err) || attempt == attempts {
+			return err
+		}
+
+		delay := policy.Delay(attempt)
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+// IsRetryable mengembalikan true untuk deadlock dan lock wait timeout MySQL,
+// serta database locked di SQLite
+func IsRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == MySQLDeadlock || mysqlErr.Number == MySQLLockWaitTimeout
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// IsConnectionError mengembalikan true untuk error koneksi yang bisa hilang sendiri,
+// misalnya database belum menerima koneksi. Akses ditolak, database tidak ada
+// dan DSN yang salah tidak akan berhasil walaupun dicoba ulang
+func IsConnectionError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case MySQLDBAccessDenied, MySQLAccessDenied, MySQLBadDB, MySQLAccessDeniedNoPassword:
+			return false
+		}
+		return true
+	}
+	if IsRetryable(err) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// Transaction menjalankan ulang seluruh closure db.Transaction kalau gagal karena deadlock,
+// karena transaksi yang kena deadlock sudah di-rollback oleh database.
+// Closure harus aman dijalankan lebih dari sekali
+func Transaction(db *gorm.DB, policy Policy, fc func(tx *gorm.DB) error) error {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Do(ctx, policy, IsRetryable, func() error {
+		return db.Transaction(fc)
+	})
+}
+
+// Reads adalah plugin GORM yang mengulang query baca (Find, First, Take, Count, Pluck)
+// yang gagal karena deadlock, query di dalam transaksi tidak diulang
+type Reads struct {
+	Policy Policy
+}
+
+func NewReads(policy Policy) *Reads {
+	return &Reads{Policy: policy}
+}
+
+func (r *Reads) Name() string {
+	return "dbretry"
+}
+
+func (r *Reads) Initialize(db *gorm.DB) error {
+	query := db.Callback().Query()
+	next := query.Get("gorm:query")
+	if next == nil {
+		return errors.New("dbretry: gorm:query callback not found")
+	}
+	return query.Replace("gorm:query", r.wrap(next))
+}
+
+func (r *Reads) wrap(next func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok || db.Error != nil {
+			next(db)
+			return
+		}
+
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		err := Do(ctx, r.Policy, IsRetryable, func() error {
+			db.Error = nil
+			next(db)
+			return db.Error
+		})
+		db.Error = err
+	}
+}
@@ -0,0 +1,179 @@
+package dbretry_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/dbretry"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type User = belajargolanggorm.User
+
+var deadlock = &mysql.MySQLError{Number: dbretry.MySQLDeadlock, Message: "Deadlock found when trying to get lock"}
+
+var fast = dbretry.Policy{Attempts: 3, Initial: time.Millisecond, Multiplier: 2}
+
+func TestDelay(t *testing.T) {
+	policy := dbretry.Policy{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	assert.Equal(t, 100*time.Millisecond, policy.Delay(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Delay(2))
+	assert.Equal(t, 800*time.Millisecond, policy.Delay(4))
+	assert.Equal(t, time.Second, policy.Delay(5))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.Delay(1)
+		assert.GreaterOrEqual(t, delay, 50*time.Millisecond)
+		assert.LessOrEqual(t, delay, 150*time.Millisecond)
+	}
+}
+
+func TestDo(t *testing.T) {
+	var retries []int
+	policy := fast
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+		retries = append(retries, attempt)
+	}
+
+	calls := 0
+	err := dbretry.Do(context.Background(), policy, dbretry.IsRetryable, func() error {
+		calls++
+		if calls < 3 {
+			return deadlock
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []int{1, 2}, retries)
+
+	// percobaan habis, error terakhir dikembalikan
+	calls = 0
+	err = dbretry.Do(context.Background(), fast, dbretry.IsRetryable, func() error {
+		calls++
+		return deadlock
+	})
+	assert.Equal(t, deadlock, err)
+	assert.Equal(t, 3, calls)
+
+	// error biasa tidak diulang
+	calls = 0
+	err = dbretry.Do(context.Background(), fast, dbretry.IsRetryable, func() error {
+		calls++
+		return gorm.ErrRecordNotFound
+	})
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	assert.Equal(t, 1, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = dbretry.Do(ctx, dbretry.Policy{Attempts: 3, Initial: time.Hour}, dbretry.IsRetryable, func() error {
+		return deadlock
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, deadlock)
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, dbretry.IsRetryable(deadlock))
+	assert.True(t, dbretry.IsRetryable(&mysql.MySQLError{Number: dbretry.MySQLLockWaitTimeout}))
+	assert.True(t, dbretry.IsRetryable(sqlite3.Error{Code: sqlite3.ErrBusy}))
+	assert.False(t, dbretry.IsRetryable(&mysql.MySQLError{Number: 1062}))
+	assert.False(t, dbretry.IsRetryable(errors.New("deadlock")))
+	assert.False(t, dbretry.IsRetryable(nil))
+}
+
+func TestIsConnectionError(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	assert.True(t, dbretry.IsConnectionError(refused))
+	assert.True(t, dbretry.IsConnectionError(mysql.ErrInvalidConn))
+	assert.True(t, dbretry.IsConnectionError(&mysql.MySQLError{Number: 1040})) // too many connections
+	assert.True(t, dbretry.IsConnectionError(sqlite3.Error{Code: sqlite3.ErrBusy}))
+
+	assert.False(t, dbretry.IsConnectionError(&mysql.MySQLError{Number: dbretry.MySQLAccessDenied}))
+	assert.False(t, dbretry.IsConnectionError(&mysql.MySQLError{Number: dbretry.MySQLBadDB}))
+	assert.False(t, dbretry.IsConnectionError(sqlite3.Error{Code: sqlite3.ErrCantOpen}))
+	assert.False(t, dbretry.IsConnectionError(errors.New("invalid DSN")))
+}
+
+func TestTransaction(t *testing.T) {
+	db := testdb.Open(t)
+
+	calls := 0
+	err := dbretry.Transaction(db, fast, func(tx *gorm.DB) error {
+		calls++
+		if err := tx.Create(&User{ID: 1, Password: "rahasia"}).Error; err != nil {
+			return err
+		}
+		if calls < 3 {
+			return deadlock
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+
+	// percobaan yang gagal sudah di-rollback, jadi user hanya tersimpan sekali
+	var count int64
+	assert.Nil(t, db.Model(&User{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+// flaky membuat callback gorm:query gagal dengan deadlock sebanyak failures kali
+func flaky(t *testing.T, db *gorm.DB, failures int) *int {
+	query := db.Callback().Query()
+	next := query.Get("gorm:query")
+	calls := 0
+	err := query.Replace("gorm:query", func(db *gorm.DB) {
+		calls++
+		if calls <= failures {
+			db.AddError(deadlock)
+			return
+		}
+		next(db)
+	})
+	assert.Nil(t, err)
+	return &calls
+}
+
+func TestReads(t *testing.T) {
+	db := testdb.Open(t)
+	assert.Nil(t, db.Create(&User{ID: 1, Password: "rahasia"}).Error)
+
+	calls := flaky(t, db, 2)
+	assert.Nil(t, db.Use(dbretry.NewReads(fast)))
+
+	var user User
+	assert.Nil(t, db.Take(&user, 1).Error)
+	assert.Equal(t, 1, user.ID)
+	assert.Equal(t, 3, *calls)
+
+	// record not found tidak diulang
+	*calls = 2
+	assert.Equal(t, gorm.ErrRecordNotFound, db.Take(&User{}, 99).Error)
+	assert.Equal(t, 3, *calls)
+}
+
+func TestReadsInTransactionNotRetried(t *testing.T) {
+	db := testdb.Open(t)
+	calls := flaky(t, db, 1)
+	assert.Nil(t, db.Use(dbretry.NewReads(fast)))
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var users []User
+		return tx.Find(&users).Error
+	})
+	assert.Equal(t, deadlock, err)
+	assert.Equal(t, 1, *calls)
+}
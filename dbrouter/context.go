@@ -0,0 +1,60 @@
+package dbrouter
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type contextKey int
+
+const (
+	sessionKey contextKey = iota
+	primaryKey
+)
+
+type session struct {
+	written atomic.Bool
+}
+
+// Session menandai context sebagai satu sesi (misalnya satu HTTP request),
+// setelah ada operasi tulis di sesi tersebut query baca ikut diarahkan ke primary
+// supaya tidak membaca data lama dari replica yang tertinggal
+func Session(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(sessionKey).(*session); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKey, &session{})
+}
+
+// Primary memaksa semua query dengan context ini dibaca dari primary
+func Primary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
+// Written mengembalikan true kalau sudah ada operasi tulis di sesi ctx
+func Written(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	s, ok := ctx.Value(sessionKey).(*session)
+	return ok && s.written.Load()
+}
+
+func markWritten(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	if s, ok := ctx.Value(sessionKey).(*session); ok {
+		s.written.Store(true)
+	}
+}
+
+func usePrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	if primary, _ := ctx.Value(primaryKey).(bool); primary {
+		return true
+	}
+	return Written(ctx)
+}
@@ -0,0 +1,229 @@
+package dbrouter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+type Options struct {
+	// HealthCheckInterval 0 berarti health check hanya dijalankan manual lewat CheckHealth
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout 0 berarti DefaultHealthCheckTimeout
+	HealthCheckTimeout time.Duration
+}
+
+// Replica adalah satu koneksi read-only beserta status kesehatannya
+type Replica struct {
+	Name string
+
+	pool    gorm.ConnPool
+	db      *sql.DB
+	healthy atomic.Bool
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+func (r *Replica) DB() *sql.DB {
+	return r.db
+}
+
+func (r *Replica) Healthy() bool {
+	return r.healthy.Load()
+}
+
+// LastError adalah error dari health check terakhir yang gagal
+func (r *Replica) LastError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErr
+}
+
+func (r *Replica) setHealth(err error) {
+	r.mu.Lock()
+	r.lastErr = err
+	r.mu.Unlock()
+	r.healthy.Store(err == nil)
+}
+
+// Router adalah plugin GORM yang mengarahkan query baca ke replica dan
+// operasi tulis serta transaksi ke primary
+type Router struct {
+	options    Options
+	dialectors []gorm.Dialector
+	replicas   []*Replica
+	next       atomic.Uint64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func New(options Options, replicas ...gorm.Dialector) *Router {
+	if options.HealthCheckTimeout <= 0 {
+		options.HealthCheckTimeout = DefaultHealthCheckTimeout
+	}
+	return &Router{options: options, dialectors: replicas}
+}
+
+func (r *Router) Name() string {
+	return "dbrouter"
+}
+
+func (r *Router) Initialize(db *gorm.DB) error {
+	for i, dialector := range r.dialectors {
+		replicaDB, err := gorm.Open(dialector, &gorm.Config{
+			Logger:                 db.Logger,
+			SkipDefaultTransaction: true,
+			PrepareStmt:            db.PrepareStmt,
+		})
+		if err != nil {
+			return fmt.Errorf("replica-%d: %w", i+1, err)
+		}
+		sqlDB, err := replicaDB.DB()
+		if err != nil {
+			return fmt.Errorf("replica-%d: %w", i+1, err)
+		}
+
+		replica := &Replica{Name: fmt.Sprintf("replica-%d", i+1), pool: replicaDB.ConnPool, db: sqlDB}
+		replica.healthy.Store(true)
+		r.replicas = append(r.replicas, replica)
+	}
+
+	callback := db.Callback()
+	err := errors.Join(
+		callback.Query().Before("gorm:query").Register("dbrouter:query", r.read),
+		callback.Row().Before("gorm:row").Register("dbrouter:row", r.read),
+		callback.Raw().Before("gorm:raw").Register("dbrouter:raw", r.read),
+		callback.Create().Before("gorm:begin_transaction").Register("dbrouter:create", r.write),
+		callback.Update().Before("gorm:begin_transaction").Register("dbrouter:update", r.write),
+		callback.Delete().Before("gorm:begin_transaction").Register("dbrouter:delete", r.write),
+	)
+	if err != nil {
+		return err
+	}
+
+	if r.options.HealthCheckInterval > 0 && len(r.replicas) > 0 {
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+		go r.healthLoop()
+	}
+	return nil
+}
+
+func (r *Router) Replicas() []*Replica {
+	return append([]*Replica(nil), r.replicas...)
+}
+
+// Close menghentikan health check dan menutup koneksi replica, primary tetap terbuka
+func (r *Router) Close() error {
+	if r.stop != nil {
+		close(r.stop)
+		<-r.done
+		r.stop = nil
+	}
+
+	var errs []error
+	for _, replica := range r.replicas {
+		errs = append(errs, replica.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// CheckHealth melakukan ping ke setiap replica, replica yang gagal tidak dipakai
+// sampai health check berikutnya berhasil
+func (r *Router) CheckHealth(ctx context.Context) {
+	for _, replica := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, r.options.HealthCheckTimeout)
+		replica.setHealth(replica.db.PingContext(pingCtx))
+		cancel()
+	}
+}
+
+func (r *Router) healthLoop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.options.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.CheckHealth(context.Background())
+		}
+	}
+}
+
+// replica dipilih bergiliran, kalau semua tidak sehat query dibaca dari primary
+func (r *Router) pick() *Replica {
+	count := len(r.replicas)
+	start := r.next.Add(1)
+	for i := 0; i < count; i++ {
+		replica := r.replicas[(start+uint64(i))%uint64(count)]
+		if replica.Healthy() {
+			return replica
+		}
+	}
+	return nil
+}
+
+func inTransaction(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+func (r *Router) read(db *gorm.DB) {
+	if inTransaction(db) {
+		return
+	}
+
+	if !isRead(db.Statement) || usePrimary(db.Statement.Context) {
+		if !isRead(db.Statement) {
+			markWritten(db.Statement.Context)
+		}
+		db.Statement.ConnPool = db.Config.ConnPool
+		return
+	}
+
+	replica := r.pick()
+	if replica == nil {
+		db.Statement.ConnPool = db.Config.ConnPool
+		return
+	}
+	db.Statement.ConnPool = replica.pool
+}
+
+func (r *Router) write(db *gorm.DB) {
+	markWritten(db.Statement.Context)
+	if !inTransaction(db) {
+		db.Statement.ConnPool = db.Config.ConnPool
+	}
+}
+
+// query Find/First/Take belum punya SQL saat callback dijalankan, query Raw dan Exec sudah
+func isRead(stmt *gorm.Statement) bool {
+	// clause.Locking (SELECT ... FOR UPDATE) harus dibaca dari primary
+	if _, ok := stmt.Clauses["FOR"]; ok {
+		return false
+	}
+	if stmt.SQL.Len() == 0 {
+		return true
+	}
+
+	sql := strings.ToUpper(strings.TrimSpace(stmt.SQL.String()))
+	if !strings.HasPrefix(sql, "SELECT") && !strings.HasPrefix(sql, "WITH") {
+		return false
+	}
+	return !strings.Contains(sql, " FOR UPDATE") && !strings.Contains(sql, " FOR SHARE") &&
+		!strings.Contains(sql, "LOCK IN SHARE MODE")
+}
@@ -0,0 +1,142 @@
+package dbrouter_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/dbrouter"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type User = belajargolanggorm.User
+
+// setup membuat dua file sqlite, user 1 punya nama berbeda di primary dan replica
+// sehingga test bisa tahu query dibaca dari mana
+func setup(t *testing.T, options dbrouter.Options) (*gorm.DB, *gorm.DB, *dbrouter.Router) {
+	primary := testdb.Open(t)
+	assert.Nil(t, primary.Create(&User{ID: 1, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Primary"}}).Error)
+
+	path := filepath.Join(t.TempDir(), "replica.db")
+	replica, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.Nil(t, err)
+	assert.Nil(t, replica.AutoMigrate(testdb.Models...))
+	assert.Nil(t, replica.Create(&User{ID: 1, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Replica"}}).Error)
+	t.Cleanup(func() {
+		sqlDB, _ := replica.DB()
+		_ = sqlDB.Close()
+	})
+
+	router := dbrouter.New(options, sqlite.Open(path))
+	assert.Nil(t, primary.Use(router))
+	t.Cleanup(func() { _ = router.Close() })
+
+	return primary, replica, router
+}
+
+func firstName(t *testing.T, db *gorm.DB) string {
+	var user User
+	assert.Nil(t, db.Take(&user, 1).Error)
+	return user.Name.FirstName
+}
+
+func TestReadsGoToReplica(t *testing.T) {
+	db, _, _ := setup(t, dbrouter.Options{})
+
+	assert.Equal(t, "Replica", firstName(t, db))
+
+	var name string
+	assert.Nil(t, db.Raw("SELECT first_name FROM users WHERE id = ?", 1).Scan(&name).Error)
+	assert.Equal(t, "Replica", name)
+
+	var users []User
+	assert.Nil(t, db.Raw("SELECT * FROM users").Find(&users).Error)
+	assert.Equal(t, "Replica", users[0].Name.FirstName)
+
+	var count int64
+	assert.Nil(t, db.Model(&User{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestWritesGoToPrimary(t *testing.T) {
+	db, replica, _ := setup(t, dbrouter.Options{})
+
+	assert.Nil(t, db.Create(&User{ID: 2, Password: "rahasia"}).Error)
+	assert.Nil(t, db.Model(&User{}).Where("id = ?", 1).Update("first_name", "Primary Baru").Error)
+	assert.Nil(t, db.Exec("UPDATE users SET last_name = ? WHERE id = ?", "Khannedy", 1).Error)
+
+	var count int64
+	assert.Nil(t, replica.Model(&User{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, "Replica", firstName(t, replica))
+
+	var user User
+	assert.Nil(t, db.WithContext(dbrouter.Primary(context.Background())).Take(&user, 1).Error)
+	assert.Equal(t, "Primary Baru", user.Name.FirstName)
+	assert.Equal(t, "Khannedy", user.Name.LastName)
+
+	assert.Nil(t, db.Delete(&User{}, 2).Error)
+	assert.Equal(t, "Replica", firstName(t, db))
+}
+
+func TestStickyPrimaryAfterWrite(t *testing.T) {
+	db, _, _ := setup(t, dbrouter.Options{})
+
+	ctx := dbrouter.Session(context.Background())
+	session := db.WithContext(ctx)
+	assert.Equal(t, "Replica", firstName(t, session))
+	assert.False(t, dbrouter.Written(ctx))
+
+	assert.Nil(t, session.Model(&User{}).Where("id = ?", 1).Update("first_name", "Baru").Error)
+	assert.True(t, dbrouter.Written(ctx))
+	assert.Equal(t, "Baru", firstName(t, session))
+
+	// sesi lain tetap membaca dari replica
+	assert.Equal(t, "Replica", firstName(t, db.WithContext(dbrouter.Session(context.Background()))))
+	assert.Equal(t, "Baru", firstName(t, db.WithContext(dbrouter.Primary(context.Background()))))
+}
+
+func TestTransactionUsesPrimary(t *testing.T) {
+	db, _, _ := setup(t, dbrouter.Options{})
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		assert.Equal(t, "Primary", firstName(t, tx))
+		return tx.Create(&User{ID: 3, Password: "rahasia"}).Error
+	})
+	assert.Nil(t, err)
+
+	var count int64
+	assert.Nil(t, db.WithContext(dbrouter.Primary(context.Background())).Model(&User{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestUnhealthyReplicaFallsBackToPrimary(t *testing.T) {
+	db, _, router := setup(t, dbrouter.Options{})
+
+	replica := router.Replicas()[0]
+	assert.Equal(t, "replica-1", replica.Name)
+	router.CheckHealth(context.Background())
+	assert.True(t, replica.Healthy())
+
+	assert.Nil(t, replica.DB().Close())
+	router.CheckHealth(context.Background())
+	assert.False(t, replica.Healthy())
+	assert.NotNil(t, replica.LastError())
+
+	assert.Equal(t, "Primary", firstName(t, db))
+}
+
+func TestHealthCheckLoop(t *testing.T) {
+	_, _, router := setup(t, dbrouter.Options{HealthCheckInterval: 10 * time.Millisecond})
+
+	replica := router.Replicas()[0]
+	assert.Nil(t, replica.DB().Close())
+	assert.Eventually(t, func() bool { return !replica.Healthy() }, time.Second, 10*time.Millisecond)
+}
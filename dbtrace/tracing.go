@@ -0,0 +1,198 @@
+package dbtrace
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"belajar-golang-gorm/internal/txhook"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	TracerName = "belajar-golang-gorm/dbtrace"
+
+	spanKey   = "dbtrace:span"
+	parentKey = "dbtrace:parent"
+)
+
+var (
+	AttrSystem       = attribute.Key("db.system")
+	AttrOperation    = attribute.Key("db.operation")
+	AttrTable        = attribute.Key("db.sql.table")
+	AttrStatement    = attribute.Key("db.statement")
+	AttrRowsAffected = attribute.Key("db.rows_affected")
+)
+
+type Options struct {
+	// TracerProvider nil berarti otel.GetTracerProvider()
+	TracerProvider trace.TracerProvider
+	// ExcludeStatement tidak menambahkan SQL ke span
+	ExcludeStatement bool
+}
+
+// Tracing adalah plugin GORM yang membuat span untuk setiap operasi dan transaksi,
+// parent span diambil dari context yang dipasang lewat db.WithContext.
+// Operasi di dalam transaksi menjadi anak dari span gorm.transaction
+type Tracing struct {
+	tracer  trace.Tracer
+	options Options
+}
+
+func New(options Options) *Tracing {
+	provider := options.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracing{tracer: provider.Tracer(TracerName), options: options}
+}
+
+func (t *Tracing) Name() string {
+	return "dbtrace"
+}
+
+func (t *Tracing) Initialize(db *gorm.DB) error {
+	// span transaksi dibuat saat Begin dan diakhiri saat Commit atau Rollback,
+	// jadi db.Transaction biasa juga tercatat
+	txhook.Register(db)
+	txhook.OnBegin(db, t.begin(db.Dialector.Name()))
+
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("dbtrace:before_create", t.before("create")),
+		callback.Create().After("gorm:create").Register("dbtrace:after_create", t.after),
+		callback.Query().Before("gorm:query").Register("dbtrace:before_query", t.before("query")),
+		callback.Query().After("gorm:query").Register("dbtrace:after_query", t.after),
+		callback.Update().Before("gorm:update").Register("dbtrace:before_update", t.before("update")),
+		callback.Update().After("gorm:update").Register("dbtrace:after_update", t.after),
+		callback.Delete().Before("gorm:delete").Register("dbtrace:before_delete", t.before("delete")),
+		callback.Delete().After("gorm:delete").Register("dbtrace:after_delete", t.after),
+		callback.Row().Before("gorm:row").Register("dbtrace:before_row", t.before("row")),
+		callback.Row().After("gorm:row").Register("dbtrace:after_row", t.after),
+		callback.Raw().Before("gorm:raw").Register("dbtrace:before_raw", t.before("raw")),
+		callback.Raw().After("gorm:raw").Register("dbtrace:after_raw", t.after),
+	)
+}
+
+func (t *Tracing) begin(system string) txhook.BeginFunc {
+	return func(ctx context.Context) (context.Context, func(err error)) {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx, span := t.tracer.Start(ctx, "gorm.transaction", trace.WithAttributes(AttrSystem.String(system)))
+		return ctx, func(err error) {
+			end(span, err)
+		}
+	}
+}
+
+func (t *Tracing) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+
+		ctx, span := t.tracer.Start(parentContext(db), "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				AttrSystem.String(db.Dialector.Name()),
+				AttrOperation.String(operation),
+			))
+
+		// context anak dipakai driver, context asal dikembalikan di after
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+		db.InstanceSet(parentKey, parent)
+	}
+}
+
+func (t *Tracing) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	if parent, ok := db.InstanceGet(parentKey); ok {
+		db.Statement.Context = parent.(context.Context)
+	}
+
+	table := db.Statement.Table
+	if table == "" && db.Statement.Schema != nil {
+		table = db.Statement.Schema.Table
+	}
+	if table != "" {
+		span.SetAttributes(AttrTable.String(table))
+	}
+	if !t.options.ExcludeStatement {
+		span.SetAttributes(AttrStatement.String(Sanitize(db.Statement.SQL.String())))
+	}
+	span.SetAttributes(AttrRowsAffected.Int64(db.Statement.RowsAffected))
+
+	end(span, db.Error)
+}
+
+// record not found bukan kegagalan query, jadi span tetap OK
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+var (
+	spacePattern  = regexp.MustCompile(`\s+`)
+	stringPattern = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberPattern = regexp.MustCompile(`\b\d+(\.\d+)?\b`)
+)
+
+// Sanitize mengganti literal string dan angka dengan ? supaya nilai dari
+// query Raw atau Exec tidak ikut tersimpan di span
+func Sanitize(sql string) string {
+	sql = stringPattern.ReplaceAllString(sql, "?")
+	sql = numberPattern.ReplaceAllString(sql, "?")
+	return strings.TrimSpace(spacePattern.ReplaceAllString(sql, " "))
+}
+
+// parentContext mengembalikan context statement, kalau statement berjalan di dalam
+// transaksi dengan context yang sama seperti saat Begin, span transaksi dijadikan parent
+func parentContext(db *gorm.DB) context.Context {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	parent, txCtx, ok := txhook.Context(db)
+	if !ok || parent == nil || txCtx == nil {
+		return ctx
+	}
+	if trace.SpanFromContext(ctx).SpanContext().Equal(trace.SpanFromContext(parent).SpanContext()) {
+		return trace.ContextWithSpan(ctx, trace.SpanFromContext(txCtx))
+	}
+	return ctx
+}
+
+// Transaction sama dengan db.Transaction, span gorm.transaction dibuat oleh plugin.
+// Kalau db sudah berada di dalam transaksi, savepoint dibungkus span gorm.savepoint
+func Transaction(db *gorm.DB, fc func(tx *gorm.DB) error) error {
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		return db.Transaction(fc)
+	}
+
+	tracer := otel.GetTracerProvider().Tracer(TracerName)
+	if plugin, ok := db.Config.Plugins["dbtrace"].(*Tracing); ok {
+		tracer = plugin.tracer
+	}
+
+	ctx, span := tracer.Start(parentContext(db), "gorm.savepoint", trace.WithAttributes(AttrSystem.String(db.Dialector.Name())))
+	err := db.WithContext(ctx).Transaction(fc)
+	end(span, err)
+	return err
+}
@@ -0,0 +1,173 @@
+package dbtrace_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/dbtrace"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+type User = belajargolanggorm.User
+
+func setup(t *testing.T) (*gorm.DB, *tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	db := testdb.Open(t)
+	assert.Nil(t, db.Use(dbtrace.New(dbtrace.Options{TracerProvider: provider})))
+	return db, exporter, provider
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	result := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		result[kv.Key] = kv.Value
+	}
+	return result
+}
+
+func TestOperationSpans(t *testing.T) {
+	db, exporter, provider := setup(t)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "handler")
+	tx := db.WithContext(ctx)
+	assert.Nil(t, tx.Create(&User{ID: 1, Password: "rahasia"}).Error)
+	var users []User
+	assert.Nil(t, tx.Where("password = ?", "rahasia").Find(&users).Error)
+	assert.Nil(t, tx.Model(&User{}).Where("id = ?", 1).Update("password", "baru").Error)
+	var count int64
+	assert.Nil(t, tx.Raw("SELECT count(*) FROM users WHERE password = 'baru'").Row().Scan(&count))
+	assert.Nil(t, tx.Exec("UPDATE users SET password = ? WHERE id = 1", "lagi").Error)
+	assert.Nil(t, tx.Delete(&User{}, 1).Error)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 7, len(spans))
+
+	names := []string{}
+	for _, span := range spans[:6] {
+		names = append(names, span.Name)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID())
+	}
+	assert.Equal(t, []string{"gorm.create", "gorm.query", "gorm.update", "gorm.row", "gorm.raw", "gorm.delete"}, names)
+
+	create := attributes(spans[0])
+	assert.Equal(t, "sqlite", create[dbtrace.AttrSystem].AsString())
+	assert.Equal(t, "create", create[dbtrace.AttrOperation].AsString())
+	assert.Equal(t, "users", create[dbtrace.AttrTable].AsString())
+	assert.Equal(t, int64(1), create[dbtrace.AttrRowsAffected].AsInt64())
+	assert.Contains(t, create[dbtrace.AttrStatement].AsString(), "INSERT INTO")
+	assert.NotContains(t, create[dbtrace.AttrStatement].AsString(), "rahasia")
+
+	query := attributes(spans[1])
+	assert.Contains(t, query[dbtrace.AttrStatement].AsString(), "password = ?")
+	assert.Equal(t, int64(1), query[dbtrace.AttrRowsAffected].AsInt64())
+
+	// literal di query Raw ikut disamarkan
+	row := attributes(spans[3])
+	assert.Equal(t, "SELECT count(*) FROM users WHERE password = ?", row[dbtrace.AttrStatement].AsString())
+	raw := attributes(spans[4])
+	assert.Equal(t, "UPDATE users SET password = ? WHERE id = ?", raw[dbtrace.AttrStatement].AsString())
+}
+
+func TestErrorSpan(t *testing.T) {
+	db, exporter, _ := setup(t)
+
+	assert.NotNil(t, db.Take(&User{}, 99).Error)
+	assert.NotNil(t, db.Exec("SELECT * FROM tabel_tidak_ada").Error)
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, 0, len(spans[0].Events))
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Equal(t, "exception", spans[1].Events[0].Name)
+	assert.False(t, spans[1].Parent.IsValid())
+}
+
+func TestTransactionSpans(t *testing.T) {
+	db, exporter, _ := setup(t)
+
+	err := dbtrace.Transaction(db, func(tx *gorm.DB) error {
+		if err := tx.Create(&User{ID: 1, Password: "rahasia"}).Error; err != nil {
+			return err
+		}
+		_ = dbtrace.Transaction(tx, func(tx *gorm.DB) error {
+			if err := tx.Create(&User{ID: 2, Password: "rahasia"}).Error; err != nil {
+				return err
+			}
+			return errors.New("batal")
+		})
+		return nil
+	})
+	assert.Nil(t, err)
+
+	var count int64
+	assert.Nil(t, db.Model(&User{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// span diekspor saat selesai, perintah SAVEPOINT dan ROLLBACK TO tercatat sebagai gorm.raw
+	spans := exporter.GetSpans()[:6]
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{"gorm.create", "gorm.raw", "gorm.create", "gorm.raw", "gorm.savepoint", "gorm.transaction"}, names)
+
+	transaction, savepoint := spans[5], spans[4]
+	assert.False(t, transaction.Parent.IsValid())
+	assert.Equal(t, codes.Unset, transaction.Status.Code)
+	assert.Equal(t, transaction.SpanContext.SpanID(), savepoint.Parent.SpanID())
+	assert.Equal(t, codes.Error, savepoint.Status.Code)
+	assert.Equal(t, transaction.SpanContext.SpanID(), spans[0].Parent.SpanID())
+	for _, span := range spans[1:4] {
+		assert.Equal(t, savepoint.SpanContext.SpanID(), span.Parent.SpanID())
+	}
+}
+
+func TestHelperTransactionSpan(t *testing.T) {
+	db, exporter, _ := setup(t)
+	assert.Nil(t, db.Create(&belajargolanggorm.Product{ID: 1, Name: "Kopi", Price: 10000}).Error)
+	exporter.Reset()
+
+	// helper di package utama memakai db.Transaction biasa, span dibuat oleh plugin
+	err := belajargolanggorm.NewLikes(db).Like(1, 1)
+	assert.Nil(t, err)
+
+	spans := exporter.GetSpans()
+	transaction := spans[len(spans)-1]
+	assert.Equal(t, "gorm.transaction", transaction.Name)
+	assert.Equal(t, codes.Unset, transaction.Status.Code)
+	for _, span := range spans[:len(spans)-1] {
+		assert.Equal(t, transaction.SpanContext.SpanID(), span.Parent.SpanID(), span.Name)
+	}
+
+	// transaksi yang di-rollback ditandai error
+	exporter.Reset()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return errors.New("batal")
+	})
+	assert.NotNil(t, err)
+	spans = exporter.GetSpans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, "gorm.transaction", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "SELECT * FROM users WHERE name = ? AND age > ? AND id IN (?,?)",
+		dbtrace.Sanitize("SELECT *\n  FROM users WHERE name = 'O''Brien' AND age > 17.5 AND id IN (1,2)"))
+	assert.Equal(t, "SELECT * FROM table1 WHERE id = ?", dbtrace.Sanitize("SELECT * FROM table1 WHERE id = ?"))
+}
@@ -0,0 +1,278 @@
+package entitycache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"belajar-golang-gorm/dbcache"
+	"belajar-golang-gorm/internal/txhook"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	DefaultTTL         = 5 * time.Minute
+	DefaultNegativeTTL = 30 * time.Second
+)
+
+var ErrNoPrimaryKey = errors.New("entity has no primary key")
+
+type Options struct {
+	// TTL 0 berarti DefaultTTL
+	TTL time.Duration
+	// NegativeTTL adalah lama id yang tidak ditemukan diingat, 0 berarti DefaultNegativeTTL
+	// dan nilai negatif mematikan negative caching
+	NegativeTTL time.Duration
+	// Capacity 0 berarti dbcache.DefaultCapacity
+	Capacity int
+}
+
+type Stats struct {
+	Hits         uint64
+	NegativeHits uint64
+	Misses       uint64
+	Loads        uint64
+}
+
+// Store adalah identity cache untuk satu model, dibaca lewat primary key.
+// Perubahan lewat Save dan Updates di Store langsung ditulis ke cache,
+// perubahan lewat GORM di tempat lain membuang cache lewat callback
+type Store[T any, ID comparable] struct {
+	db      *gorm.DB
+	table   string
+	primary *schema.Field
+	options Options
+
+	cache      *dbcache.LRU
+	group      singleflight.Group
+	generation atomic.Uint64 // dinaikkan saat semua cache dibuang
+	writes     atomic.Uint64 // dinaikkan setiap invalidasi, mencegah hasil load yang basi disimpan
+
+	hits, negativeHits, misses, loads atomic.Uint64
+}
+
+func New[T any, ID comparable](db *gorm.DB, options Options) (*Store[T, ID], error) {
+	if options.TTL <= 0 {
+		options.TTL = DefaultTTL
+	}
+	if options.NegativeTTL == 0 {
+		options.NegativeTTL = DefaultNegativeTTL
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil, ErrNoPrimaryKey
+	}
+
+	store := &Store[T, ID]{
+		db:      db,
+		table:   stmt.Schema.Table,
+		primary: stmt.Schema.PrioritizedPrimaryField,
+		options: options,
+		cache:   dbcache.NewLRU(options.Capacity),
+	}
+
+	// perubahan di dalam transaksi baru membuang cache setelah commit
+	txhook.Register(db)
+	callback := db.Callback()
+	name := "entitycache:" + store.table
+	err := errors.Join(
+		callback.Create().After("gorm:create").Register(name+":create", store.invalidateStatement),
+		callback.Update().After("gorm:update").Register(name+":update", store.invalidateStatement),
+		callback.Delete().After("gorm:delete").Register(name+":delete", store.invalidateStatement),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *Store[T, ID]) key(id interface{}) string {
+	return fmt.Sprintf("%d:%v", s.generation.Load(), id)
+}
+
+// GetByID membaca entity dari cache, kalau tidak ada dibaca dari database.
+// Beberapa request bersamaan untuk id yang sama hanya menjalankan satu query
+func (s *Store[T, ID]) GetByID(ctx context.Context, id ID) (*T, error) {
+	key := s.key(id)
+	if value, ok := s.cache.Get(key); ok {
+		if len(value) == 0 {
+			s.negativeHits.Add(1)
+			return nil, gorm.ErrRecordNotFound
+		}
+		s.hits.Add(1)
+		return decode[T](value)
+	}
+
+	s.misses.Add(1)
+	value, err, _ := s.group.Do(key, func() (interface{}, error) {
+		return s.load(ctx, key, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decode[T](value.([]byte))
+}
+
+func (s *Store[T, ID]) load(ctx context.Context, key string, id ID) ([]byte, error) {
+	s.loads.Add(1)
+	writes := s.writes.Load()
+
+	var entity T
+	err := s.db.WithContext(ctx).Where(s.primaryEq(id)).Take(&entity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if s.options.NegativeTTL > 0 && s.writes.Load() == writes {
+			s.cache.Set(key, nil, s.options.NegativeTTL)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(&entity)
+	if err != nil {
+		return nil, err
+	}
+	if s.writes.Load() == writes {
+		s.cache.Set(key, value, s.options.TTL)
+	}
+	return value, nil
+}
+
+func (s *Store[T, ID]) primaryEq(id ID) clause.Eq {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: s.primary.DBName}, Value: id}
+}
+
+// Save menyimpan entity lalu membacanya ulang ke cache, seperti Updates.
+// Relasi yang ikut ter-load di entity tidak disimpan, karena cache harus
+// sama dengan hasil GetByID dan tidak ikut dibuang saat tabel relasi berubah
+func (s *Store[T, ID]) Save(ctx context.Context, entity *T) error {
+	err := s.db.WithContext(ctx).Save(entity).Error
+	if err != nil {
+		return err
+	}
+
+	id, zero := s.primary.ValueOf(ctx, reflect.ValueOf(entity).Elem())
+	if zero {
+		return nil
+	}
+	typed, ok := id.(ID)
+	if !ok {
+		s.Purge()
+		return nil
+	}
+	_, err = s.load(ctx, s.key(typed), typed)
+	return err
+}
+
+// Updates mengubah sebagian kolom lalu membaca ulang entity supaya cache berisi data terbaru
+func (s *Store[T, ID]) Updates(ctx context.Context, id ID, values interface{}) (*T, error) {
+	model, err := s.model(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.WithContext(ctx).Model(model).Updates(values).Error
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := s.load(ctx, s.key(id), id)
+	if err != nil {
+		return nil, err
+	}
+	return decode[T](value)
+}
+
+func (s *Store[T, ID]) Delete(ctx context.Context, id ID) error {
+	model, err := s.model(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Delete(model).Error
+}
+
+// model dengan primary key terisi, supaya callback hanya membuang cache id tersebut
+func (s *Store[T, ID]) model(ctx context.Context, id ID) (*T, error) {
+	model := new(T)
+	err := s.primary.Set(ctx, reflect.ValueOf(model).Elem(), id)
+	return model, err
+}
+
+func (s *Store[T, ID]) Invalidate(ids ...ID) {
+	s.writes.Add(1)
+	for _, id := range ids {
+		s.cache.Delete(s.key(id))
+	}
+}
+
+// Purge membuang semua entity di cache
+func (s *Store[T, ID]) Purge() {
+	s.writes.Add(1)
+	s.generation.Add(1)
+}
+
+func (s *Store[T, ID]) Stats() Stats {
+	return Stats{
+		Hits:         s.hits.Load(),
+		NegativeHits: s.negativeHits.Load(),
+		Misses:       s.misses.Load(),
+		Loads:        s.loads.Load(),
+	}
+}
+
+// invalidateStatement membuang cache id yang ada di model (GORM otomatis menambahkan
+// kondisi primary key), kalau model tidak punya id semua cache dibuang.
+// Di dalam transaksi cache baru dibuang setelah commit, karena sebelum itu
+// koneksi lain masih membaca data lama dan bisa menyimpannya lagi ke cache
+func (s *Store[T, ID]) invalidateStatement(db *gorm.DB) {
+	if db.Statement.Schema == nil || db.Statement.Schema.Table != s.table {
+		return
+	}
+
+	var ids []interface{}
+	reflectValue := db.Statement.ReflectValue
+	switch reflectValue.Kind() {
+	case reflect.Struct:
+		if id, zero := s.primary.ValueOf(db.Statement.Context, reflectValue); !zero {
+			ids = append(ids, id)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < reflectValue.Len(); i++ {
+			if id, zero := s.primary.ValueOf(db.Statement.Context, reflectValue.Index(i)); !zero {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	txhook.AfterCommit(db, func() {
+		if len(ids) == 0 {
+			s.Purge()
+			return
+		}
+
+		s.writes.Add(1)
+		for _, id := range ids {
+			s.cache.Delete(s.key(id))
+		}
+	})
+}
+
+func decode[T any](value []byte) (*T, error) {
+	entity := new(T)
+	if err := json.Unmarshal(value, entity); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
@@ -0,0 +1,209 @@
+package entitycache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/entitycache"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type User = belajargolanggorm.User
+type Wallet = belajargolanggorm.Wallet
+
+var ctx = context.Background()
+
+func setup(t *testing.T, options entitycache.Options) (*gorm.DB, *entitycache.Store[User, int], *atomic.Int64) {
+	db := testdb.Open(t)
+	assert.Nil(t, db.Create(&User{ID: 1, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Eko"}}).Error)
+
+	queries := &atomic.Int64{}
+	err := db.Callback().Query().Before("gorm:query").Register("test:count", func(db *gorm.DB) {
+		queries.Add(1)
+	})
+	assert.Nil(t, err)
+
+	store, err := entitycache.New[User, int](db, options)
+	assert.Nil(t, err)
+	return db, store, queries
+}
+
+func TestGetByID(t *testing.T) {
+	_, store, queries := setup(t, entitycache.Options{})
+
+	user, err := store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Eko", user.Name.FirstName)
+
+	// hasil cache adalah salinan, mengubahnya tidak mengubah cache
+	user.Name.FirstName = "Diubah"
+	user, err = store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Eko", user.Name.FirstName)
+	assert.Equal(t, int64(1), queries.Load())
+
+	_, err = store.GetByID(ctx, 99)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	_, err = store.GetByID(ctx, 99)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	assert.Equal(t, int64(2), queries.Load())
+	assert.Equal(t, entitycache.Stats{Hits: 1, NegativeHits: 1, Misses: 2, Loads: 2}, store.Stats())
+}
+
+func TestNegativeCacheClearedOnCreate(t *testing.T) {
+	db, store, _ := setup(t, entitycache.Options{})
+
+	_, err := store.GetByID(ctx, 2)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+	assert.Nil(t, db.Create(&User{ID: 2, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Budi"}}).Error)
+	user, err := store.GetByID(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "Budi", user.Name.FirstName)
+}
+
+func TestNegativeCacheDisabled(t *testing.T) {
+	_, store, queries := setup(t, entitycache.Options{NegativeTTL: -1})
+
+	for i := 0; i < 3; i++ {
+		_, err := store.GetByID(ctx, 99)
+		assert.Equal(t, gorm.ErrRecordNotFound, err)
+	}
+	assert.Equal(t, int64(3), queries.Load())
+}
+
+func TestWriteThrough(t *testing.T) {
+	_, store, queries := setup(t, entitycache.Options{})
+
+	user, err := store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+
+	user.Name.LastName = "Khannedy"
+	assert.Nil(t, store.Save(ctx, user))
+	before := queries.Load()
+	user, err = store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Khannedy", user.Name.LastName)
+	assert.Equal(t, before, queries.Load())
+
+	user, err = store.Updates(ctx, 1, map[string]interface{}{"first_name": "Budi"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Budi", user.Name.FirstName)
+	before = queries.Load()
+	user, err = store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Budi", user.Name.FirstName)
+	assert.Equal(t, "Khannedy", user.Name.LastName)
+	assert.Equal(t, before, queries.Load())
+
+	assert.Nil(t, store.Delete(ctx, 1))
+	_, err = store.GetByID(ctx, 1)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+}
+
+func TestSaveDoesNotCacheRelations(t *testing.T) {
+	db, store, _ := setup(t, entitycache.Options{})
+	assert.Nil(t, db.Create(&Wallet{ID: "1", UserId: 1, Balance: 1000}).Error)
+
+	var user User
+	assert.Nil(t, db.Preload("Wallet").Take(&user, 1).Error)
+	user.Name.LastName = "Khannedy"
+	assert.Nil(t, store.Save(ctx, &user))
+
+	// wallet berubah di tempat lain, cache user tidak boleh menyimpan wallet lama
+	assert.Nil(t, db.Model(&Wallet{ID: "1"}).Update("balance", 0).Error)
+	cached, err := store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Khannedy", cached.Name.LastName)
+	assert.Equal(t, "", cached.Wallet.ID)
+}
+
+func TestInvalidateOnExternalWrite(t *testing.T) {
+	db, store, _ := setup(t, entitycache.Options{})
+
+	_, err := store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+
+	// update dengan kondisi bebas, id tidak diketahui sehingga semua cache dibuang
+	assert.Nil(t, db.Model(&User{}).Where("first_name = ?", "Eko").Update("last_name", "Kurniawan").Error)
+	user, err := store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Kurniawan", user.Name.LastName)
+
+	assert.Nil(t, db.Model(&User{ID: 1}).Update("middle_name", "Programmer").Error)
+	user, err = store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Programmer", user.Name.MiddleName)
+}
+
+func TestInvalidateAfterCommit(t *testing.T) {
+	db, store, _ := setup(t, entitycache.Options{})
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{ID: 1}).Update("first_name", "Joko").Error; err != nil {
+			return err
+		}
+		// store membaca lewat koneksi lain yang belum melihat perubahan
+		user, err := store.GetByID(ctx, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, "Eko", user.Name.FirstName)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	user, err := store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Joko", user.Name.FirstName)
+}
+
+func TestTTL(t *testing.T) {
+	_, store, queries := setup(t, entitycache.Options{TTL: 20 * time.Millisecond})
+
+	_, err := store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	time.Sleep(30 * time.Millisecond)
+	_, err = store.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), queries.Load())
+}
+
+func TestSingleFlight(t *testing.T) {
+	db, store, queries := setup(t, entitycache.Options{})
+	err := db.Callback().Query().Before("gorm:query").Register("test:slow", func(db *gorm.DB) {
+		time.Sleep(50 * time.Millisecond)
+	})
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := store.GetByID(ctx, 1)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, user.ID)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), queries.Load())
+	assert.Equal(t, uint64(1), store.Stats().Loads)
+}
+
+func TestStringPrimaryKey(t *testing.T) {
+	db := testdb.Open(t)
+	assert.Nil(t, db.Create(&Wallet{ID: "W1", UserId: 1, Balance: 1000}).Error)
+
+	store, err := entitycache.New[Wallet, string](db, entitycache.Options{})
+	assert.Nil(t, err)
+	wallet, err := store.GetByID(ctx, "W1")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1000), wallet.Balance)
+}
@@ -0,0 +1,324 @@
+package export
+
+import (
+	"bufio"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+
+	"belajar-golang-gorm/internal/sensitive"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	DefaultBatchSize = 500
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown export format")
+	ErrUnknownColumn = errors.New("unknown column")
+)
+
+// SensitiveColumns tidak ikut diekspor kecuali disebut langsung di Options.Columns
+var SensitiveColumns = sensitive.Columns
+
+type Options struct {
+	Format string // FormatCSV atau FormatNDJSON, kosong berarti CSV
+	// Columns berisi nama kolom database, kosong berarti semua kolom selain SensitiveColumns
+	Columns   []string
+	BatchSize int // 0 berarti DefaultBatchSize
+}
+
+// Columns mengembalikan kolom yang bisa diekspor sesuai urutan field di struct,
+// field dari struct embedded seperti Name ikut sebagai kolom biasa
+func Columns(db *gorm.DB, model interface{}) ([]string, error) {
+	sch, err := parse(db, model)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	for _, field := range exportable(sch) {
+		columns = append(columns, field.DBName)
+	}
+	return columns, nil
+}
+
+func parse(db *gorm.DB, model interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+func exportable(sch *schema.Schema) []*schema.Field {
+	var fields []*schema.Field
+	for _, field := range sch.Fields {
+		if field.DBName != "" && field.Readable {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func selectFields(sch *schema.Schema, columns []string) ([]*schema.Field, error) {
+	if len(columns) == 0 {
+		sensitive := map[string]bool{}
+		for _, column := range SensitiveColumns {
+			sensitive[column] = true
+		}
+
+		var fields []*schema.Field
+		for _, field := range exportable(sch) {
+			if !sensitive[field.DBName] {
+				fields = append(fields, field)
+			}
+		}
+		return fields, nil
+	}
+
+	fields := make([]*schema.Field, len(columns))
+	for i, column := range columns {
+		field := sch.LookUpField(column)
+		if field == nil || field.DBName == "" || !field.Readable {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// Export menulis semua baris model ke w, condition dan scope yang sudah ada di db tetap dipakai.
+// Baris dibaca per batch berdasarkan primary key dengan Rows dan ScanRows,
+// jadi memory yang dipakai tidak bergantung pada jumlah baris
+func Export(db *gorm.DB, model interface{}, w io.Writer, options Options) (int64, error) {
+	sch, err := parse(db, model)
+	if err != nil {
+		return 0, err
+	}
+	fields, err := selectFields(sch, options.Columns)
+	if err != nil {
+		return 0, err
+	}
+
+	var encoder rowEncoder
+	buffered := bufio.NewWriter(w)
+	switch options.Format {
+	case FormatCSV, "":
+		encoder = newCSVEncoder(buffered, fields)
+	case FormatNDJSON:
+		encoder = &ndjsonEncoder{writer: buffered, fields: fields}
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownFormat, options.Format)
+	}
+
+	batchSize := options.BatchSize
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+
+	selected := make([]string, 0, len(fields)+1)
+	for _, field := range fields {
+		selected = append(selected, field.DBName)
+	}
+	primary := sch.PrioritizedPrimaryField
+	if primary != nil && sch.LookUpField(primary.DBName) != nil && !contains(selected, primary.DBName) {
+		selected = append(selected, primary.DBName)
+	}
+
+	modelType := sch.ModelType
+	var count int64
+	var last interface{}
+	for {
+		query := db.Session(&gorm.Session{}).Model(model).Select(selected)
+		if primary != nil {
+			column := clause.Column{Table: clause.CurrentTable, Name: primary.DBName}
+			query = query.Order(clause.OrderByColumn{Column: column}).Limit(batchSize)
+			if last != nil {
+				query = query.Where(clause.Gt{Column: column, Value: last})
+			}
+		}
+
+		rows, err := query.Rows()
+		if err != nil {
+			return count, err
+		}
+
+		batch := 0
+		for rows.Next() {
+			row := reflect.New(modelType)
+			if err := db.ScanRows(rows, row.Interface()); err != nil {
+				rows.Close()
+				return count, err
+			}
+			if err := encoder.encode(db, row.Elem()); err != nil {
+				rows.Close()
+				return count, err
+			}
+			if primary != nil {
+				last, _ = primary.ValueOf(db.Statement.Context, row.Elem())
+			}
+			batch++
+			count++
+		}
+		err = errors.Join(rows.Err(), rows.Close())
+		if err != nil {
+			return count, err
+		}
+
+		// tanpa primary key semua baris dibaca dalam satu query
+		if primary == nil || batch < batchSize {
+			break
+		}
+	}
+
+	if err := encoder.flush(); err != nil {
+		return count, err
+	}
+	return count, buffered.Flush()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type rowEncoder interface {
+	encode(db *gorm.DB, row reflect.Value) error
+	flush() error
+}
+
+type csvEncoder struct {
+	writer *csv.Writer
+	fields []*schema.Field
+	header bool
+	record []string
+}
+
+func newCSVEncoder(w io.Writer, fields []*schema.Field) *csvEncoder {
+	return &csvEncoder{writer: csv.NewWriter(w), fields: fields, record: make([]string, len(fields))}
+}
+
+func (e *csvEncoder) encode(db *gorm.DB, row reflect.Value) error {
+	if !e.header {
+		e.header = true
+		for i, field := range e.fields {
+			e.record[i] = field.DBName
+		}
+		if err := e.writer.Write(e.record); err != nil {
+			return err
+		}
+	}
+
+	for i, field := range e.fields {
+		value, _ := field.ValueOf(db.Statement.Context, row)
+		text, err := formatCSV(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.DBName, err)
+		}
+		e.record[i] = text
+	}
+	return e.writer.Write(e.record)
+}
+
+// header tetap ditulis walaupun tidak ada baris
+func (e *csvEncoder) flush() error {
+	if !e.header {
+		e.header = true
+		for i, field := range e.fields {
+			e.record[i] = field.DBName
+		}
+		if err := e.writer.Write(e.record); err != nil {
+			return err
+		}
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func formatCSV(value interface{}) (string, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v := reflect.ValueOf(valuer); v.Kind() == reflect.Ptr && v.IsNil() {
+			return "", nil
+		}
+		var err error
+		value, err = valuer.Value()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() == reflect.Ptr {
+		if reflectValue.IsNil() {
+			return "", nil
+		}
+		return formatCSV(reflectValue.Elem().Interface())
+	}
+	return fmt.Sprint(value), nil
+}
+
+type ndjsonEncoder struct {
+	writer *bufio.Writer
+	fields []*schema.Field
+}
+
+// key ditulis manual supaya urutannya sama dengan urutan kolom
+func (e *ndjsonEncoder) encode(db *gorm.DB, row reflect.Value) error {
+	e.writer.WriteByte('{')
+	for i, field := range e.fields {
+		if i > 0 {
+			e.writer.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.DBName)
+		e.writer.Write(key)
+		e.writer.WriteByte(':')
+
+		value, _ := field.ValueOf(db.Statement.Context, row)
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.DBName, err)
+		}
+		e.writer.Write(encoded)
+	}
+	e.writer.WriteByte('}')
+	return e.writer.WriteByte('\n')
+}
+
+func (e *ndjsonEncoder) flush() error {
+	return nil
+}
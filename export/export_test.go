@@ -0,0 +1,126 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/export"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type User = belajargolanggorm.User
+type Wallet = belajargolanggorm.Wallet
+type UserLog = belajargolanggorm.UserLog
+
+func setup(t *testing.T) *gorm.DB {
+	db := testdb.Open(t)
+	email := "eko@example.com"
+	users := []User{
+		{ID: 1, Password: "rahasia", Email: &email, Name: belajargolanggorm.Name{FirstName: "Eko", MiddleName: "Kurniawan", LastName: "Khannedy"}},
+		{ID: 2, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Budi, \"Si Pintar\""}},
+		{ID: 3, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Joko"}},
+		{ID: 4, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Agus"}},
+		{ID: 5, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Andi"}},
+	}
+	assert.Nil(t, db.Create(&users).Error)
+	return db
+}
+
+func TestColumns(t *testing.T) {
+	db := setup(t)
+
+	columns, err := export.Columns(db, &User{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "first_name", "last_name", "middle_name", "email", "email_verified_at", "password", "created_at", "updated_at"}, columns)
+}
+
+func TestExportCSV(t *testing.T) {
+	db := setup(t)
+
+	var buffer bytes.Buffer
+	count, err := export.Export(db, &User{}, &buffer, export.Options{BatchSize: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), count)
+
+	records, err := csv.NewReader(&buffer).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(records))
+	assert.Equal(t, []string{"id", "first_name", "last_name", "middle_name", "email", "email_verified_at", "created_at", "updated_at"}, records[0])
+	assert.Equal(t, []string{"1", "Eko", "Khannedy", "Kurniawan", "eko@example.com", ""}, records[1][:6])
+	assert.Equal(t, "Budi, \"Si Pintar\"", records[2][1])
+	assert.Equal(t, "5", records[5][0])
+	assert.NotContains(t, buffer.String(), "rahasia")
+}
+
+func TestExportNDJSON(t *testing.T) {
+	db := setup(t)
+
+	var buffer bytes.Buffer
+	count, err := export.Export(db.Where("id <= ?", 2), &User{}, &buffer, export.Options{
+		Format:  export.FormatNDJSON,
+		Columns: []string{"first_name", "email", "id"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equal(t, `{"first_name":"Eko","email":"eko@example.com","id":1}`, lines[0])
+
+	var row map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &row))
+	assert.Equal(t, map[string]interface{}{"first_name": "Budi, \"Si Pintar\"", "email": nil, "id": float64(2)}, row)
+}
+
+func TestExportBatchesMatchSingleQuery(t *testing.T) {
+	db := setup(t)
+
+	var batched, single bytes.Buffer
+	_, err := export.Export(db, &User{}, &batched, export.Options{BatchSize: 1, Columns: []string{"id", "first_name"}})
+	assert.Nil(t, err)
+	_, err = export.Export(db, &User{}, &single, export.Options{BatchSize: 100, Columns: []string{"id", "first_name"}})
+	assert.Nil(t, err)
+	assert.Equal(t, single.String(), batched.String())
+}
+
+func TestExportOtherModels(t *testing.T) {
+	db := setup(t)
+	assert.Nil(t, db.Create(&Wallet{ID: "W1", UserId: 1, Balance: 1500.5}).Error)
+	assert.Nil(t, db.Create(&UserLog{UserId: 1, Action: "login"}).Error)
+
+	var buffer bytes.Buffer
+	_, err := export.Export(db, &Wallet{}, &buffer, export.Options{Columns: []string{"id", "user_id", "balance"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "id,user_id,balance\nW1,1,1500.5\n", buffer.String())
+
+	buffer.Reset()
+	_, err = export.Export(db, &UserLog{}, &buffer, export.Options{Format: export.FormatNDJSON})
+	assert.Nil(t, err)
+	var row map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buffer.Bytes(), &row))
+	assert.Equal(t, "login", row["action"])
+	assert.Greater(t, row["created_at"], float64(0))
+
+	// tabel kosong tetap menulis header
+	buffer.Reset()
+	count, err := export.Export(db.Where("id = ?", 99), &User{}, &buffer, export.Options{Columns: []string{"id"}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+	assert.Equal(t, "id\n", buffer.String())
+}
+
+func TestExportErrors(t *testing.T) {
+	db := setup(t)
+
+	_, err := export.Export(db, &User{}, &bytes.Buffer{}, export.Options{Format: "xml"})
+	assert.ErrorIs(t, err, export.ErrUnknownFormat)
+
+	_, err = export.Export(db, &User{}, &bytes.Buffer{}, export.Options{Columns: []string{"information"}})
+	assert.ErrorIs(t, err, export.ErrUnknownColumn)
+}
@@ -5,36 +5,18 @@ import (
 	"fmt"
 	"strconv"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
-	"gorm.io/gorm/logger"
 )
 
 func OpenConnection() *gorm.DB {
-	dialect := mysql.Open("root:@tcp(127.0.0.1:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local")
-	db, err := gorm.Open(dialect, &gorm.Config{
-		Logger:                 logger.Default.LogMode(logger.Info),
-		SkipDefaultTransaction: true, // untuk menghindari auto transaction
-		PrepareStmt:            true, // untuk menggunakan prepared statement yang sudah pernah digunakan sehingga tidak perlu di prepare lagi
-	})
+	db, err := Connect(DefaultConfig())
 	if err != nil {
 		panic("failed to connect database")
 	}
 
-	sqlDB, err := db.DB()
-	if err != nil {
-		panic(err)
-	}
-
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(30 * time.Minute)
-	sqlDB.SetConnMaxIdleTime(10 * time.Minute)
-
 	return db
 }
 
@@ -630,16 +612,11 @@ func TestCreateManyToMany(t *testing.T) {
 	err := db.Create(&product).Error
 	assert.Nil(t, err)
 
-	err = db.Table("user_like_product").Create(&map[string]interface{}{
-		"user_id":    3,
-		"product_id": 1,
-	}).Error
+	likes := NewLikes(db)
+	err = likes.Like(3, 1)
 	assert.Nil(t, err)
 
-	err = db.Table("user_like_product").Create(&map[string]interface{}{
-		"user_id":    5,
-		"product_id": 1,
-	}).Error
+	err = likes.Like(5, 1)
 	assert.Nil(t, err)
 }
 
@@ -799,14 +776,6 @@ func TestContext(t *testing.T) {
 	assert.Nil(t, err)
 }
 
-func BrokeWalletBalance(db *gorm.DB) *gorm.DB {
-	return db.Where("balance = ?", 0)
-}
-
-func SultanWalletBalance(db *gorm.DB) *gorm.DB {
-	return db.Where("balance > ?", 1000000)
-}
-
 func TestScopes(t *testing.T) {
 	var wallets []Wallet
 	err := db.Scopes(BrokeWalletBalance).Find(&wallets).Error
@@ -818,7 +787,7 @@ func TestScopes(t *testing.T) {
 }
 
 func TestMigrator(t *testing.T) {
-	err := db.Migrator().AutoMigrate(&GuestBook{})
+	err := db.Migrator().AutoMigrate(&GuestBook{}, &GuestBookModeration{})
 	assert.Nil(t, err)
 }
 
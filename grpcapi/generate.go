@@ -0,0 +1,3 @@
+package grpcapi
+
+//go:generate buf generate
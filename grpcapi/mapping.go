@@ -0,0 +1,83 @@
+package grpcapi
+
+import (
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	pb "belajar-golang-gorm/grpcapi/gen/belajargorm/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UserToProto tidak pernah menyalin password,
+// wallet hanya disalin kalau sudah di-preload
+func UserToProto(user *belajargolanggorm.User) *pb.User {
+	message := &pb.User{
+		Id:              int64(user.ID),
+		FirstName:       user.Name.FirstName,
+		MiddleName:      user.Name.MiddleName,
+		LastName:        user.Name.LastName,
+		Email:           user.Email,
+		EmailVerifiedAt: timestampOrNil(user.EmailVerifiedAt),
+		CreatedAt:       timestamppb.New(user.CreatedAt),
+		UpdatedAt:       timestamppb.New(user.UpdatedAt),
+	}
+	if user.Wallet.ID != "" {
+		message.Wallet = WalletToProto(&user.Wallet)
+	}
+	return message
+}
+
+func UserFromProto(message *pb.User) *belajargolanggorm.User {
+	user := &belajargolanggorm.User{
+		ID: int(message.GetId()),
+		Name: belajargolanggorm.Name{
+			FirstName:  message.GetFirstName(),
+			MiddleName: message.GetMiddleName(),
+			LastName:   message.GetLastName(),
+		},
+		Email:           message.Email,
+		EmailVerifiedAt: timeOrNil(message.GetEmailVerifiedAt()),
+		CreatedAt:       message.GetCreatedAt().AsTime(),
+		UpdatedAt:       message.GetUpdatedAt().AsTime(),
+	}
+	if message.GetWallet() != nil {
+		user.Wallet = *WalletFromProto(message.GetWallet())
+	}
+	return user
+}
+
+func WalletToProto(wallet *belajargolanggorm.Wallet) *pb.Wallet {
+	return &pb.Wallet{
+		Id:        wallet.ID,
+		UserId:    int64(wallet.UserId),
+		Balance:   wallet.Balance,
+		CreatedAt: timestamppb.New(wallet.CreatedAt),
+		UpdatedAt: timestamppb.New(wallet.UpdatedAt),
+	}
+}
+
+func WalletFromProto(message *pb.Wallet) *belajargolanggorm.Wallet {
+	return &belajargolanggorm.Wallet{
+		ID:        message.GetId(),
+		UserId:    int(message.GetUserId()),
+		Balance:   message.GetBalance(),
+		CreatedAt: message.GetCreatedAt().AsTime(),
+		UpdatedAt: message.GetUpdatedAt().AsTime(),
+	}
+}
+
+func timestampOrNil(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func timeOrNil(t *timestamppb.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+	value := t.AsTime()
+	return &value
+}
@@ -0,0 +1,103 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/dbrouter"
+	pb "belajar-golang-gorm/grpcapi/gen/belajargorm/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type Server struct {
+	pb.UnimplementedUserServiceServer
+	pb.UnimplementedWalletServiceServer
+	db *gorm.DB
+}
+
+func NewServer(db *gorm.DB) *Server {
+	return &Server{db: db}
+}
+
+// Register mendaftarkan UserService dan WalletService ke grpc.Server
+func Register(registrar grpc.ServiceRegistrar, db *gorm.DB) *Server {
+	server := NewServer(db)
+	pb.RegisterUserServiceServer(registrar, server)
+	pb.RegisterWalletServiceServer(registrar, server)
+	return server
+}
+
+// SessionInterceptor menjadikan setiap RPC satu sesi dbrouter supaya query baca
+// setelah operasi tulis di RPC yang sama tidak membaca replica yang tertinggal
+func SessionInterceptor(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(dbrouter.Session(ctx), request)
+}
+
+func (s *Server) GetUser(ctx context.Context, request *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	query := s.db.WithContext(ctx)
+	if request.GetIncludeWallet() {
+		query = query.Preload("Wallet")
+	}
+
+	var user belajargolanggorm.User
+	err := query.Take(&user, "id = ?", request.GetId()).Error
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.GetUserResponse{User: UserToProto(&user)}, nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, request *pb.GetBalanceRequest) (*pb.GetBalanceResponse, error) {
+	wallet, err := belajargolanggorm.FindWallet(s.db.WithContext(ctx), int(request.GetUserId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.GetBalanceResponse{Wallet: WalletToProto(wallet)}, nil
+}
+
+func (s *Server) Credit(ctx context.Context, request *pb.CreditRequest) (*pb.CreditResponse, error) {
+	wallet, err := belajargolanggorm.CreditWallet(s.db.WithContext(ctx), int(request.GetUserId()), request.GetAmount())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.CreditResponse{Wallet: WalletToProto(wallet)}, nil
+}
+
+func (s *Server) Debit(ctx context.Context, request *pb.DebitRequest) (*pb.DebitResponse, error) {
+	wallet, err := belajargolanggorm.DebitWallet(s.db.WithContext(ctx), int(request.GetUserId()), request.GetAmount())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DebitResponse{Wallet: WalletToProto(wallet)}, nil
+}
+
+func (s *Server) Transfer(ctx context.Context, request *pb.TransferRequest) (*pb.TransferResponse, error) {
+	from, to, err := belajargolanggorm.TransferBalance(s.db.WithContext(ctx),
+		int(request.GetFromUserId()), int(request.GetToUserId()), request.GetAmount())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.TransferResponse{From: WalletToProto(from), To: WalletToProto(to)}, nil
+}
+
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, belajargolanggorm.ErrWalletNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, belajargolanggorm.ErrInvalidAmount), errors.Is(err, belajargolanggorm.ErrSameWallet):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, belajargolanggorm.ErrInsufficientBalance):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
@@ -0,0 +1,152 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/dbrouter"
+	"belajar-golang-gorm/grpcapi"
+	pb "belajar-golang-gorm/grpcapi/gen/belajargorm/v1"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T) *grpc.ClientConn {
+	db := testdb.Open(t)
+	email := "eko@example.com"
+	users := []belajargolanggorm.User{
+		{ID: 1, Password: "rahasia", Email: &email, Name: belajargolanggorm.Name{FirstName: "Eko", LastName: "Khannedy"},
+			Wallet: belajargolanggorm.Wallet{ID: "w1", UserId: 1, Balance: 1000}},
+		{ID: 2, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Budi"},
+			Wallet: belajargolanggorm.Wallet{ID: "w2", UserId: 2, Balance: 0}},
+		{ID: 3, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "Tanpa Wallet"}},
+	}
+	assert.Nil(t, db.Omit("Wallet").Create(&users).Error)
+	assert.Nil(t, db.Create(&[]belajargolanggorm.Wallet{users[0].Wallet, users[1].Wallet}).Error)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.UnaryInterceptor(grpcapi.SessionInterceptor))
+	grpcapi.Register(server, db)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGetUser(t *testing.T) {
+	client := pb.NewUserServiceClient(newClient(t))
+	ctx := context.Background()
+
+	response, err := client.GetUser(ctx, &pb.GetUserRequest{Id: 1, IncludeWallet: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "Eko", response.User.FirstName)
+	assert.Equal(t, "eko@example.com", response.User.GetEmail())
+	assert.Nil(t, response.User.EmailVerifiedAt)
+	assert.Equal(t, float64(1000), response.User.Wallet.Balance)
+
+	response, err = client.GetUser(ctx, &pb.GetUserRequest{Id: 2})
+	assert.Nil(t, err)
+	assert.Nil(t, response.User.Email)
+	assert.Nil(t, response.User.Wallet)
+
+	_, err = client.GetUser(ctx, &pb.GetUserRequest{Id: 99})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestWalletOperations(t *testing.T) {
+	client := pb.NewWalletServiceClient(newClient(t))
+	ctx := context.Background()
+
+	balance, err := client.GetBalance(ctx, &pb.GetBalanceRequest{UserId: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "w1", balance.Wallet.Id)
+	assert.Equal(t, float64(1000), balance.Wallet.Balance)
+
+	credit, err := client.Credit(ctx, &pb.CreditRequest{UserId: 2, Amount: 250})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(250), credit.Wallet.Balance)
+
+	debit, err := client.Debit(ctx, &pb.DebitRequest{UserId: 1, Amount: 100})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(900), debit.Wallet.Balance)
+
+	transfer, err := client.Transfer(ctx, &pb.TransferRequest{FromUserId: 1, ToUserId: 2, Amount: 400})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(500), transfer.From.Balance)
+	assert.Equal(t, float64(650), transfer.To.Balance)
+}
+
+func TestWalletErrors(t *testing.T) {
+	client := pb.NewWalletServiceClient(newClient(t))
+	ctx := context.Background()
+
+	_, err := client.GetBalance(ctx, &pb.GetBalanceRequest{UserId: 3})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Credit(ctx, &pb.CreditRequest{UserId: 1, Amount: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Debit(ctx, &pb.DebitRequest{UserId: 2, Amount: 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.Transfer(ctx, &pb.TransferRequest{FromUserId: 1, ToUserId: 1, Amount: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Transfer(ctx, &pb.TransferRequest{FromUserId: 1, ToUserId: 3, Amount: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// transfer gagal tidak mengubah saldo
+	balance, err := client.GetBalance(ctx, &pb.GetBalanceRequest{UserId: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1000), balance.Wallet.Balance)
+}
+
+func TestMapping(t *testing.T) {
+	verified := time.Date(2025, 5, 11, 9, 0, 1, 0, time.UTC)
+	email := "eko@example.com"
+	user := &belajargolanggorm.User{
+		ID:              7,
+		Password:        "rahasia",
+		Email:           &email,
+		EmailVerifiedAt: &verified,
+		Name:            belajargolanggorm.Name{FirstName: "Eko", MiddleName: "Kurniawan", LastName: "Khannedy"},
+		CreatedAt:       verified,
+		UpdatedAt:       verified,
+		Wallet:          belajargolanggorm.Wallet{ID: "w7", UserId: 7, Balance: 10, CreatedAt: verified, UpdatedAt: verified},
+	}
+
+	back := grpcapi.UserFromProto(grpcapi.UserToProto(user))
+	assert.Equal(t, "", back.Password)
+	back.Password = user.Password
+	assert.Equal(t, user, back)
+}
+
+func TestSessionInterceptor(t *testing.T) {
+	var sessionCtx context.Context
+	_, err := grpcapi.SessionInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, request interface{}) (interface{}, error) {
+		sessionCtx = ctx
+		return nil, nil
+	})
+	assert.Nil(t, err)
+
+	// context yang sudah punya sesi dikembalikan apa adanya oleh dbrouter.Session
+	assert.NotEqual(t, context.Background(), sessionCtx)
+	assert.Equal(t, sessionCtx, dbrouter.Session(sessionCtx))
+}
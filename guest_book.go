@@ -1,12 +1,117 @@
 package belajargolanggorm
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	GuestBookPending  = "pending"
+	GuestBookApproved = "approved"
+	GuestBookRejected = "rejected"
+	GuestBookSpam     = "spam"
+)
+
+var ErrInvalidGuestBookStatus = errors.New("invalid guest book status")
 
 type GuestBook struct {
-	ID        int       `gorm:"column:id;primary_key;AUTO_INCREMENT"`
-	Name      string    `gorm:"column:name"`
-	Email     string    `gorm:"column:email"`
-	Message   string    `gorm:"column:message"`
-	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	ID          int                   `gorm:"column:id;primary_key;AUTO_INCREMENT"`
+	Name        string                `gorm:"column:name"`
+	Email       string                `gorm:"column:email;size:100;index:idx_guest_books_email_created,priority:1"`
+	Message     string                `gorm:"column:message"`
+	IP          string                `gorm:"column:ip;size:45;index:idx_guest_books_ip_created,priority:1"`
+	MessageHash string                `gorm:"column:message_hash;size:64;index"`
+	SpamScore   float64               `gorm:"column:spam_score"`
+	SpamReason  string                `gorm:"column:spam_reason"`
+	Status      string                `gorm:"column:status;size:20;index;default:pending"`
+	ModeratedBy string                `gorm:"column:moderated_by"`
+	ModeratedAt *time.Time            `gorm:"column:moderated_at"`
+	CreatedAt   time.Time             `gorm:"column:created_at;autoCreateTime;index:idx_guest_books_email_created,priority:2;index:idx_guest_books_ip_created,priority:2"`
+	UpdatedAt   time.Time             `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	Moderations []GuestBookModeration `gorm:"foreignKey:guest_book_id;references:id"` //relasi one to many
+}
+
+// audit trail setiap perubahan status guest book
+type GuestBookModeration struct {
+	ID          int       `gorm:"column:id;primary_key;autoIncrement"`
+	GuestBookId int       `gorm:"column:guest_book_id;index"`
+	Moderator   string    `gorm:"column:moderator"`
+	FromStatus  string    `gorm:"column:from_status;size:20"`
+	ToStatus    string    `gorm:"column:to_status;size:20"`
+	Reason      string    `gorm:"column:reason"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime;<-:create"`
+}
+
+// scope untuk listing publik, hanya guest book yang sudah di-approve
+func ApprovedGuestBook(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ?", GuestBookApproved)
+}
+
+func PublicGuestBooks(db *gorm.DB, limit int) ([]GuestBook, error) {
+	var guestBooks []GuestBook
+	err := db.Scopes(ApprovedGuestBook).Order("created_at desc, id desc").Limit(limit).Find(&guestBooks).Error
+	return guestBooks, err
+}
+
+// ModerationQueue mengembalikan guest book yang menunggu moderasi, paling lama lebih dulu
+func ModerationQueue(db *gorm.DB, limit int) ([]GuestBook, error) {
+	var guestBooks []GuestBook
+	err := db.Where("status = ?", GuestBookPending).Order("created_at asc, id asc").Limit(limit).Find(&guestBooks).Error
+	return guestBooks, err
+}
+
+func ModerateGuestBook(db *gorm.DB, id int, moderator string, status string, reason string) error {
+	switch status {
+	case GuestBookPending, GuestBookApproved, GuestBookRejected, GuestBookSpam:
+	default:
+		return ErrInvalidGuestBookStatus
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var guestBook GuestBook
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&guestBook, "id = ?", id).Error
+		if err != nil {
+			return err
+		}
+
+		fromStatus := guestBook.Status
+		now := time.Now()
+		err = tx.Model(&guestBook).Updates(map[string]interface{}{
+			"status":       status,
+			"moderated_by": moderator,
+			"moderated_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&GuestBookModeration{
+			GuestBookId: guestBook.ID,
+			Moderator:   moderator,
+			FromStatus:  fromStatus,
+			ToStatus:    status,
+			Reason:      reason,
+		}).Error
+	})
+}
+
+func ApproveGuestBook(db *gorm.DB, id int, moderator string) error {
+	return ModerateGuestBook(db, id, moderator, GuestBookApproved, "")
+}
+
+func RejectGuestBook(db *gorm.DB, id int, moderator string, reason string) error {
+	return ModerateGuestBook(db, id, moderator, GuestBookRejected, reason)
+}
+
+func MarkGuestBookSpam(db *gorm.DB, id int, moderator string) error {
+	return ModerateGuestBook(db, id, moderator, GuestBookSpam, "")
+}
+
+func GuestBookModerationHistory(db *gorm.DB, id int) ([]GuestBookModeration, error) {
+	var moderations []GuestBookModeration
+	err := db.Where("guest_book_id = ?", id).Order("id asc").Find(&moderations).Error
+	return moderations, err
 }
@@ -0,0 +1,306 @@
+package belajargolanggorm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// GuestBookSpamFilter dijalankan oleh hook BeforeCreate GuestBook,
+// isi dengan nil untuk mematikan pengecekan spam
+var GuestBookSpamFilter = DefaultSpamFilter()
+
+var DefaultBlockedWords = []string{"casino", "judi online", "slot gacor", "viagra", "pinjol"}
+
+type SpamVerdict struct {
+	Score   float64
+	Reasons []string
+	Spam    bool
+}
+
+// SpamCheck memberi skor spam untuk satu guest book, 0 berarti bersih.
+// reason kosong kalau check tidak menemukan apa-apa.
+type SpamCheck interface {
+	Check(db *gorm.DB, guestBook *GuestBook) (score float64, reason string, err error)
+}
+
+type SpamFilter struct {
+	Checks    []SpamCheck
+	Threshold float64
+}
+
+func DefaultSpamFilter() *SpamFilter {
+	return &SpamFilter{
+		Checks: []SpamCheck{
+			LinkCheck{MaxLinks: 2, Score: 0.5},
+			BlockedWordCheck{Words: DefaultBlockedWords, Score: 0.5},
+			DuplicateMessageCheck{Window: 24 * time.Hour, Score: 1},
+			RateLimitCheck{Limit: 5, Window: time.Hour, Score: 1},
+		},
+		Threshold: 1,
+	}
+}
+
+func (f *SpamFilter) Check(db *gorm.DB, guestBook *GuestBook) (SpamVerdict, error) {
+	var verdict SpamVerdict
+	for _, check := range f.Checks {
+		score, reason, err := check.Check(db, guestBook)
+		if err != nil {
+			return verdict, err
+		}
+
+		verdict.Score += score
+		if reason != "" {
+			verdict.Reasons = append(verdict.Reasons, reason)
+		}
+	}
+	verdict.Spam = verdict.Score >= f.Threshold
+
+	return verdict, nil
+}
+
+// hook before create, email dinormalisasi, message di-hash untuk deteksi duplikat lalu dicek spam
+func (g *GuestBook) BeforeCreate(tx *gorm.DB) error {
+	if g.Email != "" {
+		email, err := NormalizeEmail(g.Email)
+		if err != nil {
+			return err
+		}
+		g.Email = email
+	}
+
+	g.MessageHash = MessageHash(g.Message)
+	if GuestBookSpamFilter == nil {
+		return nil
+	}
+
+	verdict, err := GuestBookSpamFilter.Check(tx.Session(&gorm.Session{NewDB: true}), g)
+	if err != nil {
+		return err
+	}
+
+	g.SpamScore = verdict.Score
+	g.SpamReason = strings.Join(verdict.Reasons, ", ")
+	if verdict.Spam {
+		g.Status = GuestBookSpam
+	}
+
+	return nil
+}
+
+// MessageHash dipakai untuk mendeteksi pesan yang sama walaupun beda huruf besar kecil atau spasi
+func MessageHash(message string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(message)), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)`)
+
+type LinkCheck struct {
+	MaxLinks int
+	Score    float64
+}
+
+func (c LinkCheck) Check(db *gorm.DB, guestBook *GuestBook) (float64, string, error) {
+	links := len(linkPattern.FindAllStringIndex(guestBook.Message, -1))
+	if links > c.MaxLinks {
+		return c.Score, "too many links", nil
+	}
+	return 0, "", nil
+}
+
+type BlockedWordCheck struct {
+	Words []string
+	Score float64
+}
+
+// setiap kata terlarang yang ditemukan menambah skor
+func (c BlockedWordCheck) Check(db *gorm.DB, guestBook *GuestBook) (float64, string, error) {
+	message := strings.ToLower(guestBook.Message)
+	var found []string
+	for _, word := range c.Words {
+		if strings.Contains(message, strings.ToLower(word)) {
+			found = append(found, word)
+		}
+	}
+
+	if len(found) == 0 {
+		return 0, "", nil
+	}
+	return c.Score * float64(len(found)), "blocked words: " + strings.Join(found, ", "), nil
+}
+
+type DuplicateMessageCheck struct {
+	Window time.Duration
+	Score  float64
+}
+
+func (c DuplicateMessageCheck) Check(db *gorm.DB, guestBook *GuestBook) (float64, string, error) {
+	var count int64
+	err := db.Model(&GuestBook{}).
+		Where("message_hash = ? AND created_at > ?", MessageHash(guestBook.Message), time.Now().Add(-c.Window)).
+		Count(&count).Error
+	if err != nil {
+		return 0, "", err
+	}
+
+	if count > 0 {
+		return c.Score, "duplicate message", nil
+	}
+	return 0, "", nil
+}
+
+// RateLimitCheck menghitung guest book dari email atau IP yang sama di database
+// dalam rentang Window
+type RateLimitCheck struct {
+	Limit  int
+	Window time.Duration
+	Score  float64
+}
+
+func (c RateLimitCheck) Check(db *gorm.DB, guestBook *GuestBook) (float64, string, error) {
+	since := time.Now().Add(-c.Window)
+	for _, condition := range []struct {
+		column string
+		value  string
+	}{
+		{"email", guestBook.Email},
+		{"ip", guestBook.IP},
+	} {
+		if condition.value == "" {
+			continue
+		}
+
+		var count int64
+		err := db.Model(&GuestBook{}).
+			Where(condition.column+" = ? AND created_at > ?", condition.value, since).
+			Count(&count).Error
+		if err != nil {
+			return 0, "", err
+		}
+
+		if count >= int64(c.Limit) {
+			return c.Score, "rate limit exceeded for " + condition.column, nil
+		}
+	}
+
+	return 0, "", nil
+}
+
+type BayesCheck struct {
+	Classifier *SpamClassifier
+	Score      float64
+}
+
+func (c BayesCheck) Check(db *gorm.DB, guestBook *GuestBook) (float64, string, error) {
+	probability := c.Classifier.SpamProbability(guestBook.Message)
+	if probability <= 0.5 {
+		return 0, "", nil
+	}
+	return probability * c.Score, "classified as spam", nil
+}
+
+// SpamClassifier adalah naive bayes sederhana dengan laplace smoothing
+type SpamClassifier struct {
+	mutex       sync.RWMutex
+	spamWords   map[string]int
+	hamWords    map[string]int
+	spamTokens  int
+	hamTokens   int
+	spamEntries int
+	hamEntries  int
+}
+
+func NewSpamClassifier() *SpamClassifier {
+	return &SpamClassifier{
+		spamWords: map[string]int{},
+		hamWords:  map[string]int{},
+	}
+}
+
+// TrainSpamClassifier belajar dari guest book yang sudah dimoderasi:
+// status spam sebagai contoh spam dan approved sebagai contoh bukan spam
+func TrainSpamClassifier(db *gorm.DB) (*SpamClassifier, error) {
+	classifier := NewSpamClassifier()
+
+	rows, err := db.Model(&GuestBook{}).Select("message", "status").
+		Where("status IN ?", []string{GuestBookSpam, GuestBookApproved}).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message, status string
+		if err := rows.Scan(&message, &status); err != nil {
+			return nil, err
+		}
+		classifier.Learn(message, status == GuestBookSpam)
+	}
+
+	return classifier, rows.Err()
+}
+
+func (c *SpamClassifier) Learn(message string, spam bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, token := range spamTokens(message) {
+		if spam {
+			c.spamWords[token]++
+			c.spamTokens++
+		} else {
+			c.hamWords[token]++
+			c.hamTokens++
+		}
+	}
+
+	if spam {
+		c.spamEntries++
+	} else {
+		c.hamEntries++
+	}
+}
+
+// SpamProbability mengembalikan 0.5 kalau classifier belum punya contoh spam dan bukan spam
+func (c *SpamClassifier) SpamProbability(message string) float64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.spamEntries == 0 || c.hamEntries == 0 {
+		return 0.5
+	}
+
+	vocabulary := map[string]bool{}
+	for word := range c.spamWords {
+		vocabulary[word] = true
+	}
+	for word := range c.hamWords {
+		vocabulary[word] = true
+	}
+	size := float64(len(vocabulary))
+
+	total := float64(c.spamEntries + c.hamEntries)
+	spamLog := math.Log(float64(c.spamEntries) / total)
+	hamLog := math.Log(float64(c.hamEntries) / total)
+	for _, token := range spamTokens(message) {
+		spamLog += math.Log((float64(c.spamWords[token]) + 1) / (float64(c.spamTokens) + size))
+		hamLog += math.Log((float64(c.hamWords[token]) + 1) / (float64(c.hamTokens) + size))
+	}
+
+	return 1 / (1 + math.Exp(hamLog-spamLog))
+}
+
+func spamTokens(message string) []string {
+	return strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,94 @@
+package belajargolanggorm
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpamLinksAndBlockedWords(t *testing.T) {
+	guestBook := GuestBook{
+		Name:    "Spammer",
+		Email:   "spammer@example.com",
+		Message: "Slot gacor hari ini http://a.test http://b.test www.c.test",
+	}
+	err := db.Create(&guestBook).Error
+	assert.Nil(t, err)
+	assert.Equal(t, GuestBookSpam, guestBook.Status)
+	assert.Equal(t, float64(1), guestBook.SpamScore)
+	assert.Equal(t, "too many links, blocked words: slot gacor", guestBook.SpamReason)
+
+	guestBook = GuestBook{
+		Name:    "Salman",
+		Email:   "salman.spam@example.com",
+		Message: "Lihat katalog di http://toko.test",
+	}
+	err = db.Create(&guestBook).Error
+	assert.Nil(t, err)
+	assert.Equal(t, GuestBookPending, guestBook.Status)
+	assert.NotEqual(t, "", guestBook.MessageHash)
+}
+
+func TestSpamDuplicateMessage(t *testing.T) {
+	guestBook := GuestBook{Name: "Seif", Email: "seif.dup@example.com", Message: "Pesan yang sama persis"}
+	err := db.Create(&guestBook).Error
+	assert.Nil(t, err)
+	assert.Equal(t, GuestBookPending, guestBook.Status)
+
+	guestBook = GuestBook{Name: "Man", Email: "man.dup@example.com", Message: "  PESAN yang   sama persis "}
+	err = db.Create(&guestBook).Error
+	assert.Nil(t, err)
+	assert.Equal(t, GuestBookSpam, guestBook.Status)
+	assert.Equal(t, "duplicate message", guestBook.SpamReason)
+}
+
+func TestSpamRateLimit(t *testing.T) {
+	for i := 1; i <= 5; i++ {
+		guestBook := GuestBook{Name: "Flood", Email: "flood@example.com", IP: "10.0.0.32", Message: "Pesan ke-" + strconv.Itoa(i)}
+		err := db.Create(&guestBook).Error
+		assert.Nil(t, err)
+		assert.Equal(t, GuestBookPending, guestBook.Status)
+	}
+
+	// email beda tapi IP sama
+	guestBook := GuestBook{Name: "Flood", Email: "flood2@example.com", IP: "10.0.0.32", Message: "Pesan ke-6"}
+	err := db.Create(&guestBook).Error
+	assert.Nil(t, err)
+	assert.Equal(t, GuestBookSpam, guestBook.Status)
+	assert.Equal(t, "rate limit exceeded for ip", guestBook.SpamReason)
+}
+
+func TestSpamClassifier(t *testing.T) {
+	classifier := NewSpamClassifier()
+	assert.Equal(t, 0.5, classifier.SpamProbability("apa saja"))
+
+	classifier.Learn("menang hadiah besar klik sekarang", true)
+	classifier.Learn("pinjaman cepat tanpa jaminan klik sekarang", true)
+	classifier.Learn("terima kasih pelayanannya ramah", false)
+	classifier.Learn("barang sampai dengan cepat, terima kasih", false)
+
+	assert.Greater(t, classifier.SpamProbability("klik sekarang untuk hadiah"), 0.5)
+	assert.Less(t, classifier.SpamProbability("terima kasih, barang bagus"), 0.5)
+}
+
+func TestTrainSpamClassifier(t *testing.T) {
+	guestBook := GuestBook{Name: "Salman", Email: "salman.ham@example.com", Message: "Terima kasih, pelayanan cepat"}
+	err := db.Create(&guestBook).Error
+	assert.Nil(t, err)
+
+	err = ApproveGuestBook(db, guestBook.ID, "admin")
+	assert.Nil(t, err)
+
+	classifier, err := TrainSpamClassifier(db)
+	assert.Nil(t, err)
+	assert.Greater(t, classifier.SpamProbability("slot gacor http://a.test"), 0.5)
+	assert.Less(t, classifier.SpamProbability("terima kasih"), 0.5)
+
+	filter := DefaultSpamFilter()
+	filter.Checks = append(filter.Checks, BayesCheck{Classifier: classifier, Score: 1})
+
+	verdict, err := filter.Check(db, &GuestBook{Message: "slot gacor"})
+	assert.Nil(t, err)
+	assert.True(t, verdict.Spam)
+}
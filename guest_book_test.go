@@ -0,0 +1,64 @@
+package belajargolanggorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuestBookModeration(t *testing.T) {
+	guestBooks := []GuestBook{
+		{Name: "Salman", Email: "salman@example.com", Message: "Mantap"},
+		{Name: "Seif", Email: "seif@example.com", Message: "Beli obat murah di sini"},
+	}
+	err := db.Create(&guestBooks).Error
+	assert.Nil(t, err)
+	assert.Equal(t, GuestBookPending, guestBooks[0].Status)
+
+	queue, err := ModerationQueue(db, 100)
+	assert.Nil(t, err)
+	ids := map[int]bool{}
+	for _, guestBook := range queue {
+		assert.Equal(t, GuestBookPending, guestBook.Status)
+		ids[guestBook.ID] = true
+	}
+	assert.True(t, ids[guestBooks[0].ID])
+	assert.True(t, ids[guestBooks[1].ID])
+
+	err = ApproveGuestBook(db, guestBooks[0].ID, "admin")
+	assert.Nil(t, err)
+
+	err = RejectGuestBook(db, guestBooks[1].ID, "admin", "iklan")
+	assert.Nil(t, err)
+
+	err = MarkGuestBookSpam(db, guestBooks[1].ID, "admin")
+	assert.Nil(t, err)
+
+	err = ModerateGuestBook(db, guestBooks[1].ID, "admin", "deleted", "")
+	assert.Equal(t, ErrInvalidGuestBookStatus, err)
+
+	history, err := GuestBookModerationHistory(db, guestBooks[1].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(history))
+	assert.Equal(t, GuestBookPending, history[0].FromStatus)
+	assert.Equal(t, GuestBookRejected, history[0].ToStatus)
+	assert.Equal(t, "iklan", history[0].Reason)
+	assert.Equal(t, GuestBookRejected, history[1].FromStatus)
+	assert.Equal(t, GuestBookSpam, history[1].ToStatus)
+
+	var guestBook GuestBook
+	err = db.Preload("Moderations").Take(&guestBook, "id = ?", guestBooks[0].ID).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", guestBook.ModeratedBy)
+	assert.NotNil(t, guestBook.ModeratedAt)
+	assert.Equal(t, 1, len(guestBook.Moderations))
+}
+
+func TestPublicGuestBooks(t *testing.T) {
+	guestBooks, err := PublicGuestBooks(db, 100)
+	assert.Nil(t, err)
+	assert.NotEqual(t, 0, len(guestBooks))
+	for _, guestBook := range guestBooks {
+		assert.Equal(t, GuestBookApproved, guestBook.Status)
+	}
+}
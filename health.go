@@ -0,0 +1,94 @@
+package belajargolanggorm
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"belajar-golang-gorm/dbrouter"
+
+	"gorm.io/gorm"
+)
+
+const (
+	HealthUp       = "up"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+type PoolHealth struct {
+	Name              string  `json:"name"`
+	Healthy           bool    `json:"healthy"`
+	Error             string  `json:"error,omitempty"`
+	OpenConnections   int     `json:"open_connections"`
+	InUse             int     `json:"in_use"`
+	Idle              int     `json:"idle"`
+	MaxOpen           int     `json:"max_open_connections"`
+	WaitCount         int64   `json:"wait_count"`
+	WaitDurationMs    float64 `json:"wait_duration_ms"`
+	PingLatencyMs     float64 `json:"ping_latency_ms,omitempty"`
+	MaxIdleClosed     int64   `json:"max_idle_closed"`
+	MaxLifetimeClosed int64   `json:"max_lifetime_closed"`
+}
+
+// Health adalah hasil HealthCheck, Status down kalau primary tidak bisa di-ping
+// dan degraded kalau ada replica yang tidak sehat
+type Health struct {
+	Status   string       `json:"status"`
+	Primary  PoolHealth   `json:"primary"`
+	Replicas []PoolHealth `json:"replicas,omitempty"`
+}
+
+// HealthCheck melakukan ping ke primary (dan replica kalau dbrouter dipakai)
+// lalu melaporkan latency dan statistik connection pool
+func HealthCheck(ctx context.Context, db *gorm.DB) Health {
+	health := Health{Status: HealthUp, Primary: PoolHealth{Name: "primary"}}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		health.Status = HealthDown
+		health.Primary.Error = err.Error()
+		return health
+	}
+
+	start := time.Now()
+	err = sqlDB.PingContext(ctx)
+	health.Primary = poolHealth("primary", sqlDB.Stats(), err)
+	health.Primary.PingLatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		health.Status = HealthDown
+	}
+
+	if router, ok := db.Config.Plugins["dbrouter"].(*dbrouter.Router); ok {
+		router.CheckHealth(ctx)
+		for _, replica := range router.Replicas() {
+			pool := poolHealth(replica.Name, replica.DB().Stats(), replica.LastError())
+			pool.Healthy = replica.Healthy()
+			if !pool.Healthy && health.Status == HealthUp {
+				health.Status = HealthDegraded
+			}
+			health.Replicas = append(health.Replicas, pool)
+		}
+	}
+
+	return health
+}
+
+func poolHealth(name string, stats sql.DBStats, err error) PoolHealth {
+	pool := PoolHealth{
+		Name:              name,
+		Healthy:           err == nil,
+		OpenConnections:   stats.OpenConnections,
+		InUse:             stats.InUse,
+		Idle:              stats.Idle,
+		MaxOpen:           stats.MaxOpenConnections,
+		WaitCount:         stats.WaitCount,
+		WaitDurationMs:    float64(stats.WaitDuration.Microseconds()) / 1000,
+		MaxIdleClosed:     stats.MaxIdleClosed,
+		MaxLifetimeClosed: stats.MaxLifetimeClosed,
+	}
+	if err != nil {
+		pool.Error = err.Error()
+	}
+	return pool
+}
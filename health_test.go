@@ -0,0 +1,24 @@
+package belajargolanggorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheck(t *testing.T) {
+	health := HealthCheck(context.Background(), db)
+	assert.Equal(t, HealthUp, health.Status)
+	assert.True(t, health.Primary.Healthy)
+	assert.Equal(t, 100, health.Primary.MaxOpen)
+	assert.Greater(t, health.Primary.PingLatencyMs, float64(0))
+	assert.Empty(t, health.Replicas)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	health = HealthCheck(ctx, db)
+	assert.Equal(t, HealthDown, health.Status)
+	assert.False(t, health.Primary.Healthy)
+	assert.NotEmpty(t, health.Primary.Error)
+}
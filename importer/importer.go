@@ -0,0 +1,387 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"belajar-golang-gorm/export"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	FormatCSV    = export.FormatCSV
+	FormatNDJSON = export.FormatNDJSON
+
+	DefaultBatchSize = 500
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format")
+	ErrUnknownColumn = errors.New("unknown column")
+
+	errDryRun = errors.New("dry run")
+)
+
+type Options struct {
+	Format    string // FormatCSV atau FormatNDJSON, kosong berarti CSV
+	BatchSize int    // 0 berarti DefaultBatchSize
+
+	// ConflictColumns mengaktifkan upsert, baris yang bentrok di kolom ini di-update.
+	// UpdateColumns kosong berarti semua kolom di-update (clause.OnConflict{UpdateAll: true})
+	ConflictColumns []string
+	UpdateColumns   []string
+
+	// DryRun menjalankan semua insert di dalam transaction lalu di-rollback,
+	// jadi error dari database (duplicate, hook) tetap terlaporkan
+	DryRun bool
+
+	// Validate dipanggil untuk setiap baris setelah di-decode, row adalah pointer ke model
+	Validate func(row interface{}) error
+
+	// Report menerima baris yang ditolak dalam format CSV: line,reason,record
+	Report io.Writer
+}
+
+// AfterUpsertInterface diimplementasikan model yang perlu menyesuaikan data lain setelah upsert.
+// Dipanggil sekali per batch dengan pointer ke slice model (atau pointer ke satu model saat
+// baris dicoba satu per satu), karena hook AfterCreate tidak tahu baris mana yang sudah ada
+type AfterUpsertInterface interface {
+	AfterUpsert(tx *gorm.DB, values interface{}) error
+}
+
+// Rejection adalah satu baris yang tidak di-import, Line dihitung dari 1 termasuk header
+type Rejection struct {
+	Line   int
+	Reason string
+	Record string
+}
+
+type Result struct {
+	Read       int
+	Imported   int
+	Rejections []Rejection
+}
+
+type row struct {
+	line   int
+	record string
+	value  reflect.Value
+}
+
+type importer struct {
+	db      *gorm.DB
+	schema  *schema.Schema
+	options Options
+	result  Result
+	report  *csv.Writer
+}
+
+// Import membaca CSV atau NDJSON ke model lalu menyimpannya per batch dengan Create.
+// Baris yang tidak valid dicatat di Result.Rejections (dan Options.Report), kalau satu batch
+// gagal di database setiap baris di batch tersebut dicoba satu per satu supaya baris yang
+// bermasalah bisa diketahui
+func Import(db *gorm.DB, model interface{}, r io.Reader, options Options) (Result, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return Result{}, err
+	}
+	if options.BatchSize < 1 {
+		options.BatchSize = DefaultBatchSize
+	}
+	for _, column := range append(append([]string{}, options.ConflictColumns...), options.UpdateColumns...) {
+		if field := stmt.Schema.LookUpField(column); field == nil || field.DBName == "" {
+			return Result{}, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+	}
+
+	i := &importer{db: db, schema: stmt.Schema, options: options}
+	if options.Report != nil {
+		i.report = csv.NewWriter(options.Report)
+		if err := i.report.Write([]string{"line", "reason", "record"}); err != nil {
+			return Result{}, err
+		}
+	}
+
+	run := func(tx *gorm.DB) error {
+		switch options.Format {
+		case FormatCSV, "":
+			return i.readCSV(tx, r)
+		case FormatNDJSON:
+			return i.readNDJSON(tx, r)
+		default:
+			return fmt.Errorf("%w: %s", ErrUnknownFormat, options.Format)
+		}
+	}
+
+	var err error
+	if options.DryRun {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := run(tx); err != nil {
+				return err
+			}
+			return errDryRun
+		})
+		if errors.Is(err, errDryRun) {
+			err = nil
+		}
+	} else {
+		err = run(db)
+	}
+
+	if i.report != nil {
+		i.report.Flush()
+		if reportErr := i.report.Error(); err == nil {
+			err = reportErr
+		}
+	}
+	return i.result, err
+}
+
+func (i *importer) fields(header []string) ([]*schema.Field, error) {
+	fields := make([]*schema.Field, len(header))
+	for index, column := range header {
+		field := i.schema.LookUpField(strings.TrimSpace(column))
+		if field == nil || field.DBName == "" || !field.Creatable {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+		fields[index] = field
+	}
+	return fields, nil
+}
+
+func (i *importer) readCSV(tx *gorm.DB, r io.Reader) error {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fields, err := i.fields(header)
+	if err != nil {
+		return err
+	}
+	reader.FieldsPerRecord = len(header)
+
+	var batch []row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		// hanya ParseError yang berarti baris rusak, error lain dari reader dikembalikan.
+		// FieldPos hanya valid untuk baris yang berhasil dibaca
+		var line int
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			line = parseErr.StartLine
+		} else if err != nil {
+			return err
+		} else {
+			line, _ = reader.FieldPos(0)
+		}
+		i.result.Read++
+		current := row{line: line, record: csvRecord(record)}
+		if err != nil {
+			i.reject(current, err)
+			continue
+		}
+
+		values := make(map[*schema.Field]interface{}, len(fields))
+		for index, field := range fields {
+			// sel kosong dibiarkan zero value supaya default dan autoCreateTime tetap jalan
+			if record[index] != "" {
+				values[field] = record[index]
+			}
+		}
+		current.value, err = i.decode(tx, values)
+		if err != nil {
+			i.reject(current, err)
+			continue
+		}
+		if err := i.validate(current); err != nil {
+			i.reject(current, err)
+			continue
+		}
+
+		batch = append(batch, current)
+		if len(batch) >= i.options.BatchSize {
+			if err := i.flush(tx, batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+	}
+	return i.flush(tx, batch)
+}
+
+func (i *importer) readNDJSON(tx *gorm.DB, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	var batch []row
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		i.result.Read++
+		current := row{line: line, record: string(text)}
+		var object map[string]interface{}
+		if err := json.Unmarshal(text, &object); err != nil {
+			i.reject(current, errors.New("invalid json"))
+			continue
+		}
+
+		// kolom yang tidak dikenal adalah kesalahan format file, bukan kesalahan satu baris
+		values := make(map[*schema.Field]interface{}, len(object))
+		for column, value := range object {
+			fields, err := i.fields([]string{column})
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			if value == nil {
+				continue
+			}
+			// angka json selalu float64, bilangan bulat diubah dulu supaya bisa masuk ke kolom integer
+			if number, ok := value.(float64); ok && number == float64(int64(number)) {
+				value = int64(number)
+			}
+			values[fields[0]] = value
+		}
+
+		var err error
+		current.value, err = i.decode(tx, values)
+		if err != nil {
+			i.reject(current, err)
+			continue
+		}
+		if err := i.validate(current); err != nil {
+			i.reject(current, err)
+			continue
+		}
+
+		batch = append(batch, current)
+		if len(batch) >= i.options.BatchSize {
+			if err := i.flush(tx, batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return i.flush(tx, batch)
+}
+
+func (i *importer) decode(tx *gorm.DB, values map[*schema.Field]interface{}) (reflect.Value, error) {
+	value := reflect.New(i.schema.ModelType)
+	for field, fieldValue := range values {
+		if err := field.Set(tx.Statement.Context, value.Elem(), fieldValue); err != nil {
+			return value, fmt.Errorf("invalid value for %s: %v", field.DBName, fieldValue)
+		}
+	}
+	return value, nil
+}
+
+func (i *importer) validate(current row) error {
+	if i.options.Validate == nil {
+		return nil
+	}
+	return i.options.Validate(current.value.Interface())
+}
+
+func (i *importer) create(tx *gorm.DB, value interface{}) error {
+	if len(i.options.ConflictColumns) == 0 {
+		return tx.Create(value).Error
+	}
+
+	onConflict := clause.OnConflict{UpdateAll: len(i.options.UpdateColumns) == 0}
+	for _, column := range i.options.ConflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: i.schema.LookUpField(column).DBName})
+	}
+	if len(i.options.UpdateColumns) > 0 {
+		columns := make([]string, len(i.options.UpdateColumns))
+		for index, column := range i.options.UpdateColumns {
+			columns[index] = i.schema.LookUpField(column).DBName
+		}
+		onConflict.DoUpdates = clause.AssignmentColumns(columns)
+	}
+	err := tx.Clauses(onConflict).Create(value).Error
+	if err != nil {
+		return err
+	}
+
+	if upserter, ok := reflect.New(i.schema.ModelType).Interface().(AfterUpsertInterface); ok {
+		return upserter.AfterUpsert(tx, value)
+	}
+	return nil
+}
+
+// flush menyimpan satu batch, kalau gagal setiap baris dicoba sendiri-sendiri
+// di dalam transaction (atau savepoint kalau sedang dry run) supaya baris yang valid tetap tersimpan
+func (i *importer) flush(tx *gorm.DB, batch []row) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	values := reflect.MakeSlice(reflect.SliceOf(i.schema.ModelType), len(batch), len(batch))
+	for index, current := range batch {
+		values.Index(index).Set(current.value.Elem())
+	}
+	slice := reflect.New(values.Type())
+	slice.Elem().Set(values)
+
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		return i.create(tx, slice.Interface())
+	})
+	if err == nil {
+		i.result.Imported += len(batch)
+		return nil
+	}
+
+	for _, current := range batch {
+		err := tx.Transaction(func(tx *gorm.DB) error {
+			return i.create(tx, current.value.Interface())
+		})
+		if err != nil {
+			i.reject(current, err)
+			continue
+		}
+		i.result.Imported++
+	}
+	return nil
+}
+
+func (i *importer) reject(current row, err error) {
+	rejection := Rejection{Line: current.line, Reason: err.Error(), Record: current.record}
+	i.result.Rejections = append(i.result.Rejections, rejection)
+	if i.report != nil {
+		_ = i.report.Write([]string{strconv.Itoa(rejection.Line), rejection.Reason, rejection.Record})
+	}
+}
+
+func csvRecord(record []string) string {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	_ = writer.Write(record)
+	writer.Flush()
+	return strings.TrimRight(buffer.String(), "\r\n")
+}
@@ -0,0 +1,224 @@
+package importer_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/importer"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type User = belajargolanggorm.User
+type Wallet = belajargolanggorm.Wallet
+type Product = belajargolanggorm.Product
+type ProductPrice = belajargolanggorm.ProductPrice
+
+const usersCSV = `id,first_name,last_name,email,password
+1,Eko,Khannedy,eko@example.com,rahasia
+2,Budi,,budi@example.com,rahasia
+abc,Salah,,,rahasia
+3,Joko,,bukan-email,rahasia
+4,"Agus ""Si Pintar""",,,rahasia
+5,Kurang
+`
+
+func count(t *testing.T, db *gorm.DB) int64 {
+	var total int64
+	assert.Nil(t, db.Model(&User{}).Count(&total).Error)
+	return total
+}
+
+func TestImportCSV(t *testing.T) {
+	db := testdb.Open(t)
+
+	var report bytes.Buffer
+	result, err := importer.Import(db, &User{}, strings.NewReader(usersCSV), importer.Options{BatchSize: 2, Report: &report})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, result.Read)
+	assert.Equal(t, 3, result.Imported)
+	assert.Equal(t, int64(3), count(t, db))
+
+	lines := []int{}
+	for _, rejection := range result.Rejections {
+		lines = append(lines, rejection.Line)
+	}
+	assert.ElementsMatch(t, []int{4, 5, 7}, lines)
+
+	var user User
+	assert.Nil(t, db.Take(&user, 4).Error)
+	assert.Equal(t, `Agus "Si Pintar"`, user.Name.FirstName)
+	assert.Nil(t, user.Email)
+	assert.False(t, user.CreatedAt.IsZero())
+
+	// baris yang gagal di hook BeforeCreate dilaporkan dengan alasan dari hook
+	assert.Contains(t, report.String(), "line,reason,record\n")
+	assert.Contains(t, report.String(), "5,invalid email address,\"3,Joko,,bukan-email,rahasia\"\n")
+	assert.Contains(t, report.String(), "4,invalid value for id: abc,")
+	assert.Contains(t, report.String(), "7,")
+}
+
+func TestUpsert(t *testing.T) {
+	db := testdb.Open(t)
+	assert.Nil(t, db.Create(&User{ID: 1, Password: "lama", Name: belajargolanggorm.Name{FirstName: "Lama", LastName: "Tetap"}}).Error)
+
+	input := "id,first_name,password\n1,Baru,baru\n2,Dua,rahasia\n"
+	result, err := importer.Import(db, &User{}, strings.NewReader(input), importer.Options{
+		ConflictColumns: []string{"id"},
+		UpdateColumns:   []string{"first_name"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Empty(t, result.Rejections)
+
+	var user User
+	assert.Nil(t, db.Take(&user, 1).Error)
+	assert.Equal(t, "Baru", user.Name.FirstName)
+	assert.Equal(t, "lama", user.Password)
+	assert.Equal(t, int64(2), count(t, db))
+
+	// tanpa upsert baris yang sudah ada ditolak sebagai duplicate
+	result, err = importer.Import(db, &User{}, strings.NewReader(input), importer.Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 2, len(result.Rejections))
+	assert.Contains(t, result.Rejections[0].Reason, "UNIQUE")
+}
+
+func TestUpsertProductPrice(t *testing.T) {
+	db := testdb.Open(t)
+	assert.Nil(t, db.Create(&Product{ID: 1, Name: "Kopi", Price: 10000}).Error)
+
+	upsert := func(input string, updateColumns ...string) {
+		result, err := importer.Import(db, &Product{}, strings.NewReader(input), importer.Options{
+			ConflictColumns: []string{"id"},
+			UpdateColumns:   updateColumns,
+		})
+		assert.Nil(t, err)
+		assert.Empty(t, result.Rejections)
+	}
+	openPrices := func() []ProductPrice {
+		var prices []ProductPrice
+		assert.Nil(t, db.Where("product_id = ? AND effective_to IS NULL", 1).Find(&prices).Error)
+		return prices
+	}
+
+	// harga sama tidak menambah riwayat
+	upsert("id,name,price\n1,Kopi Gayo,10000\n")
+	prices := openPrices()
+	assert.Equal(t, 1, len(prices))
+	assert.Equal(t, int64(10000), prices[0].Price)
+
+	upsert("id,name,price\n1,Kopi Gayo,12000\n")
+	prices = openPrices()
+	assert.Equal(t, 1, len(prices))
+	assert.Equal(t, int64(12000), prices[0].Price)
+
+	// price tidak ikut diupdate, riwayat mengikuti harga di database
+	upsert("id,name,price\n1,Kopi Aceh,15000\n", "name")
+	prices = openPrices()
+	assert.Equal(t, 1, len(prices))
+	assert.Equal(t, int64(12000), prices[0].Price)
+
+	var product Product
+	assert.Nil(t, db.Take(&product, 1).Error)
+	assert.Equal(t, "Kopi Aceh", product.Name)
+	assert.Equal(t, int64(12000), product.Price)
+
+	// riwayat satu batch disesuaikan dengan jumlah query yang tetap, bukan per baris
+	queries := 0
+	err := db.Callback().Query().Before("gorm:query").Register("test:count", func(db *gorm.DB) {
+		queries++
+	})
+	assert.Nil(t, err)
+
+	input := "id,name,price\n"
+	for id := 1; id <= 50; id++ {
+		input += fmt.Sprintf("%d,Product %d,12000\n", id, id)
+	}
+	upsert(input)
+	assert.Equal(t, 3, queries)
+
+	var open []ProductPrice
+	assert.Nil(t, db.Where("effective_to IS NULL").Find(&open).Error)
+	assert.Equal(t, 50, len(open))
+}
+
+func TestDryRun(t *testing.T) {
+	db := testdb.Open(t)
+
+	result, err := importer.Import(db, &User{}, strings.NewReader(usersCSV), importer.Options{DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Imported)
+	assert.Equal(t, 3, len(result.Rejections))
+	assert.Equal(t, int64(0), count(t, db))
+}
+
+func TestImportNDJSON(t *testing.T) {
+	db := testdb.Open(t)
+
+	input := `{"id":"W1","user_id":1,"balance":1000.5}
+
+{"id":"W2","user_id":2,"balance":-5}
+{"id":"W3","user_id":"tiga"}
+bukan json
+`
+	result, err := importer.Import(db, &Wallet{}, strings.NewReader(input), importer.Options{
+		Format: importer.FormatNDJSON,
+		Validate: func(row interface{}) error {
+			if row.(*Wallet).Balance < 0 {
+				return errors.New("balance must not be negative")
+			}
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, result.Read)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, []importer.Rejection{
+		{Line: 3, Reason: "balance must not be negative", Record: `{"id":"W2","user_id":2,"balance":-5}`},
+		{Line: 4, Reason: "invalid value for user_id: tiga", Record: `{"id":"W3","user_id":"tiga"}`},
+		{Line: 5, Reason: "invalid json", Record: "bukan json"},
+	}, result.Rejections)
+
+	var wallet Wallet
+	assert.Nil(t, db.Take(&wallet, "id = ?", "W1").Error)
+	assert.Equal(t, float64(1000.5), wallet.Balance)
+}
+
+func TestImportErrors(t *testing.T) {
+	db := testdb.Open(t)
+
+	_, err := importer.Import(db, &User{}, strings.NewReader("id,information\n1,x\n"), importer.Options{})
+	assert.ErrorIs(t, err, importer.ErrUnknownColumn)
+
+	_, err = importer.Import(db, &User{}, strings.NewReader(`{"id":1,"umur":20}`), importer.Options{Format: importer.FormatNDJSON})
+	assert.ErrorIs(t, err, importer.ErrUnknownColumn)
+
+	_, err = importer.Import(db, &User{}, strings.NewReader(""), importer.Options{Format: "xml"})
+	assert.ErrorIs(t, err, importer.ErrUnknownFormat)
+
+	_, err = importer.Import(db, &User{}, strings.NewReader(""), importer.Options{ConflictColumns: []string{"nik"}})
+	assert.ErrorIs(t, err, importer.ErrUnknownColumn)
+
+	// quote di tengah field gagal sebelum ada field yang terbaca, tetap dilaporkan sebagai baris rusak
+	result, err := importer.Import(db, &User{}, strings.NewReader("id,first_name,password\nx\"y,Eko,rahasia\n2,Budi,rahasia\n"), importer.Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, len(result.Rejections))
+	assert.Equal(t, 2, result.Rejections[0].Line)
+
+	// error dari reader bukan baris yang rusak, import dihentikan
+	broken := errors.New("koneksi terputus")
+	input := io.MultiReader(strings.NewReader("id,first_name,password\n1,Eko,rahasia\n"), iotest.ErrReader(broken))
+	_, err = importer.Import(db, &User{}, input, importer.Options{})
+	assert.ErrorIs(t, err, broken)
+}
@@ -0,0 +1,5 @@
+// Package sensitive berisi kolom yang isinya tidak boleh keluar lewat export
+// maupun ditebak lewat filter dan sort di API
+package sensitive
+
+var Columns = []string{"password", "token"}
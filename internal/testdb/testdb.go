@@ -0,0 +1,57 @@
+package testdb
+
+import (
+	"path/filepath"
+	"testing"
+
+	belajargolanggorm "belajar-golang-gorm"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var Models = []interface{}{
+	&belajargolanggorm.User{},
+	&belajargolanggorm.Wallet{},
+	&belajargolanggorm.Address{},
+	&belajargolanggorm.Product{},
+	&belajargolanggorm.ProductPrice{},
+	&belajargolanggorm.UserLikeProduct{},
+	&belajargolanggorm.Order{},
+	&belajargolanggorm.OrderItem{},
+	&belajargolanggorm.Todo{},
+	&belajargolanggorm.UserLog{},
+	&belajargolanggorm.GuestBook{},
+	&belajargolanggorm.GuestBookModeration{},
+	&belajargolanggorm.EmailVerification{},
+}
+
+// Open membuat database sqlite baru di folder temporary test,
+// semua tabel model sudah dimigrasi sehingga test tidak butuh MySQL
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:                 logger.Default.LogMode(logger.Silent),
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(Models...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return db
+}
@@ -0,0 +1,197 @@
+// Package txhook menjalankan fungsi saat transaksi GORM dibuka dan setelah di-commit,
+// dipakai cache supaya invalidasi tidak terjadi sebelum data terlihat oleh koneksi lain
+// dan dipakai tracing untuk membuat span transaksi
+package txhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ErrRolledBack diberikan ke fungsi end dari OnBegin kalau transaksi di-rollback
+var ErrRolledBack = errors.New("transaction rolled back")
+
+// BeginFunc dipanggil sebelum transaksi dibuka, context yang dikembalikan bisa dibaca
+// lewat Context dan end dipanggil sekali saat transaksi selesai dengan error Commit,
+// ErrRolledBack, atau error saat membuka transaksi
+type BeginFunc func(ctx context.Context) (context.Context, func(err error))
+
+// Register membungkus ConnPool db supaya transaksi yang dibuka setelahnya
+// bisa menyimpan fungsi AfterCommit, aman dipanggil lebih dari sekali
+func Register(db *gorm.DB) {
+	if _, ok := db.Config.ConnPool.(*pool); ok {
+		return
+	}
+
+	original := db.Config.ConnPool
+	wrapped := &pool{ConnPool: original}
+	db.Config.ConnPool = wrapped
+	if db.Statement.ConnPool == original {
+		db.Statement.ConnPool = wrapped
+	}
+}
+
+// OnBegin mendaftarkan fn untuk setiap transaksi yang dibuka lewat db, Register harus
+// sudah dipanggil. Dipanggil saat setup, bukan ketika query sedang berjalan
+func OnBegin(db *gorm.DB, fn BeginFunc) {
+	if p, ok := db.Config.ConnPool.(*pool); ok {
+		p.begins = append(p.begins, fn)
+	}
+}
+
+// AfterCommit menjalankan fn setelah transaksi db di-commit dan membuangnya kalau
+// transaksi di-rollback. Di luar transaksi fn langsung dijalankan
+func AfterCommit(db *gorm.DB, fn func()) {
+	if tx, ok := current(db); ok {
+		tx.mu.Lock()
+		tx.hooks = append(tx.hooks, fn)
+		tx.mu.Unlock()
+		return
+	}
+	fn()
+}
+
+// Context mengembalikan context yang diberikan saat transaksi db dibuka (parent)
+// dan context hasil OnBegin (ctx), ok false kalau db tidak di dalam transaksi
+func Context(db *gorm.DB) (parent context.Context, ctx context.Context, ok bool) {
+	tx, ok := current(db)
+	if !ok {
+		return nil, nil, false
+	}
+	return tx.parent, tx.ctx, true
+}
+
+func current(db *gorm.DB) (*Tx, bool) {
+	conn := db.Statement.ConnPool
+	// Session(&gorm.Session{PrepareStmt: true}) membungkus Tx dengan PreparedStmtTX
+	if prepared, ok := conn.(*gorm.PreparedStmtTX); ok {
+		conn = prepared.Tx
+	}
+	tx, ok := conn.(*Tx)
+	return tx, ok
+}
+
+type pool struct {
+	gorm.ConnPool
+	begins []BeginFunc
+}
+
+func (p *pool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	parent := ctx
+	ends := make([]func(error), 0, len(p.begins))
+	for _, begin := range p.begins {
+		var end func(error)
+		ctx, end = begin(ctx)
+		ends = append(ends, end)
+	}
+
+	var (
+		conn gorm.ConnPool
+		err  error
+	)
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		conn, err = beginner.BeginTx(ctx, opts)
+	case gorm.ConnPoolBeginner:
+		conn, err = beginner.BeginTx(ctx, opts)
+	default:
+		err = gorm.ErrInvalidTransaction
+	}
+	if err != nil {
+		for _, end := range ends {
+			end(err)
+		}
+		return nil, err
+	}
+
+	tx, ok := conn.(gorm.Tx)
+	if !ok {
+		for _, end := range ends {
+			end(nil)
+		}
+		return conn, nil
+	}
+	return &Tx{Tx: tx, pool: p, parent: parent, ctx: ctx, ends: ends}, nil
+}
+
+func (p *pool) GetDBConn() (*sql.DB, error) {
+	if connector, ok := p.ConnPool.(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+	if sqlDB, ok := p.ConnPool.(*sql.DB); ok {
+		return sqlDB, nil
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// Tx adalah transaksi yang menyimpan fungsi AfterCommit
+type Tx struct {
+	gorm.Tx
+	pool        *pool
+	parent, ctx context.Context
+
+	mu    sync.Mutex
+	hooks []func()
+	ends  []func(error)
+}
+
+func (tx *Tx) Commit() error {
+	err := tx.Tx.Commit()
+	tx.end(err)
+	if err != nil {
+		return err
+	}
+
+	tx.mu.Lock()
+	hooks := tx.hooks
+	tx.hooks = nil
+	tx.mu.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+	return nil
+}
+
+func (tx *Tx) Rollback() error {
+	tx.mu.Lock()
+	tx.hooks = nil
+	tx.mu.Unlock()
+
+	err := tx.Tx.Rollback()
+	tx.end(errors.Join(ErrRolledBack, err))
+	return err
+}
+
+// end dipanggil sekali, GORM juga memanggil Rollback setelah Commit yang gagal
+func (tx *Tx) end(err error) {
+	tx.mu.Lock()
+	ends := tx.ends
+	tx.ends = nil
+	tx.mu.Unlock()
+	for _, end := range ends {
+		end(err)
+	}
+}
+
+// ExecContext menjalankan SAVEPOINT tanpa prepared statement seperti yang dilakukan GORM,
+// pengecekan *gorm.PreparedStmtTX di GORM tidak berlaku karena transaksi sudah dibungkus
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if prepared, ok := tx.Tx.(*gorm.PreparedStmtTX); ok && isSavePoint(query) {
+		return prepared.Tx.ExecContext(ctx, query, args...)
+	}
+	return tx.Tx.ExecContext(ctx, query, args...)
+}
+
+func (tx *Tx) GetDBConn() (*sql.DB, error) {
+	return tx.pool.GetDBConn()
+}
+
+func isSavePoint(query string) bool {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	return strings.HasPrefix(query, "SAVEPOINT ") || strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT ")
+}
@@ -0,0 +1,69 @@
+package txhook_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/internal/txhook"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type User = belajargolanggorm.User
+
+func open(t *testing.T, prepareStmt bool) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger:      logger.Default.LogMode(logger.Silent),
+		PrepareStmt: prepareStmt,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&User{}))
+	txhook.Register(db)
+	return db
+}
+
+func TestAfterCommit(t *testing.T) {
+	for _, prepareStmt := range []bool{false, true} {
+		db := open(t, prepareStmt)
+
+		var calls []string
+		txhook.AfterCommit(db, func() { calls = append(calls, "langsung") })
+		assert.Equal(t, []string{"langsung"}, calls)
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			txhook.AfterCommit(tx, func() { calls = append(calls, "commit") })
+
+			// savepoint tetap berjalan walaupun transaksi dibungkus
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				txhook.AfterCommit(tx, func() { calls = append(calls, "nested") })
+				return tx.Create(&User{ID: 1, Password: "rahasia"}).Error
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"langsung"}, calls)
+
+			return tx.Session(&gorm.Session{PrepareStmt: true}).Create(&User{ID: 2, Password: "rahasia"}).Error
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"langsung", "commit", "nested"}, calls)
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			txhook.AfterCommit(tx, func() { calls = append(calls, "rollback") })
+			return errors.New("batal")
+		})
+		assert.NotNil(t, err)
+		assert.Equal(t, 3, len(calls))
+
+		var count int64
+		assert.Nil(t, db.Model(&User{}).Count(&count).Error)
+		assert.Equal(t, int64(2), count)
+
+		sqlDB, err := db.DB()
+		assert.Nil(t, err)
+		assert.Nil(t, sqlDB.Close())
+	}
+}
@@ -0,0 +1,94 @@
+package belajargolanggorm
+
+import (
+	"time"
+
+	"belajar-golang-gorm/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const LikesPageSize = 10
+
+// tabel pivot user_like_product
+type UserLikeProduct struct {
+	UserId    int       `gorm:"column:user_id;primaryKey"`
+	ProductId int       `gorm:"column:product_id;primaryKey"`
+	LikedAt   time.Time `gorm:"column:liked_at;autoCreateTime"`
+}
+
+func (u *UserLikeProduct) TableName() string {
+	return "user_like_product"
+}
+
+// Likes menjaga tabel pivot dan products.like_count tetap sinkron,
+// jadi like/unlike sebaiknya lewat service ini, bukan lewat Association
+type Likes struct {
+	db *gorm.DB
+}
+
+func NewLikes(db *gorm.DB) *Likes {
+	return &Likes{db: db}
+}
+
+func (l *Likes) Like(userId int, productId int) error {
+	return l.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&UserLikeProduct{
+			UserId:    userId,
+			ProductId: productId,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// sudah pernah like, counter tidak perlu ditambah
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return tx.Model(&Product{}).Where("id = ?", productId).
+			UpdateColumn("like_count", gorm.Expr("like_count + ?", 1)).Error
+	})
+}
+
+func (l *Likes) Unlike(userId int, productId int) error {
+	return l.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&UserLikeProduct{}, "user_id = ? AND product_id = ?", userId, productId)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return tx.Model(&Product{}).Where("id = ? AND like_count > ?", productId, 0).
+			UpdateColumn("like_count", gorm.Expr("like_count - ?", 1)).Error
+	})
+}
+
+func (l *Likes) IsLiked(userId int, productId int) (bool, error) {
+	var count int64
+	err := l.db.Model(&UserLikeProduct{}).Where("user_id = ? AND product_id = ?", userId, productId).Count(&count).Error
+	return count > 0, err
+}
+
+// LikedProducts mengembalikan product yang di-like user, terbaru lebih dulu, page mulai dari 1
+func (l *Likes) LikedProducts(userId int, page int) ([]Product, error) {
+	var products []Product
+	err := l.db.Joins("JOIN user_like_product ulp ON ulp.product_id = products.id").
+		Where("ulp.user_id = ?", userId).
+		Order("ulp.liked_at desc, products.id desc").
+		Scopes(pagination.Paginate(page, LikesPageSize)).
+		Find(&products).Error
+
+	return products, err
+}
+
+// Recount menghitung ulang like_count dari tabel pivot,
+// dipakai kalau pivot sempat diubah tanpa lewat service
+func (l *Likes) Recount() error {
+	count := l.db.Model(&UserLikeProduct{}).Select("count(*)").Where("user_like_product.product_id = products.id")
+	return l.db.Model(&Product{}).Where("1 = 1").UpdateColumn("like_count", count).Error
+}
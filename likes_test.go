@@ -0,0 +1,80 @@
+package belajargolanggorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLikes(t *testing.T) {
+	user := User{
+		ID:       28,
+		Password: "Rahasia",
+		Name: Name{
+			FirstName: "Salman 28",
+		},
+	}
+	err := db.Create(&user).Error
+	assert.Nil(t, err)
+
+	products := []Product{
+		{ID: 30, Name: "Product 30", Price: 10000},
+		{ID: 31, Name: "Product 31", Price: 20000},
+	}
+	err = db.Create(&products).Error
+	assert.Nil(t, err)
+
+	likes := NewLikes(db)
+	err = likes.Like(28, 30)
+	assert.Nil(t, err)
+
+	err = likes.Like(28, 31)
+	assert.Nil(t, err)
+
+	// like dua kali tidak menambah counter
+	err = likes.Like(28, 30)
+	assert.Nil(t, err)
+
+	liked, err := likes.IsLiked(28, 30)
+	assert.Nil(t, err)
+	assert.True(t, liked)
+
+	var product Product
+	err = db.Take(&product, "id = ?", 30).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), product.LikeCount)
+
+	likedProducts, err := likes.LikedProducts(28, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(likedProducts))
+
+	likedProducts, err = likes.LikedProducts(28, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(likedProducts))
+}
+
+func TestUnlike(t *testing.T) {
+	likes := NewLikes(db)
+	err := likes.Unlike(28, 30)
+	assert.Nil(t, err)
+
+	err = likes.Unlike(28, 30)
+	assert.Nil(t, err)
+
+	liked, err := likes.IsLiked(28, 30)
+	assert.Nil(t, err)
+	assert.False(t, liked)
+
+	var product Product
+	err = db.Take(&product, "id = ?", 30).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), product.LikeCount)
+
+	err = likes.Recount()
+	assert.Nil(t, err)
+
+	product = Product{}
+	err = db.Take(&product, "id = ?", 31).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), product.LikeCount)
+}
@@ -0,0 +1,207 @@
+package belajargolanggorm
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrUnknownMigration = errors.New("applied migration is not in the migration list")
+
+type Migration struct {
+	ID     string
+	Models []interface{}
+}
+
+// Migrations diurutkan dari yang paling lama, up memakai AutoMigrate
+// dan down menghapus tabel milik migration tersebut
+var Migrations = []Migration{
+	{ID: "0001_core", Models: []interface{}{&User{}, &Wallet{}, &Address{}, &Product{}, &UserLikeProduct{}, &Todo{}, &UserLog{}, &GuestBook{}}},
+	{ID: "0002_product_prices", Models: []interface{}{&ProductPrice{}}},
+	{ID: "0003_orders", Models: []interface{}{&Order{}, &OrderItem{}}},
+	{ID: "0004_guest_book_moderations", Models: []interface{}{&GuestBookModeration{}}},
+	{ID: "0005_email_verifications", Models: []interface{}{&EmailVerification{}}},
+}
+
+type SchemaMigration struct {
+	ID        string    `gorm:"column:id;primaryKey;size:100"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+type MigrationState struct {
+	ID        string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+func appliedMigrations(db *gorm.DB) (map[string]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := map[string]SchemaMigration{}
+	for _, row := range rows {
+		applied[row.ID] = row
+	}
+	return applied, nil
+}
+
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []MigrationState
+	for _, migration := range Migrations {
+		state := MigrationState{ID: migration.ID}
+		if row, ok := applied[migration.ID]; ok {
+			state.Applied = true
+			state.AppliedAt = &row.AppliedAt
+			delete(applied, migration.ID)
+		}
+		states = append(states, state)
+	}
+	if len(applied) > 0 {
+		return states, ErrUnknownMigration
+	}
+	return states, nil
+}
+
+// MigrateUp menjalankan migration yang belum pernah dijalankan, steps <= 0 berarti semua
+func MigrateUp(db *gorm.DB, steps int) ([]string, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []string
+	for _, migration := range Migrations {
+		if steps > 0 && len(done) == steps {
+			break
+		}
+		if _, ok := applied[migration.ID]; ok {
+			continue
+		}
+
+		if err := db.AutoMigrate(migration.Models...); err != nil {
+			return done, err
+		}
+		err := db.Create(&SchemaMigration{ID: migration.ID, AppliedAt: time.Now()}).Error
+		if err != nil {
+			return done, err
+		}
+		done = append(done, migration.ID)
+	}
+	return done, nil
+}
+
+// MigrateDown membatalkan migration terakhir, steps <= 0 berarti satu migration
+func MigrateDown(db *gorm.DB, steps int) ([]string, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []string
+	for i := len(Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := Migrations[i]
+		if _, ok := applied[migration.ID]; !ok {
+			continue
+		}
+
+		// tabel dihapus dari belakang supaya foreign key tidak menghalangi
+		for j := len(migration.Models) - 1; j >= 0; j-- {
+			if err := dropModel(db, migration.Models[j]); err != nil {
+				return done, err
+			}
+		}
+		err := db.Delete(&SchemaMigration{}, "id = ?", migration.ID).Error
+		if err != nil {
+			return done, err
+		}
+		done = append(done, migration.ID)
+	}
+	return done, nil
+}
+
+func dropModel(db *gorm.DB, model interface{}) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+
+	// join table many2many ikut dibuat oleh AutoMigrate, jadi ikut dihapus
+	for _, relationship := range stmt.Schema.Relationships.Many2Many {
+		if err := db.Migrator().DropTable(relationship.JoinTable.Table); err != nil {
+			return err
+		}
+	}
+	return db.Migrator().DropTable(model)
+}
+
+type SchemaIssue struct {
+	Table   string
+	Column  string
+	Problem string
+}
+
+// SchemaCheck membandingkan kolom model dengan tabel di database
+func SchemaCheck(db *gorm.DB) ([]SchemaIssue, error) {
+	var issues []SchemaIssue
+	migrator := db.Migrator()
+	for _, migration := range Migrations {
+		for _, model := range migration.Models {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(model); err != nil {
+				return nil, err
+			}
+			table := stmt.Schema.Table
+
+			if !migrator.HasTable(table) {
+				issues = append(issues, SchemaIssue{Table: table, Problem: "missing table"})
+				continue
+			}
+
+			columns, err := migrator.ColumnTypes(model)
+			if err != nil {
+				return nil, err
+			}
+			existing := map[string]bool{}
+			for _, column := range columns {
+				existing[column.Name()] = true
+			}
+
+			for _, field := range stmt.Schema.Fields {
+				if field.DBName == "" || field.IgnoreMigration {
+					continue
+				}
+				if !existing[field.DBName] {
+					issues = append(issues, SchemaIssue{Table: table, Column: field.DBName, Problem: "missing column"})
+				}
+				delete(existing, field.DBName)
+			}
+			for column := range existing {
+				issues = append(issues, SchemaIssue{Table: table, Column: column, Problem: "column not in model"})
+			}
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Table != issues[j].Table {
+			return issues[i].Table < issues[j].Table
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues, nil
+}
@@ -0,0 +1,150 @@
+package nplusone
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// DefaultThreshold adalah jumlah maksimal query dengan bentuk yang sama di dalam satu Watch
+const DefaultThreshold = 1
+
+// Violation adalah satu bentuk query yang dijalankan berulang kali
+type Violation struct {
+	Table string
+	Shape string
+	Count int
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("query dijalankan %d kali: %s", v.Count, v.Shape)
+}
+
+// Error berisi semua query yang berulang beserta saran perbaikannya
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	var builder strings.Builder
+	builder.WriteString("kemungkinan N+1 query, gunakan Preload atau Joins untuk memuat relasi sekaligus:")
+	for _, violation := range e.Violations {
+		builder.WriteString("\n  - ")
+		builder.WriteString(violation.String())
+		if violation.Table != "" {
+			fmt.Fprintf(&builder, " (relasi ke tabel %s)", violation.Table)
+		}
+	}
+	return builder.String()
+}
+
+// Detector adalah plugin GORM yang menghitung SELECT per bentuk query,
+// hanya aktif selama fungsi di dalam Watch berjalan
+type Detector struct {
+	Threshold int
+
+	mu     sync.Mutex
+	active bool
+	counts map[string]int
+	tables map[string]string
+	order  []string
+}
+
+func New(threshold int) *Detector {
+	if threshold < 1 {
+		threshold = DefaultThreshold
+	}
+	return &Detector{Threshold: threshold}
+}
+
+func (d *Detector) Name() string {
+	return "nplusone"
+}
+
+func (d *Detector) Initialize(db *gorm.DB) error {
+	err := db.Callback().Query().After("gorm:query").Register("nplusone:after_query", d.record)
+	if err != nil {
+		return err
+	}
+	return db.Callback().Row().After("gorm:row").Register("nplusone:after_row", d.record)
+}
+
+func (d *Detector) record(db *gorm.DB) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.active || db.Statement.SQL.Len() == 0 {
+		return
+	}
+
+	shape := Shape(db.Statement.SQL.String())
+	if _, ok := d.counts[shape]; !ok {
+		d.order = append(d.order, shape)
+		d.tables[shape] = db.Statement.Table
+	}
+	d.counts[shape]++
+}
+
+// Watch menjalankan fn lalu mengembalikan error kalau ada bentuk query
+// yang dijalankan lebih dari Threshold kali
+func (d *Detector) Watch(fn func()) error {
+	d.mu.Lock()
+	d.active = true
+	d.counts = map[string]int{}
+	d.tables = map[string]string{}
+	d.order = nil
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		d.active = false
+		d.mu.Unlock()
+	}()
+	fn()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var violations []Violation
+	for _, shape := range d.order {
+		if d.counts[shape] > d.Threshold {
+			violations = append(violations, Violation{Table: d.tables[shape], Shape: shape, Count: d.counts[shape]})
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Count > violations[j].Count
+	})
+	return &Error{Violations: violations}
+}
+
+// AssertNone menggagalkan test kalau fn menjalankan N+1 query
+func (d *Detector) AssertNone(t testing.TB, fn func()) bool {
+	t.Helper()
+	if err := d.Watch(fn); err != nil {
+		t.Error(err)
+		return false
+	}
+	return true
+}
+
+var (
+	spacePattern  = regexp.MustCompile(`\s+`)
+	stringPattern = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberPattern = regexp.MustCompile(`\b\d+(\.\d+)?\b`)
+	listPattern   = regexp.MustCompile(`\(\s*\?(\s*,\s*\?)*\s*\)`)
+)
+
+// Shape menyamakan query yang hanya berbeda nilai parameter,
+// termasuk IN dengan jumlah parameter yang berbeda
+func Shape(sql string) string {
+	sql = stringPattern.ReplaceAllString(sql, "?")
+	sql = numberPattern.ReplaceAllString(sql, "?")
+	sql = listPattern.ReplaceAllString(sql, "(?)")
+	return strings.TrimSpace(spacePattern.ReplaceAllString(sql, " "))
+}
@@ -0,0 +1,87 @@
+package nplusone_test
+
+import (
+	"testing"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/internal/testdb"
+	"belajar-golang-gorm/nplusone"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type User = belajargolanggorm.User
+type Wallet = belajargolanggorm.Wallet
+
+func setup(t *testing.T) (*gorm.DB, *nplusone.Detector) {
+	db := testdb.Open(t)
+	for i := 1; i <= 3; i++ {
+		err := db.Create(&User{ID: i, Password: "rahasia", Wallet: Wallet{ID: string(rune('0' + i)), UserId: i}}).Error
+		assert.Nil(t, err)
+	}
+
+	detector := nplusone.New(1)
+	assert.Nil(t, db.Use(detector))
+	return db, detector
+}
+
+func TestDetectLazyLoading(t *testing.T) {
+	db, detector := setup(t)
+
+	err := detector.Watch(func() {
+		var users []User
+		assert.Nil(t, db.Find(&users).Error)
+		for i := range users {
+			assert.Nil(t, db.Where("user_id = ?", users[i].ID).Take(&users[i].Wallet).Error)
+		}
+	})
+
+	violation, ok := err.(*nplusone.Error)
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(violation.Violations))
+	assert.Equal(t, 3, violation.Violations[0].Count)
+	assert.Equal(t, "wallets", violation.Violations[0].Table)
+	assert.Contains(t, err.Error(), "Preload")
+	assert.Contains(t, err.Error(), "user_id = ?")
+}
+
+func TestPreloadPasses(t *testing.T) {
+	db, detector := setup(t)
+
+	passed := detector.AssertNone(t, func() {
+		var users []User
+		assert.Nil(t, db.Preload("Wallet").Preload("Addresses").Find(&users).Error)
+		assert.Equal(t, 3, len(users))
+		assert.Equal(t, 2, users[1].Wallet.UserId)
+	})
+	assert.True(t, passed)
+
+	// di luar Watch query tidak dihitung
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, db.Take(&User{}, i+1).Error)
+	}
+	assert.Nil(t, detector.Watch(func() {}))
+}
+
+func TestThreshold(t *testing.T) {
+	db, detector := setup(t)
+	countWallets := func() {
+		for i := 1; i <= 3; i++ {
+			var count int64
+			assert.Nil(t, db.Raw("SELECT count(*) FROM wallets WHERE user_id = "+string(rune('0'+i))).Scan(&count).Error)
+		}
+	}
+
+	detector.Threshold = 3
+	assert.Nil(t, detector.Watch(countWallets))
+
+	detector.Threshold = 2
+	assert.NotNil(t, detector.Watch(countWallets))
+}
+
+func TestShape(t *testing.T) {
+	assert.Equal(t, "SELECT * FROM users WHERE id IN (?) AND name = ?",
+		nplusone.Shape("SELECT *\n FROM users WHERE id IN (?,?, ?) AND name = 'Eko'"))
+	assert.Equal(t, nplusone.Shape("SELECT * FROM wallets WHERE user_id = 1"), nplusone.Shape("SELECT * FROM wallets WHERE user_id = 22"))
+}
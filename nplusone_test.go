@@ -0,0 +1,56 @@
+package belajargolanggorm
+
+import (
+	"testing"
+
+	"belajar-golang-gorm/nplusone"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var queryDetector = useDetector()
+
+func useDetector() *nplusone.Detector {
+	detector := nplusone.New(nplusone.DefaultThreshold)
+	err := db.Use(detector)
+	if err != nil {
+		panic(err)
+	}
+	return detector
+}
+
+// assertNoNPlusOne menggagalkan test kalau fn menjalankan query yang sama berulang kali
+func assertNoNPlusOne(t *testing.T, fn func()) {
+	t.Helper()
+	queryDetector.AssertNone(t, fn)
+}
+
+func TestNoNPlusOne(t *testing.T) {
+	users := []User{
+		{ID: 44, Password: "Rahasia", Name: Name{FirstName: "Salman 44"}, Wallet: Wallet{ID: "44", UserId: 44, Balance: 100}},
+		{ID: 441, Password: "Rahasia", Name: Name{FirstName: "Salman 441"}, Wallet: Wallet{ID: "441", UserId: 441, Balance: 200}},
+	}
+	err := db.Create(&users).Error
+	assert.Nil(t, err)
+
+	assertNoNPlusOne(t, func() {
+		var result []User
+		err := db.Preload("Wallet").Preload("Addresses").Where("id IN ?", []int{44, 441}).Order("id").Find(&result).Error
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(result))
+		assert.Equal(t, float64(200), result[1].Wallet.Balance)
+	})
+
+	// memuat wallet satu per satu di dalam loop terdeteksi sebagai N+1
+	err = queryDetector.Watch(func() {
+		var result []User
+		err := db.Where("id IN ?", []int{44, 441}).Find(&result).Error
+		assert.Nil(t, err)
+		for i := range result {
+			err := db.Where("user_id = ?", result[i].ID).Take(&result[i].Wallet).Error
+			assert.Nil(t, err)
+		}
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "wallets")
+}
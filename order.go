@@ -0,0 +1,209 @@
+package belajargolanggorm
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	OrderStatusPaid      = "paid"
+	OrderStatusCancelled = "cancelled"
+)
+
+var (
+	ErrEmptyOrder          = errors.New("order has no items")
+	ErrInvalidQuantity     = errors.New("quantity must be greater than zero")
+	ErrAddressNotFound     = errors.New("address not found")
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrOutOfStock          = errors.New("product out of stock")
+	ErrInsufficientBalance = errors.New("insufficient wallet balance")
+	ErrOrderNotCancellable = errors.New("order cannot be cancelled")
+)
+
+type Order struct {
+	ID              int         `gorm:"column:id;primary_key;autoIncrement"`
+	UserId          int         `gorm:"column:user_id"`
+	WalletId        string      `gorm:"column:wallet_id"`
+	AddressId       int         `gorm:"column:address_id"`
+	ShippingAddress string      `gorm:"column:shipping_address"`
+	Status          string      `gorm:"column:status"`
+	Total           int64       `gorm:"column:total"`
+	CreatedAt       time.Time   `gorm:"column:created_at;autoCreateTime;<-:create"`
+	UpdatedAt       time.Time   `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	User            User        `gorm:"foreignKey:user_id;references:id"`  //relasi many to one
+	Items           []OrderItem `gorm:"foreignKey:order_id;references:id"` //relasi one to many
+}
+
+// harga dan nama product disalin saat checkout supaya order lama tidak ikut berubah
+type OrderItem struct {
+	ID          int       `gorm:"column:id;primary_key;autoIncrement"`
+	OrderId     int       `gorm:"column:order_id"`
+	ProductId   int       `gorm:"column:product_id"`
+	ProductName string    `gorm:"column:product_name"`
+	Price       int64     `gorm:"column:price"`
+	Quantity    int       `gorm:"column:quantity"`
+	Subtotal    int64     `gorm:"column:subtotal"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime;<-:create"`
+	Product     Product   `gorm:"foreignKey:product_id;references:id"` //relasi many to one
+}
+
+type CheckoutItem struct {
+	ProductId int
+	Quantity  int
+}
+
+type CheckoutRequest struct {
+	UserId    int
+	AddressId int
+	Items     []CheckoutItem
+}
+
+// Checkout membuat order dalam satu transaction: harga disalin, stok dikurangi,
+// saldo wallet didebit dan alamat pengiriman dicatat. Kalau salah satu langkah
+// gagal semua perubahan di-rollback.
+func Checkout(db *gorm.DB, request CheckoutRequest) (*Order, error) {
+	items, err := mergeCheckoutItems(request.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	order := Order{
+		UserId:    request.UserId,
+		AddressId: request.AddressId,
+		Status:    OrderStatusPaid,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var address Address
+		err := tx.Where("user_id = ?", request.UserId).Limit(1).Find(&address, "id = ?", request.AddressId).Error
+		if err != nil {
+			return err
+		}
+		if address.ID == 0 {
+			return ErrAddressNotFound
+		}
+		order.ShippingAddress = address.Address
+
+		var wallet Wallet
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", request.UserId).Limit(1).Find(&wallet).Error
+		if err != nil {
+			return err
+		}
+		if wallet.ID == "" {
+			return ErrWalletNotFound
+		}
+		order.WalletId = wallet.ID
+
+		now := time.Now()
+		for _, item := range items {
+			var product Product
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&product, "id = ?", item.ProductId).Error
+			if err != nil {
+				return err
+			}
+
+			price, err := PriceAt(tx, product.ID, now)
+			if errors.Is(err, ErrPriceNotFound) {
+				price = product.Price
+			} else if err != nil {
+				return err
+			}
+
+			result := tx.Model(&Product{}).Where("id = ? AND stock >= ?", product.ID, item.Quantity).
+				UpdateColumn("stock", gorm.Expr("stock - ?", item.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrOutOfStock
+			}
+
+			subtotal := price * int64(item.Quantity)
+			order.Total += subtotal
+			order.Items = append(order.Items, OrderItem{
+				ProductId:   product.ID,
+				ProductName: product.Name,
+				Price:       price,
+				Quantity:    item.Quantity,
+				Subtotal:    subtotal,
+			})
+		}
+
+		result := tx.Model(&Wallet{}).Where("id = ? AND balance >= ?", wallet.ID, order.Total).
+			Update("balance", gorm.Expr("balance - ?", order.Total))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientBalance
+		}
+
+		return tx.Create(&order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// CancelOrder adalah kompensasi untuk order yang sudah dibayar:
+// stok dikembalikan dan saldo di-refund ke wallet
+func CancelOrder(db *gorm.DB, orderId int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var order Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").Take(&order, "id = ?", orderId).Error
+		if err != nil {
+			return err
+		}
+		if order.Status != OrderStatusPaid {
+			return ErrOrderNotCancellable
+		}
+
+		for _, item := range order.Items {
+			err := tx.Model(&Product{}).Where("id = ?", item.ProductId).
+				UpdateColumn("stock", gorm.Expr("stock + ?", item.Quantity)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Model(&Wallet{}).Where("id = ?", order.WalletId).
+			Update("balance", gorm.Expr("balance + ?", order.Total)).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&order).Update("status", OrderStatusCancelled).Error
+	})
+}
+
+// item dengan product yang sama digabung dan diurutkan berdasarkan id
+// supaya urutan lock selalu sama dan tidak terjadi deadlock
+func mergeCheckoutItems(items []CheckoutItem) ([]CheckoutItem, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyOrder
+	}
+
+	quantities := map[int]int{}
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+		quantities[item.ProductId] += item.Quantity
+	}
+
+	merged := make([]CheckoutItem, 0, len(quantities))
+	for productId, quantity := range quantities {
+		merged = append(merged, CheckoutItem{ProductId: productId, Quantity: quantity})
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].ProductId < merged[j].ProductId
+	})
+
+	return merged, nil
+}
@@ -0,0 +1,148 @@
+package belajargolanggorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hapus data checkout dari run sebelumnya supaya test bisa diulang di database yang sama
+func cleanCheckout(t *testing.T) {
+	orders := db.Model(&Order{}).Select("id").Where("user_id = ?", 27)
+	require.NoError(t, db.Where("order_id IN (?)", orders).Delete(&OrderItem{}).Error)
+	require.NoError(t, db.Where("user_id = ?", 27).Delete(&Order{}).Error)
+	require.NoError(t, db.Where("user_id = ?", 27).Delete(&Address{}).Error)
+	require.NoError(t, db.Where("user_id = ?", 27).Delete(&Wallet{}).Error)
+	require.NoError(t, db.Where("id = ?", 27).Delete(&User{}).Error)
+	require.NoError(t, db.Where("product_id IN ?", []int{27, 28, 29}).Delete(&ProductPrice{}).Error)
+	require.NoError(t, db.Where("id IN ?", []int{27, 28, 29}).Delete(&Product{}).Error)
+}
+
+func TestCheckout(t *testing.T) {
+	cleanCheckout(t)
+
+	user := User{
+		ID:       27,
+		Password: "Rahasia",
+		Name: Name{
+			FirstName: "Salman 27",
+		},
+		Wallet: Wallet{
+			ID:      "27",
+			UserId:  27,
+			Balance: 100000,
+		},
+		Addresses: []Address{
+			{
+				UserId:  27,
+				Address: "Jl. Checkout No 27",
+			},
+		},
+	}
+	err := db.Create(&user).Error
+	require.NoError(t, err)
+
+	products := []Product{
+		{ID: 27, Name: "Product 27", Price: 10000, Stock: 5},
+		{ID: 28, Name: "Product 28", Price: 25000, Stock: 1},
+	}
+	err = db.Create(&products).Error
+	require.NoError(t, err)
+
+	order, err := Checkout(db, CheckoutRequest{
+		UserId:    27,
+		AddressId: user.Addresses[0].ID,
+		Items: []CheckoutItem{
+			{ProductId: 27, Quantity: 2},
+			{ProductId: 28, Quantity: 1},
+			{ProductId: 27, Quantity: 1},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(55000), order.Total)
+	assert.Equal(t, "Jl. Checkout No 27", order.ShippingAddress)
+	assert.Equal(t, 2, len(order.Items))
+
+	var saved Order
+	err = db.Preload("Items").Take(&saved, "id = ?", order.ID).Error
+	assert.Nil(t, err)
+	assert.Equal(t, OrderStatusPaid, saved.Status)
+	assert.Equal(t, 2, len(saved.Items))
+
+	var wallet Wallet
+	err = db.Take(&wallet, "id = ?", "27").Error
+	assert.Nil(t, err)
+	assert.Equal(t, float64(45000), wallet.Balance)
+
+	var product Product
+	err = db.Take(&product, "id = ?", 27).Error
+	assert.Nil(t, err)
+	assert.Equal(t, 2, product.Stock)
+}
+
+func TestCheckoutRollback(t *testing.T) {
+	var address Address
+	err := db.Take(&address, "user_id = ?", 27).Error
+	assert.Nil(t, err)
+
+	// stok product 28 sudah habis, stok product 27 tidak boleh ikut berkurang
+	_, err = Checkout(db, CheckoutRequest{
+		UserId:    27,
+		AddressId: address.ID,
+		Items: []CheckoutItem{
+			{ProductId: 27, Quantity: 1},
+			{ProductId: 28, Quantity: 1},
+		},
+	})
+	assert.Equal(t, ErrOutOfStock, err)
+
+	product := Product{ID: 29, Name: "Product 29", Price: 50000, Stock: 1}
+	err = db.Create(&product).Error
+	assert.Nil(t, err)
+
+	// saldo wallet tinggal 45000, stok product 29 harus dikembalikan
+	_, err = Checkout(db, CheckoutRequest{
+		UserId:    27,
+		AddressId: address.ID,
+		Items: []CheckoutItem{
+			{ProductId: 29, Quantity: 1},
+		},
+	})
+	assert.Equal(t, ErrInsufficientBalance, err)
+
+	product = Product{}
+	err = db.Take(&product, "id = ?", 29).Error
+	assert.Nil(t, err)
+	assert.Equal(t, 1, product.Stock)
+
+	product = Product{}
+	err = db.Take(&product, "id = ?", 27).Error
+	assert.Nil(t, err)
+	assert.Equal(t, 2, product.Stock)
+
+	_, err = Checkout(db, CheckoutRequest{UserId: 27, AddressId: address.ID})
+	assert.Equal(t, ErrEmptyOrder, err)
+}
+
+func TestCancelOrder(t *testing.T) {
+	var order Order
+	err := db.Where("user_id = ?", 27).Order("id desc").Take(&order).Error
+	assert.Nil(t, err)
+
+	err = CancelOrder(db, order.ID)
+	assert.Nil(t, err)
+
+	err = CancelOrder(db, order.ID)
+	assert.Equal(t, ErrOrderNotCancellable, err)
+
+	var wallet Wallet
+	err = db.Take(&wallet, "id = ?", "27").Error
+	assert.Nil(t, err)
+	assert.Equal(t, float64(100000), wallet.Balance)
+
+	var product Product
+	err = db.Take(&product, "id = ?", 27).Error
+	assert.Nil(t, err)
+	assert.Equal(t, 5, product.Stock)
+}
@@ -0,0 +1,358 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var columnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+type cursorKey struct {
+	Column string          `json:"c"`
+	Desc   bool            `json:"d,omitempty"`
+	Type   string          `json:"t,omitempty"`
+	Value  json.RawMessage `json:"v"`
+}
+
+// cursor menyimpan nilai kolom sort dari baris terakhir (atau pertama kalau Before)
+type cursor struct {
+	Keys   []cursorKey `json:"k"`
+	Before bool        `json:"b,omitempty"`
+}
+
+// After adalah scope keyset pagination, mengambil baris setelah (atau sebelum) cursor
+// sesuai urutan sort yang tersimpan di dalam cursor. Kalau sorts diisi, cursor harus
+// dibuat untuk sort yang sama. Kolom cursor dicek terhadap schema model query.
+func After(encoded string, sorts ...Sort) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		c, err := decodeCursor(encoded)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		if len(sorts) > 0 && !sameSorts(c, sorts) {
+			db.AddError(ErrInvalidCursor)
+			return db
+		}
+
+		model := db.Statement.Model
+		if model == nil {
+			model = db.Statement.Dest
+		}
+		stmt := &gorm.Statement{DB: db}
+		if model == nil || stmt.Parse(model) != nil {
+			db.AddError(ErrInvalidCursor)
+			return db
+		}
+
+		if _, err := sortFields(stmt.Schema, c.sorts()); err != nil {
+			db.AddError(err)
+			return db
+		}
+
+		return applyCursor(db, c)
+	}
+}
+
+func OrderBy(sorts ...Sort) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, sort := range sorts {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Column}, Desc: sort.Desc})
+		}
+		return db
+	}
+}
+
+// FindCursor menjalankan keyset pagination. Primary key otomatis ditambahkan ke sort
+// supaya urutan selalu unik. Cursor kosong berarti halaman pertama.
+func FindCursor[T any](db *gorm.DB, encoded string, size int, sorts ...Sort) (Page[T], error) {
+	_, size = normalize(1, size)
+	result := Page[T]{Size: size}
+
+	query := db.Session(&gorm.Session{})
+	stmt := &gorm.Statement{DB: query}
+	if err := stmt.Parse(new(T)); err != nil {
+		return result, err
+	}
+
+	sorts = withPrimaryKey(stmt.Schema, sorts)
+	fields, err := sortFields(stmt.Schema, sorts)
+	if err != nil {
+		return result, err
+	}
+
+	err = query.Model(new(T)).Count(&result.Total).Error
+	if err != nil {
+		return result, err
+	}
+
+	c := cursor{}
+	if encoded != "" {
+		c, err = decodeCursor(encoded)
+		if err != nil {
+			return result, err
+		}
+		if !sameSorts(c, sorts) {
+			return result, ErrInvalidCursor
+		}
+	} else {
+		for _, sort := range sorts {
+			c.Keys = append(c.Keys, cursorKey{Column: sort.Column, Desc: sort.Desc})
+		}
+	}
+
+	err = applyCursor(query, c).Limit(size + 1).Find(&result.Items).Error
+	if err != nil {
+		return result, err
+	}
+
+	hasMore := len(result.Items) > size
+	if hasMore {
+		result.Items = result.Items[:size]
+	}
+	if c.Before {
+		for i, j := 0, len(result.Items)-1; i < j; i, j = i+1, j-1 {
+			result.Items[i], result.Items[j] = result.Items[j], result.Items[i]
+		}
+	}
+
+	if len(result.Items) == 0 {
+		return result, nil
+	}
+
+	hasNext := hasMore || (c.Before && encoded != "")
+	hasPrev := (!c.Before && encoded != "") || (c.Before && hasMore)
+	if hasNext {
+		result.NextCursor, err = encodeCursor(query, fields, sorts, result.Items[len(result.Items)-1], false)
+		if err != nil {
+			return result, err
+		}
+	}
+	if hasPrev {
+		result.PrevCursor, err = encodeCursor(query, fields, sorts, result.Items[0], true)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// sortFields mencari field schema untuk setiap kolom sort, kolom yang tidak ada di model ditolak
+func sortFields(s *schema.Schema, sorts []Sort) ([]*schema.Field, error) {
+	fields := make([]*schema.Field, len(sorts))
+	for i, sort := range sorts {
+		if !columnPattern.MatchString(sort.Column) {
+			return nil, ErrInvalidCursor
+		}
+
+		if dot := strings.LastIndex(sort.Column, "."); dot >= 0 && sort.Column[:dot] != s.Table {
+			return nil, ErrInvalidCursor
+		}
+		column := sort.Column[strings.LastIndex(sort.Column, ".")+1:]
+		fields[i] = s.LookUpField(column)
+		if fields[i] == nil || fields[i].DBName == "" {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return fields, nil
+}
+
+func withPrimaryKey(s *schema.Schema, sorts []Sort) []Sort {
+	primary := s.PrioritizedPrimaryField
+	if primary == nil {
+		return sorts
+	}
+
+	for _, sort := range sorts {
+		if sort.Column == primary.DBName || sort.Column == s.Table+"."+primary.DBName {
+			return sorts
+		}
+	}
+
+	return append(append([]Sort(nil), sorts...), Sort{Column: primary.DBName})
+}
+
+func (c cursor) sorts() []Sort {
+	sorts := make([]Sort, len(c.Keys))
+	for i, key := range c.Keys {
+		sorts[i] = Sort{Column: key.Column, Desc: key.Desc}
+	}
+	return sorts
+}
+
+func sameSorts(c cursor, sorts []Sort) bool {
+	if len(c.Keys) != len(sorts) {
+		return false
+	}
+	for i, key := range c.Keys {
+		if key.Column != sorts[i].Column || key.Desc != sorts[i].Desc {
+			return false
+		}
+	}
+	return true
+}
+
+// untuk sort (a asc, b desc) kondisinya: a > ? OR (a = ? AND b < ?).
+// NULL dianggap paling kecil seperti urutan MySQL dan SQLite, jadi nilai NULL
+// dibandingkan dengan IS NULL / IS NOT NULL supaya barisnya tidak hilang
+func applyCursor(db *gorm.DB, c cursor) *gorm.DB {
+	var conditions []string
+	var vars []interface{}
+	for i, key := range c.Keys {
+		if !columnPattern.MatchString(key.Column) {
+			db.AddError(ErrInvalidCursor)
+			return db
+		}
+
+		desc := key.Desc != c.Before
+		if key.Value != nil {
+			var parts []string
+			var partVars []interface{}
+			for _, previous := range c.Keys[:i] {
+				value, err := decodeValue(previous)
+				if err != nil {
+					db.AddError(err)
+					return db
+				}
+				if value == nil {
+					parts = append(parts, "? IS NULL")
+					partVars = append(partVars, clause.Column{Name: previous.Column})
+				} else {
+					parts = append(parts, "? = ?")
+					partVars = append(partVars, clause.Column{Name: previous.Column}, value)
+				}
+			}
+
+			value, err := decodeValue(key)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+
+			column := clause.Column{Name: key.Column}
+			switch {
+			case value == nil && desc:
+				// tidak ada nilai yang lebih kecil dari NULL
+				parts = nil
+			case value == nil:
+				parts = append(parts, "? IS NOT NULL")
+				partVars = append(partVars, column)
+			case desc:
+				parts = append(parts, "(? < ? OR ? IS NULL)")
+				partVars = append(partVars, column, value, column)
+			default:
+				parts = append(parts, "? > ?")
+				partVars = append(partVars, column, value)
+			}
+
+			if parts != nil {
+				conditions = append(conditions, "("+strings.Join(parts, " AND ")+")")
+				vars = append(vars, partVars...)
+			}
+		}
+
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: key.Column}, Desc: desc})
+	}
+
+	if len(conditions) > 0 {
+		db = db.Where(strings.Join(conditions, " OR "), vars...)
+	} else if c.Keys[0].Value != nil {
+		// cursor sudah di baris terakhir
+		db = db.Where("1 = 0")
+	}
+	return db
+}
+
+func encodeCursor(db *gorm.DB, fields []*schema.Field, sorts []Sort, item interface{}, before bool) (string, error) {
+	value := reflect.ValueOf(item)
+	c := cursor{Before: before}
+	for i, sort := range sorts {
+		fieldValue, _ := fields[i].ValueOf(db.Statement.Context, value)
+		if t, ok := fieldValue.(*time.Time); ok && t != nil {
+			fieldValue = *t
+		}
+
+		key := cursorKey{Column: sort.Column, Desc: sort.Desc}
+		if t, ok := fieldValue.(time.Time); ok {
+			key.Type = "time"
+			fieldValue = t.Format(time.RFC3339Nano)
+		}
+
+		raw, err := json.Marshal(fieldValue)
+		if err != nil {
+			return "", err
+		}
+		key.Value = raw
+		c.Keys = append(c.Keys, key)
+	}
+
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursor(encoded string) (cursor, error) {
+	var c cursor
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &c); err != nil || len(c.Keys) == 0 {
+		return c, ErrInvalidCursor
+	}
+	for _, key := range c.Keys {
+		if key.Value == nil {
+			return c, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+func decodeValue(key cursorKey) (interface{}, error) {
+	if key.Type == "time" {
+		var value string
+		if err := json.Unmarshal(key.Value, &value); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return t, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(key.Value))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if number, ok := value.(json.Number); ok {
+		if integer, err := number.Int64(); err == nil {
+			return integer, nil
+		}
+		return number.Float64()
+	}
+	return value, nil
+}
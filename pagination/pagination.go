@@ -0,0 +1,64 @@
+package pagination
+
+import (
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultSize = 10
+	MaxSize     = 100
+)
+
+// Page adalah hasil pagination, untuk offset pagination Page dan TotalPages diisi,
+// untuk cursor pagination NextCursor dan PrevCursor yang diisi
+type Page[T any] struct {
+	Items      []T
+	Page       int
+	Size       int
+	Total      int64
+	TotalPages int
+	NextCursor string
+	PrevCursor string
+}
+
+// Paginate adalah scope offset pagination, page mulai dari 1
+func Paginate(page int, size int) func(db *gorm.DB) *gorm.DB {
+	page, size = normalize(page, size)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Offset((page - 1) * size).Limit(size)
+	}
+}
+
+// FindPage menjalankan query db dengan offset pagination,
+// condition dan order yang sudah ada di db tetap dipakai
+func FindPage[T any](db *gorm.DB, page int, size int) (Page[T], error) {
+	page, size = normalize(page, size)
+	result := Page[T]{Page: page, Size: size}
+
+	query := db.Session(&gorm.Session{})
+	err := query.Model(new(T)).Count(&result.Total).Error
+	if err != nil {
+		return result, err
+	}
+
+	err = query.Scopes(Paginate(page, size)).Find(&result.Items).Error
+	if err != nil {
+		return result, err
+	}
+
+	result.TotalPages = int((result.Total + int64(size) - 1) / int64(size))
+	return result, nil
+}
+
+func normalize(page int, size int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = DefaultSize
+	}
+	if size > MaxSize {
+		size = MaxSize
+	}
+	return page, size
+}
@@ -0,0 +1,198 @@
+package pagination_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/internal/testdb"
+	"belajar-golang-gorm/pagination"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type User = belajargolanggorm.User
+
+func createUsers(t *testing.T, db *gorm.DB) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var users []User
+	for i := 1; i <= 25; i++ {
+		users = append(users, User{
+			ID:       i,
+			Password: "123456",
+			Name: belajargolanggorm.Name{
+				FirstName: "User " + strconv.Itoa(i),
+				// beberapa user punya last_name sama supaya sort butuh tie breaker
+				LastName: "Ke-" + strconv.Itoa(i%5),
+			},
+			CreatedAt: created.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	err := db.Create(&users).Error
+	assert.Nil(t, err)
+}
+
+func userIds(users []User) []int {
+	var ids []int
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+func TestPaginate(t *testing.T) {
+	db := testdb.Open(t)
+	createUsers(t, db)
+
+	var users []User
+	err := db.Order("id asc").Scopes(pagination.Paginate(2, 5)).Find(&users).Error
+	assert.Nil(t, err)
+	assert.Equal(t, []int{6, 7, 8, 9, 10}, userIds(users))
+}
+
+func TestFindPage(t *testing.T) {
+	db := testdb.Open(t)
+	createUsers(t, db)
+
+	page, err := pagination.FindPage[User](db.Order("id asc"), 3, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(25), page.Total)
+	assert.Equal(t, 3, page.TotalPages)
+	assert.Equal(t, []int{21, 22, 23, 24, 25}, userIds(page.Items))
+
+	page, err = pagination.FindPage[User](db.Where("last_name = ?", "Ke-1").Order("id asc"), 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), page.Total)
+	assert.Equal(t, 3, page.TotalPages)
+	assert.Equal(t, []int{1, 6}, userIds(page.Items))
+
+	page, err = pagination.FindPage[User](db, 0, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, page.Page)
+	assert.Equal(t, pagination.MaxSize, page.Size)
+}
+
+func TestFindCursor(t *testing.T) {
+	db := testdb.Open(t)
+	createUsers(t, db)
+
+	var ids []int
+	var pages []pagination.Page[User]
+	cursor := ""
+	for {
+		page, err := pagination.FindCursor[User](db, cursor, 10, pagination.Sort{Column: "created_at", Desc: true})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(25), page.Total)
+
+		ids = append(ids, userIds(page.Items)...)
+		pages = append(pages, page)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	assert.Equal(t, 3, len(pages))
+	assert.Equal(t, 25, len(ids))
+	assert.Equal(t, 25, ids[0])
+	assert.Equal(t, 1, ids[24])
+	assert.Equal(t, "", pages[0].PrevCursor)
+
+	page, err := pagination.FindCursor[User](db, pages[2].PrevCursor, 10, pagination.Sort{Column: "created_at", Desc: true})
+	assert.Nil(t, err)
+	assert.Equal(t, userIds(pages[1].Items), userIds(page.Items))
+	assert.NotEqual(t, "", page.PrevCursor)
+	assert.NotEqual(t, "", page.NextCursor)
+
+	page, err = pagination.FindCursor[User](db, page.PrevCursor, 10, pagination.Sort{Column: "created_at", Desc: true})
+	assert.Nil(t, err)
+	assert.Equal(t, userIds(pages[0].Items), userIds(page.Items))
+	assert.Equal(t, "", page.PrevCursor)
+}
+
+func TestFindCursorMixedSort(t *testing.T) {
+	db := testdb.Open(t)
+	createUsers(t, db)
+
+	sorts := []pagination.Sort{{Column: "last_name"}, {Column: "id", Desc: true}}
+	page, err := pagination.FindCursor[User](db, "", 4, sorts...)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{25, 20, 15, 10}, userIds(page.Items))
+
+	page, err = pagination.FindCursor[User](db, page.NextCursor, 4, sorts...)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{5, 21, 16, 11}, userIds(page.Items))
+
+	// scope After bisa dipakai langsung dengan query biasa
+	var users []User
+	err = db.Scopes(pagination.After(page.NextCursor)).Limit(2).Find(&users).Error
+	assert.Nil(t, err)
+	assert.Equal(t, []int{6, 1}, userIds(users))
+}
+
+func TestInvalidCursor(t *testing.T) {
+	db := testdb.Open(t)
+	createUsers(t, db)
+
+	_, err := pagination.FindCursor[User](db, "bukan-cursor", 10)
+	assert.Equal(t, pagination.ErrInvalidCursor, err)
+
+	page, err := pagination.FindCursor[User](db, "", 10, pagination.Sort{Column: "first_name"})
+	assert.Nil(t, err)
+
+	// cursor dibuat untuk sort lain
+	_, err = pagination.FindCursor[User](db, page.NextCursor, 10, pagination.Sort{Column: "last_name"})
+	assert.Equal(t, pagination.ErrInvalidCursor, err)
+
+	_, err = pagination.FindCursor[User](db, "", 10, pagination.Sort{Column: "id; drop table users"})
+	assert.Equal(t, pagination.ErrInvalidCursor, err)
+
+	var users []User
+	err = db.Scopes(pagination.After("bukan-cursor")).Find(&users).Error
+	assert.Equal(t, pagination.ErrInvalidCursor, err)
+
+	// After menolak cursor untuk sort lain dan kolom yang tidak ada di model
+	err = db.Scopes(pagination.After(page.NextCursor, pagination.Sort{Column: "last_name"})).Find(&users).Error
+	assert.Equal(t, pagination.ErrInvalidCursor, err)
+
+	err = db.Model(&belajargolanggorm.Product{}).Scopes(pagination.After(page.NextCursor)).Find(&users).Error
+	assert.Equal(t, pagination.ErrInvalidCursor, err)
+
+	err = db.Scopes(pagination.After(page.NextCursor, pagination.Sort{Column: "first_name"}, pagination.Sort{Column: "id"})).Limit(1).Find(&users).Error
+	assert.Nil(t, err)
+}
+
+func TestFindCursorNullableSort(t *testing.T) {
+	db := testdb.Open(t)
+	createUsers(t, db)
+
+	// sebagian user tidak punya email, baris dengan NULL tidak boleh hilang
+	for i := 1; i <= 25; i += 2 {
+		err := db.Model(&User{}).Where("id = ?", i).Update("email", "user"+strconv.Itoa(i)+"@example.com").Error
+		assert.Nil(t, err)
+	}
+
+	for _, desc := range []bool{false, true} {
+		var ids []int
+		cursor := ""
+		for {
+			page, err := pagination.FindCursor[User](db, cursor, 4, pagination.Sort{Column: "email", Desc: desc})
+			assert.Nil(t, err)
+			ids = append(ids, userIds(page.Items)...)
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+
+		seen := map[int]bool{}
+		for _, id := range ids {
+			seen[id] = true
+		}
+		assert.Equal(t, 25, len(ids))
+		assert.Equal(t, 25, len(seen))
+	}
+}
@@ -6,6 +6,8 @@ type Product struct {
 	ID           int       `gorm:"column:id;primary_key"`
 	Name         string    `gorm:"column:name"`
 	Price        int64     `gorm:"column:price"`
+	Stock        int       `gorm:"column:stock"`
+	LikeCount    int64     `gorm:"column:like_count"`
 	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime"`
 	UpdatedAt    time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
 	LikedByUsers []User    `gorm:"many2many:user_like_product;foreignKey:id;joinForeignKey:product_id;references:id;joinReferences:user_id"`
@@ -0,0 +1,275 @@
+package belajargolanggorm
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrPriceNotFound = errors.New("price not found")
+
+// riwayat harga product, effective_to kosong berarti masih berlaku
+type ProductPrice struct {
+	ID            int        `gorm:"column:id;primary_key;autoIncrement"`
+	ProductId     int        `gorm:"column:product_id"`
+	Price         int64      `gorm:"column:price"`
+	EffectiveFrom time.Time  `gorm:"column:effective_from"`
+	EffectiveTo   *time.Time `gorm:"column:effective_to"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime;<-:create"`
+}
+
+// hook after create, harga awal langsung dicatat ke riwayat.
+// upsert bisa mengenai product yang sudah ada dan belum tentu menulis kolom price,
+// riwayatnya disesuaikan sekali per batch lewat SyncProductPrices
+func (p *Product) AfterCreate(tx *gorm.DB) error {
+	if _, ok := tx.Statement.Clauses["ON CONFLICT"]; ok {
+		return nil
+	}
+	return recordProductPrice(tx, p.ID, p.Price, p.CreatedAt)
+}
+
+// AfterUpsert dipanggil importer setelah satu batch upsert
+func (p *Product) AfterUpsert(tx *gorm.DB, values interface{}) error {
+	var ids []int
+	switch values := values.(type) {
+	case *[]Product:
+		for _, product := range *values {
+			ids = append(ids, product.ID)
+		}
+	case *Product:
+		ids = append(ids, values.ID)
+	}
+	return SyncProductPrices(tx, ids)
+}
+
+// hook before update, tandai kalau update menulis kolom price
+// (Update("price"), Updates, Save) supaya riwayat ikut dicatat.
+// Update tanpa primary key seperti Model(&Product{}).Where("id = ?", id).Update("price", ...)
+// tidak tahu product mana yang berubah, id-nya dicari dulu dengan kondisi WHERE yang sama
+func (p *Product) BeforeUpdate(tx *gorm.DB) error {
+	if !updatesPrice(tx.Statement) {
+		return nil
+	}
+	tx.Statement.Settings.Store("product:price_changed", true)
+	if p.ID != 0 {
+		return nil
+	}
+
+	where, ok := tx.Statement.Clauses["WHERE"]
+	if !ok {
+		return nil
+	}
+	var ids []int
+	err := tx.Model(&Product{}).Clauses(where.Expression).Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	tx.Statement.Settings.Store("product:price_ids", ids)
+	return nil
+}
+
+// hook after update, harga baru berlaku mulai sekarang
+func (p *Product) AfterUpdate(tx *gorm.DB) error {
+	if _, ok := tx.Statement.Settings.Load("product:price_changed"); !ok {
+		return nil
+	}
+	if ids, ok := tx.Statement.Settings.Load("product:price_ids"); ok {
+		return SyncProductPrices(tx, ids.([]int))
+	}
+	if p.ID == 0 {
+		return nil
+	}
+	return recordProductPrice(tx, p.ID, p.Price, time.Now())
+}
+
+func updatesPrice(stmt *gorm.Statement) bool {
+	if stmt.Changed("Price") {
+		return true
+	}
+	if _, ok := stmt.Dest.(map[string]interface{}); ok {
+		return false
+	}
+
+	// Save memakai Select("*"), nilai dest sama dengan model
+	columns, _ := stmt.SelectAndOmitColumns(false, true)
+	return len(stmt.Selects) > 0 && columns["price"]
+}
+
+// ChangeProductPrice mencatat harga baru yang berlaku mulai effectiveFrom.
+// effectiveFrom di masa depan berarti harga dijadwalkan, products.price baru
+// berubah setelah ApplyScheduledPrices dijalankan.
+func ChangeProductPrice(db *gorm.DB, productId int, price int64, effectiveFrom time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var product Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&product, "id = ?", productId).Error
+		if err != nil {
+			return err
+		}
+
+		err = recordProductPrice(tx, productId, price, effectiveFrom)
+		if err != nil {
+			return err
+		}
+
+		return syncProductPrice(tx, productId, time.Now())
+	})
+}
+
+// recordProductPrice menyisipkan harga ke riwayat, baris sebelumnya ditutup
+// pada effectiveFrom. tidak melakukan apa-apa kalau harga yang berlaku sudah sama
+func recordProductPrice(tx *gorm.DB, productId int, price int64, effectiveFrom time.Time) error {
+	current, err := PriceAt(tx, productId, effectiveFrom)
+	if err == nil && current == price {
+		return nil
+	}
+	if err != nil && !errors.Is(err, ErrPriceNotFound) {
+		return err
+	}
+
+	// jadwal di waktu yang sama diganti dengan harga baru
+	err = tx.Where("product_id = ? AND effective_from = ?", productId, effectiveFrom).Delete(&ProductPrice{}).Error
+	if err != nil {
+		return err
+	}
+
+	var effectiveTo *time.Time
+	var next ProductPrice
+	err = tx.Where("product_id = ? AND effective_from > ?", productId, effectiveFrom).Order("effective_from asc").Limit(1).Find(&next).Error
+	if err != nil {
+		return err
+	}
+	if next.ID != 0 {
+		effectiveTo = &next.EffectiveFrom
+	}
+
+	var prev ProductPrice
+	err = tx.Where("product_id = ? AND effective_from < ?", productId, effectiveFrom).Order("effective_from desc").Limit(1).Find(&prev).Error
+	if err != nil {
+		return err
+	}
+	if prev.ID != 0 {
+		err = tx.Model(&prev).Update("effective_to", effectiveFrom).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(&ProductPrice{
+		ProductId:     productId,
+		Price:         price,
+		EffectiveFrom: effectiveFrom,
+		EffectiveTo:   effectiveTo,
+	}).Error
+}
+
+// SyncProductPrices menyamakan riwayat harga dengan products.price untuk banyak product
+// sekaligus, dipakai setelah upsert. Product tanpa riwayat dicatat mulai created_at,
+// product yang harganya berbeda dengan harga yang berlaku dicatat mulai sekarang
+func SyncProductPrices(tx *gorm.DB, productIds []int) error {
+	if len(productIds) == 0 {
+		return nil
+	}
+
+	var products []Product
+	err := tx.Select("id", "price", "created_at").Where("id IN ?", productIds).Find(&products).Error
+	if err != nil {
+		return err
+	}
+
+	var history []int
+	err = tx.Model(&ProductPrice{}).Where("product_id IN ?", productIds).Distinct("product_id").Pluck("product_id", &history).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var current []ProductPrice
+	err = tx.Where("product_id IN ? AND effective_from <= ?", productIds, now).
+		Where("effective_to IS NULL OR effective_to > ?", now).
+		Order("effective_from asc").
+		Find(&current).Error
+	if err != nil {
+		return err
+	}
+
+	recorded := make(map[int]bool, len(history))
+	for _, id := range history {
+		recorded[id] = true
+	}
+	prices := make(map[int]int64, len(current))
+	for _, price := range current {
+		prices[price.ProductId] = price.Price
+	}
+
+	var initial []ProductPrice
+	for _, product := range products {
+		if !recorded[product.ID] {
+			initial = append(initial, ProductPrice{ProductId: product.ID, Price: product.Price, EffectiveFrom: product.CreatedAt})
+			continue
+		}
+		if price, ok := prices[product.ID]; ok && price == product.Price {
+			continue
+		}
+		err = recordProductPrice(tx, product.ID, product.Price, now)
+		if err != nil {
+			return err
+		}
+	}
+	if len(initial) == 0 {
+		return nil
+	}
+	return tx.Create(&initial).Error
+}
+
+// PriceAt mengembalikan harga product yang berlaku pada waktu at
+func PriceAt(db *gorm.DB, productId int, at time.Time) (int64, error) {
+	var price ProductPrice
+	err := db.Where("product_id = ? AND effective_from <= ?", productId, at).
+		Where("effective_to IS NULL OR effective_to > ?", at).
+		Order("effective_from desc").
+		Take(&price).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrPriceNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return price.Price, nil
+}
+
+func ProductPriceHistory(db *gorm.DB, productId int) ([]ProductPrice, error) {
+	var prices []ProductPrice
+	err := db.Where("product_id = ?", productId).Order("effective_from asc").Find(&prices).Error
+	return prices, err
+}
+
+// ApplyScheduledPrices menyamakan products.price dengan harga yang berlaku pada waktu now,
+// dijalankan berkala (cron) supaya harga terjadwal aktif
+func ApplyScheduledPrices(db *gorm.DB, now time.Time) (int64, error) {
+	current := db.Model(&ProductPrice{}).Select("price").
+		Where("product_prices.product_id = products.id AND effective_from <= ?", now).
+		Where("effective_to IS NULL OR effective_to > ?", now).
+		Order("effective_from desc").Limit(1)
+
+	result := db.Model(&Product{}).
+		Where("EXISTS (?)", current).
+		Where("price <> (?)", current).
+		Update("price", current)
+
+	return result.RowsAffected, result.Error
+}
+
+func syncProductPrice(tx *gorm.DB, productId int, now time.Time) error {
+	price, err := PriceAt(tx, productId, now)
+	if errors.Is(err, ErrPriceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&Product{}).Where("id = ?", productId).Update("price", price).Error
+}
@@ -0,0 +1,150 @@
+package belajargolanggorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/clause"
+)
+
+func TestProductPriceHistory(t *testing.T) {
+	now := time.Now()
+	product := Product{
+		ID:        26,
+		Name:      "Product 26",
+		Price:     10000,
+		CreatedAt: now.Add(-48 * time.Hour),
+	}
+	err := db.Create(&product).Error
+	assert.Nil(t, err)
+
+	err = ChangeProductPrice(db, product.ID, 15000, now.Add(-24*time.Hour))
+	assert.Nil(t, err)
+
+	prices, err := ProductPriceHistory(db, product.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(prices))
+
+	price, err := PriceAt(db, product.ID, now.Add(-36*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10000), price)
+
+	price, err = PriceAt(db, product.ID, now)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(15000), price)
+
+	_, err = PriceAt(db, product.ID, now.Add(-72*time.Hour))
+	assert.Equal(t, ErrPriceNotFound, err)
+
+	product = Product{}
+	err = db.Take(&product, "id = ?", 26).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(15000), product.Price)
+}
+
+func TestScheduledProductPrice(t *testing.T) {
+	now := time.Now()
+	err := ChangeProductPrice(db, 26, 20000, now.Add(24*time.Hour))
+	assert.Nil(t, err)
+
+	// harga di tengah jadwal, effective_to harus menyesuaikan jadwal berikutnya
+	err = ChangeProductPrice(db, 26, 12000, now.Add(-12*time.Hour))
+	assert.Nil(t, err)
+
+	var product Product
+	err = db.Take(&product, "id = ?", 26).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12000), product.Price)
+
+	price, err := PriceAt(db, 26, now.Add(-18*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(15000), price)
+
+	price, err = PriceAt(db, 26, now.Add(48*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20000), price)
+
+	affected, err := ApplyScheduledPrices(db, now.Add(25*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	product = Product{}
+	err = db.Take(&product, "id = ?", 26).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20000), product.Price)
+}
+
+func openPrices(t *testing.T, productId int) int64 {
+	var count int64
+	err := db.Model(&ProductPrice{}).Where("product_id = ? AND effective_to IS NULL", productId).Count(&count).Error
+	assert.Nil(t, err)
+	return count
+}
+
+func TestProductPriceUpdateHooks(t *testing.T) {
+	product := Product{ID: 32, Name: "Product 32", Price: 10000, Stock: 1}
+	err := db.Create(&product).Error
+	assert.Nil(t, err)
+
+	// upsert product yang sudah ada tidak boleh menambah baris yang masih berlaku
+	err = db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&Product{ID: 32, Name: "Product 32", Price: 10000, Stock: 1}).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), openPrices(t, 32))
+
+	err = db.Model(&product).Update("stock", 2).Error
+	assert.Nil(t, err)
+
+	prices, err := ProductPriceHistory(db, 32)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(prices))
+
+	err = db.Model(&product).Update("price", 12000).Error
+	assert.Nil(t, err)
+
+	product.Price = 14000
+	err = db.Save(&product).Error
+	assert.Nil(t, err)
+
+	prices, err = ProductPriceHistory(db, 32)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(prices))
+	assert.Equal(t, int64(1), openPrices(t, 32))
+
+	price, err := PriceAt(db, 32, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(14000), price)
+}
+
+func TestProductPriceUpdateWithoutPrimaryKey(t *testing.T) {
+	err := db.Where("product_id IN ?", []int{33, 34}).Delete(&ProductPrice{}).Error
+	assert.Nil(t, err)
+	err = db.Where("id IN ?", []int{33, 34}).Delete(&Product{}).Error
+	assert.Nil(t, err)
+
+	products := []Product{
+		{ID: 33, Name: "Product 33", Price: 10000, Stock: 1},
+		{ID: 34, Name: "Product 34", Price: 10000, Stock: 1},
+	}
+	err = db.Create(&products).Error
+	assert.Nil(t, err)
+
+	err = db.Model(&Product{}).Where("id = ?", 33).Update("price", 12000).Error
+	assert.Nil(t, err)
+	err = db.Model(&Product{}).Where("id IN ?", []int{33, 34}).Updates(map[string]interface{}{"price": 15000}).Error
+	assert.Nil(t, err)
+
+	prices, err := ProductPriceHistory(db, 33)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(prices))
+	assert.Equal(t, int64(1), openPrices(t, 33))
+
+	prices, err = ProductPriceHistory(db, 34)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(prices))
+	assert.Equal(t, int64(1), openPrices(t, 34))
+
+	price, err := PriceAt(db, 34, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(15000), price)
+}
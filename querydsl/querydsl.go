@@ -0,0 +1,325 @@
+package querydsl
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"belajar-golang-gorm/internal/sensitive"
+	"belajar-golang-gorm/pagination"
+	"belajar-golang-gorm/scopes"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	OpEq   = "eq"
+	OpNe   = "ne"
+	OpGt   = "gt"
+	OpGte  = "gte"
+	OpLt   = "lt"
+	OpLte  = "lte"
+	OpLike = "like"
+	OpIn   = "in"
+	OpNull = "null"
+)
+
+var filterPattern = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// Spec adalah whitelist kolom yang boleh difilter dan disort untuk satu model,
+// diambil dari schema GORM termasuk field embedded seperti Name di User.
+// Kolom sensitif (sensitive.Columns) tidak pernah masuk whitelist
+type Spec struct {
+	model      interface{}
+	registry   *scopes.Registry
+	filterable map[string]*schema.Field
+	sortable   map[string]*schema.Field
+}
+
+type Option func(spec *Spec)
+
+// Exclude menghapus kolom dari whitelist filter dan sort, misalnya password
+func Exclude(columns ...string) Option {
+	return func(spec *Spec) {
+		for _, column := range columns {
+			delete(spec.filterable, column)
+			delete(spec.sortable, column)
+		}
+	}
+}
+
+// SortableOnly membatasi kolom yang boleh disort
+func SortableOnly(columns ...string) Option {
+	return func(spec *Spec) {
+		sortable := map[string]*schema.Field{}
+		for _, column := range columns {
+			if field, ok := spec.sortable[column]; ok {
+				sortable[column] = field
+			}
+		}
+		spec.sortable = sortable
+	}
+}
+
+// WithRegistry mengganti registry untuk parameter scope, defaultnya scopes.Default
+func WithRegistry(registry *scopes.Registry) Option {
+	return func(spec *Spec) {
+		spec.registry = registry
+	}
+}
+
+func For(db *gorm.DB, model interface{}, options ...Option) (*Spec, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	spec := &Spec{
+		model:      model,
+		registry:   scopes.Default,
+		filterable: map[string]*schema.Field{},
+		sortable:   map[string]*schema.Field{},
+	}
+	for _, field := range stmt.Schema.Fields {
+		// field relasi dan field dengan tag gorm:"-" tidak punya kolom
+		if field.DBName == "" || !field.Readable {
+			continue
+		}
+		spec.filterable[field.DBName] = field
+		spec.sortable[field.DBName] = field
+	}
+	// password dan token tidak boleh ditebak lewat filter atau urutan sort
+	for _, column := range sensitive.Columns {
+		delete(spec.filterable, column)
+		delete(spec.sortable, column)
+	}
+
+	for _, option := range options {
+		option(spec)
+	}
+
+	return spec, nil
+}
+
+func (s *Spec) Filterable() []string {
+	return sortedKeys(s.filterable)
+}
+
+func (s *Spec) Sortable() []string {
+	return sortedKeys(s.sortable)
+}
+
+type Filter struct {
+	Column   string
+	Operator string
+	Value    interface{} // untuk OpIn berisi []interface{}, untuk OpNull berisi bool
+}
+
+type Query struct {
+	Filters []Filter
+	Scopes  []scopes.Scope
+	Sorts   []pagination.Sort
+}
+
+// Parse membaca parameter seperti filter[first_name][like]=User&sort=-created_at&scope=broke|sultan,
+// parameter lain (page, include, dll) diabaikan
+func (s *Spec) Parse(values url.Values) (*Query, error) {
+	query := &Query{}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "filter") {
+			continue
+		}
+		match := filterPattern.FindStringSubmatch(key)
+		if match == nil {
+			return nil, fmt.Errorf("%w: malformed parameter %q", ErrInvalidQuery, key)
+		}
+
+		column, operator := match[1], match[2]
+		if operator == "" {
+			operator = OpEq
+		}
+
+		field, ok := s.filterable[column]
+		if !ok {
+			return nil, fmt.Errorf("%w: column %q is not filterable", ErrInvalidQuery, column)
+		}
+
+		for _, raw := range values[key] {
+			value, err := parseValue(field, operator, raw)
+			if err != nil {
+				return nil, err
+			}
+			query.Filters = append(query.Filters, Filter{Column: column, Operator: operator, Value: value})
+		}
+	}
+
+	for _, raw := range values["scope"] {
+		scope, err := s.registry.Parse(s.model, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidQuery, err)
+		}
+		query.Scopes = append(query.Scopes, scope)
+	}
+
+	for _, raw := range values["sort"] {
+		for _, item := range strings.Split(raw, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+
+			desc := strings.HasPrefix(item, "-")
+			column := strings.TrimPrefix(item, "-")
+			if _, ok := s.sortable[column]; !ok {
+				return nil, fmt.Errorf("%w: column %q is not sortable", ErrInvalidQuery, column)
+			}
+			query.Sorts = append(query.Sorts, pagination.Sort{Column: column, Desc: desc})
+		}
+	}
+
+	return query, nil
+}
+
+// Scope mem-parse values lalu mengembalikan scope GORM yang siap dipakai db.Scopes
+func (s *Spec) Scope(values url.Values) (func(db *gorm.DB) *gorm.DB, error) {
+	query, err := s.Parse(values)
+	if err != nil {
+		return nil, err
+	}
+	return query.Scope, nil
+}
+
+// Scope menerapkan filter dan sort, nama kolom selalu di-quote dan nilai selalu jadi parameter
+func (q *Query) Scope(db *gorm.DB) *gorm.DB {
+	db = q.FilterScope(db)
+	for _, sort := range q.Sorts {
+		db = db.Order(clause.OrderByColumn{Column: column(sort.Column), Desc: sort.Desc})
+	}
+	return db
+}
+
+// FilterScope hanya menerapkan filter, dipakai kalau urutan diatur oleh pagination cursor
+func (q *Query) FilterScope(db *gorm.DB) *gorm.DB {
+	for _, filter := range q.Filters {
+		db = db.Where(filter.expression())
+	}
+	return db.Scopes(q.Scopes...)
+}
+
+func (f Filter) expression() clause.Expression {
+	col := column(f.Column)
+	switch f.Operator {
+	case OpNe:
+		return clause.Neq{Column: col, Value: f.Value}
+	case OpGt:
+		return clause.Gt{Column: col, Value: f.Value}
+	case OpGte:
+		return clause.Gte{Column: col, Value: f.Value}
+	case OpLt:
+		return clause.Lt{Column: col, Value: f.Value}
+	case OpLte:
+		return clause.Lte{Column: col, Value: f.Value}
+	case OpLike:
+		// escape char ditulis eksplisit karena default MySQL (backslash) dan SQLite (tidak ada) berbeda
+		return clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []interface{}{col, f.Value}}
+	case OpIn:
+		return clause.IN{Column: col, Values: f.Value.([]interface{})}
+	case OpNull:
+		if f.Value.(bool) {
+			return clause.Expr{SQL: "? IS NULL", Vars: []interface{}{col}}
+		}
+		return clause.Expr{SQL: "? IS NOT NULL", Vars: []interface{}{col}}
+	default:
+		return clause.Eq{Column: col, Value: f.Value}
+	}
+}
+
+func column(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
+
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+func parseValue(field *schema.Field, operator string, raw string) (interface{}, error) {
+	switch operator {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte:
+		return convert(field, raw)
+	case OpLike:
+		// like berarti "mengandung", karakter wildcard dari user di-escape
+		return "%" + likeEscaper.Replace(raw) + "%", nil
+	case OpIn:
+		var values []interface{}
+		for _, item := range strings.Split(raw, ",") {
+			value, err := convert(field, strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case OpNull:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s[null] must be true or false", ErrInvalidQuery, field.DBName)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidQuery, operator)
+	}
+}
+
+func convert(field *schema.Field, raw string) (interface{}, error) {
+	var value interface{}
+	var err error
+	switch field.GORMDataType {
+	case schema.Int:
+		value, err = strconv.ParseInt(raw, 10, 64)
+	case schema.Uint:
+		value, err = strconv.ParseUint(raw, 10, 64)
+	case schema.Float:
+		value, err = strconv.ParseFloat(raw, 64)
+	case schema.Bool:
+		value, err = strconv.ParseBool(raw)
+	case schema.Time:
+		value, err = parseTime(raw)
+	default:
+		value = raw
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid value %q for %s", ErrInvalidQuery, raw, field.DBName)
+	}
+	return value, nil
+}
+
+func parseTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", raw, time.Local)
+}
+
+func sortedKeys(fields map[string]*schema.Field) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
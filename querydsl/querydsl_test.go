@@ -0,0 +1,229 @@
+package querydsl_test
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/internal/testdb"
+	"belajar-golang-gorm/pagination"
+	"belajar-golang-gorm/querydsl"
+	"belajar-golang-gorm/scopes"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type User = belajargolanggorm.User
+
+func createUsers(t *testing.T, db *gorm.DB) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var users []User
+	for i := 1; i <= 10; i++ {
+		users = append(users, User{
+			ID:       i,
+			Password: "rahasia",
+			Name: belajargolanggorm.Name{
+				FirstName: "User " + strconv.Itoa(i),
+				LastName:  "Ke-" + strconv.Itoa(i%3),
+			},
+			CreatedAt: created.Add(time.Duration(i) * 24 * time.Hour),
+		})
+	}
+	users = append(users, User{ID: 11, Password: "rahasia", Name: belajargolanggorm.Name{FirstName: "50%_off"}, CreatedAt: created})
+
+	err := db.Create(&users).Error
+	assert.Nil(t, err)
+}
+
+func userSpec(t *testing.T, db *gorm.DB) *querydsl.Spec {
+	spec, err := querydsl.For(db, &User{}, querydsl.Exclude("password"))
+	assert.Nil(t, err)
+	return spec
+}
+
+func find(t *testing.T, db *gorm.DB, spec *querydsl.Spec, raw string) ([]int, error) {
+	values, err := url.ParseQuery(raw)
+	assert.Nil(t, err)
+
+	query, err := spec.Parse(values)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []User
+	err = db.Scopes(query.Scope).Order("id asc").Find(&users).Error
+	assert.Nil(t, err)
+
+	var ids []int
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+func TestSpecColumns(t *testing.T) {
+	db := testdb.Open(t)
+	spec := userSpec(t, db)
+
+	columns := spec.Filterable()
+	assert.Contains(t, columns, "first_name")
+	assert.Contains(t, columns, "last_name")
+	assert.Contains(t, columns, "created_at")
+	assert.NotContains(t, columns, "password")
+	// Information punya tag gorm:"-", relasi juga tidak punya kolom
+	assert.NotContains(t, columns, "information")
+	assert.NotContains(t, columns, "wallet")
+
+	// kolom sensitif tetap tidak bisa difilter atau disort walaupun tidak di-Exclude
+	spec, err := querydsl.For(db, &User{})
+	assert.Nil(t, err)
+	assert.NotContains(t, spec.Filterable(), "password")
+	assert.NotContains(t, spec.Sortable(), "password")
+
+	spec, err = querydsl.For(db, &belajargolanggorm.EmailVerification{})
+	assert.Nil(t, err)
+	assert.Contains(t, spec.Filterable(), "email")
+	assert.NotContains(t, spec.Filterable(), "token")
+	assert.NotContains(t, spec.Sortable(), "token")
+}
+
+func TestFilterLike(t *testing.T) {
+	db := testdb.Open(t)
+	createUsers(t, db)
+	spec := userSpec(t, db)
+
+	ids, err := find(t, db, spec, "filter[first_name][like]=User 1")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 10}, ids)
+
+	// % dan _ dari user tidak boleh jadi wildcard
+	ids, err = find(t, db, spec, "filter[first_name][like]=%25_")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{11}, ids)
+}
+
+func TestFilterOperators(t *testing.T) {
+	db := testdb.Open(t)
+	createUsers(t, db)
+	spec := userSpec(t, db)
+
+	ids, err := find(t, db, spec, "filter[id][gte]=3&filter[id][lt]=6")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 4, 5}, ids)
+
+	ids, err = find(t, db, spec, "filter[last_name]=Ke-0")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 6, 9}, ids)
+
+	ids, err = find(t, db, spec, "filter[id][in]=2,4,8&filter[last_name][ne]=Ke-1")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 8}, ids)
+
+	ids, err = find(t, db, spec, "filter[created_at][gt]=2025-01-08")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{8, 9, 10}, ids)
+
+	ids, err = find(t, db, spec, "filter[email][null]=true&filter[id][lte]=2")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, ids)
+}
+
+func TestSort(t *testing.T) {
+	db := testdb.Open(t)
+	createUsers(t, db)
+	spec := userSpec(t, db)
+
+	values, _ := url.ParseQuery("sort=-created_at,first_name&filter[id][lte]=4")
+	query, err := spec.Parse(values)
+	assert.Nil(t, err)
+	assert.Equal(t, []pagination.Sort{{Column: "created_at", Desc: true}, {Column: "first_name"}}, query.Sorts)
+
+	var users []User
+	err = db.Scopes(query.Scope).Find(&users).Error
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(users))
+	assert.Equal(t, 4, users[0].ID)
+	assert.Equal(t, 1, users[3].ID)
+}
+
+func TestRejectInvalidQuery(t *testing.T) {
+	db := testdb.Open(t)
+	spec := userSpec(t, db)
+
+	invalid := []url.Values{
+		{"filter[password]": {"rahasia"}},
+		{"filter[information]": {"x"}},
+		{"filter[id) OR 1=1 --]": {"1"}},
+		{"filter[id][between]": {"1"}},
+		{"filter[id]": {"satu"}},
+		{"filter[email][null]": {"mungkin"}},
+		{"filter": {"id"}},
+		{"sort": {"password"}},
+		{"sort": {"id;DROP TABLE users"}},
+	}
+	for _, values := range invalid {
+		_, err := spec.Parse(values)
+		assert.True(t, errors.Is(err, querydsl.ErrInvalidQuery), values.Encode())
+	}
+
+	// parameter lain seperti page tidak ikut divalidasi
+	values, _ := url.ParseQuery("page=2&include=wallet")
+	query, err := spec.Parse(values)
+	assert.Nil(t, err)
+	assert.Empty(t, query.Filters)
+}
+
+func TestSortableOnly(t *testing.T) {
+	db := testdb.Open(t)
+	spec, err := querydsl.For(db, &User{}, querydsl.SortableOnly("id", "created_at"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"created_at", "id"}, spec.Sortable())
+
+	_, err = spec.Scope(url.Values{"sort": {"first_name"}})
+	assert.True(t, errors.Is(err, querydsl.ErrInvalidQuery))
+}
+
+func TestQuoteColumns(t *testing.T) {
+	db := testdb.Open(t)
+	spec := userSpec(t, db)
+
+	scope, err := spec.Scope(url.Values{"filter[first_name]": {"x' OR '1'='1"}})
+	assert.Nil(t, err)
+
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&User{}).Scopes(scope).Find(&[]User{})
+	})
+	assert.Contains(t, sql, "`users`.`first_name` = \"x' OR '1'='1\"")
+}
+
+func TestNamedScope(t *testing.T) {
+	db := testdb.Open(t)
+	wallets := []belajargolanggorm.Wallet{
+		{ID: "w1", UserId: 1, Balance: 0},
+		{ID: "w2", UserId: 2, Balance: 500},
+		{ID: "w3", UserId: 3, Balance: 2000000},
+	}
+	err := db.Create(&wallets).Error
+	assert.Nil(t, err)
+
+	spec, err := querydsl.For(db, &belajargolanggorm.Wallet{})
+	assert.Nil(t, err)
+
+	scope, err := spec.Scope(url.Values{"scope": {"broke|sultan"}, "sort": {"-balance"}})
+	assert.Nil(t, err)
+
+	wallets = nil
+	err = db.Scopes(scope).Find(&wallets).Error
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(wallets))
+	assert.Equal(t, "w3", wallets[0].ID)
+	assert.Equal(t, "w1", wallets[1].ID)
+
+	_, err = spec.Scope(url.Values{"scope": {"kaya"}})
+	assert.True(t, errors.Is(err, querydsl.ErrInvalidQuery))
+	assert.True(t, errors.Is(err, scopes.ErrUnknownScope))
+}
@@ -0,0 +1,142 @@
+package belajargolanggorm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"belajar-golang-gorm/dbcache"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+type ProductScore struct {
+	ProductId int    `gorm:"column:product_id"`
+	Name      string `gorm:"column:name"`
+	Score     int64  `gorm:"column:score"`
+}
+
+// TrendingProducts mengembalikan product dengan like terbanyak di rentang [since, until)
+func TrendingProducts(db *gorm.DB, since time.Time, until time.Time, limit int) ([]ProductScore, error) {
+	var scores []ProductScore
+	err := db.Table("user_like_product ulp").
+		Select("p.id as product_id, p.name, count(*) as score").
+		Joins("JOIN products p ON p.id = ulp.product_id").
+		Where("ulp.liked_at >= ? AND ulp.liked_at < ?", since, until).
+		Group("p.id, p.name").
+		Order("score desc, p.id asc").
+		Limit(limit).
+		Scan(&scores).Error
+
+	return scores, err
+}
+
+// AlsoLiked: user yang like product ini juga like product apa saja
+func AlsoLiked(db *gorm.DB, productId int, limit int) ([]ProductScore, error) {
+	var scores []ProductScore
+	err := db.Table("user_like_product base").
+		Select("p.id as product_id, p.name, count(*) as score").
+		Joins("JOIN user_like_product other ON other.user_id = base.user_id AND other.product_id <> base.product_id").
+		Joins("JOIN products p ON p.id = other.product_id").
+		Where("base.product_id = ?", productId).
+		Group("p.id, p.name").
+		Order("score desc, p.id asc").
+		Limit(limit).
+		Scan(&scores).Error
+
+	return scores, err
+}
+
+// SuggestProducts mencari product yang di-like user lain dengan selera sama,
+// makin banyak product yang sama-sama di-like makin besar skornya.
+// Product yang sudah di-like user tidak ikut disarankan.
+func SuggestProducts(db *gorm.DB, userId int, limit int) ([]ProductScore, error) {
+	liked := db.Table("user_like_product").Select("product_id").Where("user_id = ?", userId)
+
+	var scores []ProductScore
+	err := db.Table("user_like_product mine").
+		Select("p.id as product_id, p.name, count(*) as score").
+		Joins("JOIN user_like_product peer ON peer.product_id = mine.product_id AND peer.user_id <> mine.user_id").
+		Joins("JOIN user_like_product other ON other.user_id = peer.user_id").
+		Joins("JOIN products p ON p.id = other.product_id").
+		Where("mine.user_id = ?", userId).
+		Where("other.product_id NOT IN (?)", liked).
+		Group("p.id, p.name").
+		Order("score desc, p.id asc").
+		Limit(limit).
+		Scan(&scores).Error
+
+	return scores, err
+}
+
+// Recommender menyimpan hasil query rekomendasi di memory selama ttl,
+// karena query agregasi ini cukup berat kalau dipanggil tiap request.
+// Jumlah hasil yang disimpan dibatasi LRU, beberapa request bersamaan
+// dengan key yang sama hanya menjalankan satu query
+type Recommender struct {
+	db         *gorm.DB
+	ttl        time.Duration
+	cache      *dbcache.LRU
+	group      singleflight.Group
+	generation atomic.Uint64 // dinaikkan oleh Invalidate
+}
+
+func NewRecommender(db *gorm.DB, ttl time.Duration) *Recommender {
+	return &Recommender{
+		db:    db,
+		ttl:   ttl,
+		cache: dbcache.NewLRU(dbcache.DefaultCapacity),
+	}
+}
+
+func (r *Recommender) Trending(since time.Time, until time.Time, limit int) ([]ProductScore, error) {
+	key := fmt.Sprintf("trending:%d:%d:%d", since.UnixNano(), until.UnixNano(), limit)
+	return r.remember(key, func() ([]ProductScore, error) {
+		return TrendingProducts(r.db, since, until, limit)
+	})
+}
+
+func (r *Recommender) AlsoLiked(productId int, limit int) ([]ProductScore, error) {
+	key := fmt.Sprintf("also-liked:%d:%d", productId, limit)
+	return r.remember(key, func() ([]ProductScore, error) {
+		return AlsoLiked(r.db, productId, limit)
+	})
+}
+
+func (r *Recommender) Suggest(userId int, limit int) ([]ProductScore, error) {
+	key := fmt.Sprintf("suggest:%d:%d", userId, limit)
+	return r.remember(key, func() ([]ProductScore, error) {
+		return SuggestProducts(r.db, userId, limit)
+	})
+}
+
+func (r *Recommender) Invalidate() {
+	r.generation.Add(1)
+}
+
+func (r *Recommender) remember(key string, query func() ([]ProductScore, error)) ([]ProductScore, error) {
+	key = fmt.Sprintf("%d:%s", r.generation.Load(), key)
+	if value, ok := r.cache.Get(key); ok {
+		var scores []ProductScore
+		if json.Unmarshal(value, &scores) == nil {
+			return scores, nil
+		}
+	}
+
+	value, err, _ := r.group.Do(key, func() (interface{}, error) {
+		scores, err := query()
+		if err != nil {
+			return nil, err
+		}
+		if encoded, err := json.Marshal(scores); err == nil && r.ttl > 0 {
+			r.cache.Set(key, encoded, r.ttl)
+		}
+		return scores, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]ProductScore), nil
+}
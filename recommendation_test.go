@@ -0,0 +1,136 @@
+package belajargolanggorm
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"belajar-golang-gorm/dbcache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func productIds(scores []ProductScore) []int {
+	var ids []int
+	for _, score := range scores {
+		ids = append(ids, score.ProductId)
+	}
+	return ids
+}
+
+func TestCreateRecommendationFixtures(t *testing.T) {
+	var users []User
+	var products []Product
+	for i := 290; i <= 293; i++ {
+		users = append(users, User{ID: i, Password: "123456", Name: Name{FirstName: "User " + strconv.Itoa(i)}})
+		products = append(products, Product{ID: i, Name: "Product " + strconv.Itoa(i), Price: 10000})
+	}
+
+	err := db.Create(&users).Error
+	assert.Nil(t, err)
+
+	err = db.Create(&products).Error
+	assert.Nil(t, err)
+
+	january := time.Date(2000, 1, 10, 0, 0, 0, 0, time.Local)
+	likes := []UserLikeProduct{
+		{UserId: 290, ProductId: 290, LikedAt: january},
+		{UserId: 290, ProductId: 291, LikedAt: january},
+		{UserId: 290, ProductId: 292, LikedAt: january},
+		{UserId: 291, ProductId: 290, LikedAt: january},
+		{UserId: 291, ProductId: 291, LikedAt: january},
+		{UserId: 292, ProductId: 290, LikedAt: january},
+		{UserId: 292, ProductId: 293, LikedAt: january},
+		{UserId: 293, ProductId: 291, LikedAt: january.AddDate(0, 1, 0)},
+	}
+	err = db.Create(&likes).Error
+	assert.Nil(t, err)
+
+	// liked_at perlu diatur manual, jadi pivot diisi langsung lalu like_count dihitung ulang
+	err = NewLikes(db).Recount()
+	assert.Nil(t, err)
+
+	var product Product
+	err = db.Take(&product, "id = ?", 290).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), product.LikeCount)
+}
+
+func TestTrendingProducts(t *testing.T) {
+	since := time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local)
+	scores, err := TrendingProducts(db, since, since.AddDate(0, 1, 0), 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{290, 291, 292}, productIds(scores))
+	assert.Equal(t, int64(3), scores[0].Score)
+	assert.Equal(t, int64(2), scores[1].Score)
+}
+
+func TestAlsoLiked(t *testing.T) {
+	scores, err := AlsoLiked(db, 290, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{291, 292, 293}, productIds(scores))
+	assert.Equal(t, int64(2), scores[0].Score)
+}
+
+func TestSuggestProducts(t *testing.T) {
+	scores, err := SuggestProducts(db, 291, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{292, 293}, productIds(scores))
+	assert.Equal(t, int64(2), scores[0].Score)
+}
+
+func TestRecommenderCache(t *testing.T) {
+	recommender := NewRecommender(db, time.Minute)
+	scores, err := recommender.AlsoLiked(292, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{290, 291}, productIds(scores))
+
+	err = NewLikes(db).Like(293, 292)
+	assert.Nil(t, err)
+
+	// masih dari cache
+	scores, err = recommender.AlsoLiked(292, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{290, 291}, productIds(scores))
+
+	recommender.Invalidate()
+	scores, err = recommender.AlsoLiked(292, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{291, 290}, productIds(scores))
+}
+
+func TestRecommenderSingleFlight(t *testing.T) {
+	recommender := NewRecommender(db, time.Minute)
+
+	var queries atomic.Int64
+	query := func() ([]ProductScore, error) {
+		queries.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return []ProductScore{{ProductId: 290, Score: 3}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scores, err := recommender.remember("trending", query)
+			assert.Nil(t, err)
+			assert.Equal(t, []int{290}, productIds(scores))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), queries.Load())
+
+	// key yang selalu berbeda tidak membuat cache tumbuh tanpa batas
+	for i := 0; i < dbcache.DefaultCapacity+100; i++ {
+		_, err := recommender.remember(fmt.Sprintf("trending:%d", i), func() ([]ProductScore, error) {
+			return nil, nil
+		})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, dbcache.DefaultCapacity, recommender.cache.Len())
+}
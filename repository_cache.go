@@ -0,0 +1,32 @@
+package belajargolanggorm
+
+import (
+	"belajar-golang-gorm/entitycache"
+
+	"gorm.io/gorm"
+)
+
+// CachedRepository membaca User, Product dan Wallet berdasarkan primary key lewat identity cache,
+// cukup dibuat sekali per *gorm.DB karena setiap store mendaftarkan callback invalidasi
+type CachedRepository struct {
+	Users    *entitycache.Store[User, int]
+	Products *entitycache.Store[Product, int]
+	Wallets  *entitycache.Store[Wallet, string]
+}
+
+func NewCachedRepository(db *gorm.DB, options entitycache.Options) (*CachedRepository, error) {
+	users, err := entitycache.New[User, int](db, options)
+	if err != nil {
+		return nil, err
+	}
+	products, err := entitycache.New[Product, int](db, options)
+	if err != nil {
+		return nil, err
+	}
+	wallets, err := entitycache.New[Wallet, string](db, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CachedRepository{Users: users, Products: products, Wallets: wallets}, nil
+}
@@ -0,0 +1,46 @@
+package belajargolanggorm
+
+import (
+	"context"
+	"testing"
+
+	"belajar-golang-gorm/entitycache"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCachedRepository(t *testing.T) {
+	ctx := context.Background()
+	repository, err := NewCachedRepository(db, entitycache.Options{})
+	assert.Nil(t, err)
+
+	user := User{ID: 48, Password: "Rahasia", Name: Name{FirstName: "Salman 48"}, Wallet: Wallet{ID: "48", UserId: 48, Balance: 1000}}
+	err = db.Create(&user).Error
+	assert.Nil(t, err)
+
+	found, err := repository.Users.GetByID(ctx, 48)
+	assert.Nil(t, err)
+	assert.Equal(t, "Salman 48", found.Name.FirstName)
+
+	found, err = repository.Users.GetByID(ctx, 48)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), repository.Users.Stats().Hits)
+
+	wallet, err := repository.Wallets.GetByID(ctx, "48")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1000), wallet.Balance)
+
+	// saldo berubah lewat CreditWallet, cache wallet ikut dibuang
+	_, err = CreditWallet(db, 48, 500)
+	assert.Nil(t, err)
+	wallet, err = repository.Wallets.GetByID(ctx, "48")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1500), wallet.Balance)
+
+	_, err = repository.Products.GetByID(ctx, 480)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	_, err = repository.Products.GetByID(ctx, 480)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	assert.Equal(t, uint64(1), repository.Products.Stats().NegativeHits)
+}
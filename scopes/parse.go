@@ -0,0 +1,160 @@
+package scopes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var identPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// Parse membangun scope dari ekspresi teks, dipakai oleh querydsl (?scope=...) dan CLI.
+//
+//	broke                     satu scope
+//	sultan(min=5000000)       scope dengan parameter
+//	broke|sultan              OR
+//	!broke                    NOT
+//	sultan,!(broke|empty)     AND, koma punya prioritas paling rendah
+func (r *Registry) Parse(model interface{}, expression string) (Scope, error) {
+	p := &parser{registry: r, model: model, input: expression}
+	scope, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return scope, nil
+}
+
+func Parse(model interface{}, expression string) (Scope, error) {
+	return Default.Parse(model, expression)
+}
+
+type parser struct {
+	registry *Registry
+	model    interface{}
+	input    string
+	pos      int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at position %d in %q", ErrInvalidArgs, fmt.Sprintf(format, args...), p.pos, p.input)
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) consume(c byte) bool {
+	p.skipSpaces()
+	if p.pos < len(p.input) && p.input[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseAnd() (Scope, error) {
+	var scopes []Scope
+	for {
+		scope, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+		if !p.consume(',') {
+			break
+		}
+	}
+	if len(scopes) == 1 {
+		return scopes[0], nil
+	}
+	return And(scopes...), nil
+}
+
+func (p *parser) parseOr() (Scope, error) {
+	var scopes []Scope
+	for {
+		scope, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+		if !p.consume('|') {
+			break
+		}
+	}
+	if len(scopes) == 1 {
+		return scopes[0], nil
+	}
+	return Or(scopes...), nil
+}
+
+func (p *parser) parseUnary() (Scope, error) {
+	if p.consume('!') {
+		scope, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(scope), nil
+	}
+
+	if p.consume('(') {
+		scope, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(')') {
+			return nil, p.errorf("missing )")
+		}
+		return scope, nil
+	}
+
+	name := p.ident()
+	if name == "" {
+		return nil, p.errorf("expected scope name")
+	}
+
+	args := Args{}
+	if p.consume('(') {
+		for !p.consume(')') {
+			if len(args) > 0 && !p.consume(',') {
+				return nil, p.errorf("expected , or )")
+			}
+			key := p.ident()
+			if key == "" || !p.consume('=') {
+				return nil, p.errorf("expected name=value")
+			}
+			args[key] = p.value()
+		}
+	}
+
+	return p.registry.Get(p.model, name, args)
+}
+
+func (p *parser) ident() string {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || p.pos > start && c >= '0' && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *parser) value() string {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(",)", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	return strings.TrimSpace(p.input[start:p.pos])
+}
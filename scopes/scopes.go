@@ -0,0 +1,222 @@
+package scopes
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnknownScope   = errors.New("unknown scope")
+	ErrDuplicateScope = errors.New("scope already registered")
+	ErrInvalidArgs    = errors.New("invalid scope arguments")
+)
+
+type Scope = func(db *gorm.DB) *gorm.DB
+
+type Param struct {
+	Name        string
+	Type        string // string, int, float atau bool, hanya untuk dokumentasi
+	Default     string
+	Description string
+}
+
+// Args berisi parameter scope yang sudah diisi default, nilainya masih string
+// karena bisa datang dari URL atau argumen CLI
+type Args map[string]string
+
+func (a Args) String(name string) string {
+	return a[name]
+}
+
+func (a Args) Int(name string) (int64, error) {
+	value, err := strconv.ParseInt(a[name], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be an integer", ErrInvalidArgs, name)
+	}
+	return value, nil
+}
+
+func (a Args) Float(name string) (float64, error) {
+	value, err := strconv.ParseFloat(a[name], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be a number", ErrInvalidArgs, name)
+	}
+	return value, nil
+}
+
+func (a Args) Bool(name string) (bool, error) {
+	value, err := strconv.ParseBool(a[name])
+	if err != nil {
+		return false, fmt.Errorf("%w: %s must be true or false", ErrInvalidArgs, name)
+	}
+	return value, nil
+}
+
+type Definition struct {
+	Name        string
+	Description string
+	Params      []Param
+	Build       func(args Args) (Scope, error)
+}
+
+// Static membungkus scope tanpa parameter seperti BrokeWalletBalance
+func Static(scope Scope) func(args Args) (Scope, error) {
+	return func(args Args) (Scope, error) {
+		return scope, nil
+	}
+}
+
+type Registry struct {
+	mu     sync.RWMutex
+	models map[reflect.Type]map[string]Definition
+}
+
+func NewRegistry() *Registry {
+	return &Registry{models: map[reflect.Type]map[string]Definition{}}
+}
+
+// Default dipakai oleh model di package utama dan oleh querydsl
+var Default = NewRegistry()
+
+func Register(model interface{}, definition Definition) error {
+	return Default.Register(model, definition)
+}
+
+func MustRegister(model interface{}, definition Definition) {
+	if err := Default.Register(model, definition); err != nil {
+		panic(err)
+	}
+}
+
+func modelType(model interface{}) reflect.Type {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t
+}
+
+func (r *Registry) Register(model interface{}, definition Definition) error {
+	if !identPattern.MatchString(definition.Name) || definition.Build == nil {
+		return fmt.Errorf("%w: scope needs a valid name and a Build function", ErrInvalidArgs)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := modelType(model)
+	if r.models[t] == nil {
+		r.models[t] = map[string]Definition{}
+	}
+	if _, ok := r.models[t][definition.Name]; ok {
+		return fmt.Errorf("%w: %s.%s", ErrDuplicateScope, t.Name(), definition.Name)
+	}
+	r.models[t][definition.Name] = definition
+	return nil
+}
+
+// Describe mengembalikan semua scope milik model, urut berdasarkan nama
+func (r *Registry) Describe(model interface{}) []Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var definitions []Definition
+	for _, definition := range r.models[modelType(model)] {
+		definitions = append(definitions, definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+	return definitions
+}
+
+func (r *Registry) lookup(model interface{}, name string) (Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	definition, ok := r.models[modelType(model)][name]
+	return definition, ok
+}
+
+// Get membangun scope berdasarkan nama, parameter yang tidak diisi memakai default
+func (r *Registry) Get(model interface{}, name string, args Args) (Scope, error) {
+	definition, ok := r.lookup(model, name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s.%s", ErrUnknownScope, modelType(model).Name(), name)
+	}
+
+	filled := Args{}
+	for _, param := range definition.Params {
+		filled[param.Name] = param.Default
+	}
+	for key, value := range args {
+		if !definition.hasParam(key) {
+			return nil, fmt.Errorf("%w: %s has no parameter %q", ErrInvalidArgs, name, key)
+		}
+		filled[key] = value
+	}
+
+	return definition.Build(filled)
+}
+
+func (d Definition) hasParam(name string) bool {
+	for _, param := range d.Params {
+		if param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// condition menjalankan scope di session baru supaya kondisi WHERE-nya bisa dikelompokkan
+func condition(db *gorm.DB, scope Scope) clause.Expression {
+	group := db.Session(&gorm.Session{NewDB: true}).Scopes(scope)
+	return clause.And(db.Statement.BuildCondition(group)...)
+}
+
+// And, Or dan Not hanya mengambil kondisi WHERE dari scope,
+// Order/Limit di dalam scope yang digabung akan diabaikan
+func And(scopes ...Scope) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(scopes...)
+	}
+}
+
+func Or(scopes ...Scope) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		var exprs []clause.Expression
+		for _, scope := range scopes {
+			expr := condition(db, scope)
+			if expr == nil {
+				// scope tanpa kondisi berarti semua baris, OR-nya pun semua baris
+				return db
+			}
+			exprs = append(exprs, expr)
+		}
+		switch len(exprs) {
+		case 0:
+			return db
+		case 1:
+			return db.Where(exprs[0])
+		}
+		return db.Where(clause.Or(exprs...))
+	}
+}
+
+func Not(scope Scope) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		expr := condition(db, scope)
+		if expr == nil {
+			return db.Where("1 = 0")
+		}
+		// clause.Not menegasikan tiap kondisi satu per satu, jadi NOT (...) ditulis sendiri
+		return db.Where(clause.Expr{SQL: "NOT (?)", Vars: []interface{}{expr}})
+	}
+}
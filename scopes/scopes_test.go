@@ -0,0 +1,130 @@
+package scopes_test
+
+import (
+	"errors"
+	"testing"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/internal/testdb"
+	"belajar-golang-gorm/scopes"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type Wallet = belajargolanggorm.Wallet
+
+func createWallets(t *testing.T, db *gorm.DB) {
+	wallets := []Wallet{
+		{ID: "w1", UserId: 1, Balance: 0},
+		{ID: "w2", UserId: 2, Balance: 500},
+		{ID: "w3", UserId: 3, Balance: 2000000},
+		{ID: "w4", UserId: 4, Balance: 7000000},
+	}
+	err := db.Create(&wallets).Error
+	assert.Nil(t, err)
+}
+
+func find(t *testing.T, db *gorm.DB, scope scopes.Scope) []string {
+	var wallets []Wallet
+	err := db.Scopes(scope).Order("id asc").Find(&wallets).Error
+	assert.Nil(t, err)
+
+	var ids []string
+	for _, wallet := range wallets {
+		ids = append(ids, wallet.ID)
+	}
+	return ids
+}
+
+func parse(t *testing.T, expression string) scopes.Scope {
+	scope, err := scopes.Parse(&Wallet{}, expression)
+	assert.Nil(t, err)
+	return scope
+}
+
+func TestWalletScopesRegistered(t *testing.T) {
+	definitions := scopes.Default.Describe(&Wallet{})
+	assert.Equal(t, 2, len(definitions))
+	assert.Equal(t, "broke", definitions[0].Name)
+	assert.Equal(t, "sultan", definitions[1].Name)
+	assert.Equal(t, "min", definitions[1].Params[0].Name)
+	assert.Equal(t, "1000000", definitions[1].Params[0].Default)
+
+	// model boleh dikirim sebagai pointer, value atau slice
+	assert.Equal(t, 2, len(scopes.Default.Describe([]Wallet{})))
+}
+
+func TestGetByName(t *testing.T) {
+	db := testdb.Open(t)
+	createWallets(t, db)
+
+	assert.Equal(t, []string{"w1"}, find(t, db, parse(t, "broke")))
+	assert.Equal(t, []string{"w3", "w4"}, find(t, db, parse(t, "sultan")))
+	assert.Equal(t, []string{"w4"}, find(t, db, parse(t, "sultan(min=5000000)")))
+}
+
+func TestCombinators(t *testing.T) {
+	db := testdb.Open(t)
+	createWallets(t, db)
+
+	broke := belajargolanggorm.BrokeWalletBalance
+	sultan := belajargolanggorm.SultanWalletBalance
+
+	assert.Equal(t, []string{"w1", "w3", "w4"}, find(t, db, scopes.Or(broke, sultan)))
+	assert.Equal(t, []string{"w2"}, find(t, db, scopes.Not(scopes.Or(broke, sultan))))
+	assert.Equal(t, []string{"w2", "w3", "w4"}, find(t, db, scopes.Not(broke)))
+	assert.Nil(t, find(t, db, scopes.And(broke, sultan)))
+
+	// OR harus dikelompokkan supaya tidak bocor ke kondisi lain
+	var wallets []Wallet
+	err := db.Where("user_id = ?", 2).Scopes(scopes.Or(broke, sultan)).Find(&wallets).Error
+	assert.Nil(t, err)
+	assert.Empty(t, wallets)
+}
+
+func TestParseExpression(t *testing.T) {
+	db := testdb.Open(t)
+	createWallets(t, db)
+
+	assert.Equal(t, []string{"w1", "w3", "w4"}, find(t, db, parse(t, "broke|sultan")))
+	assert.Equal(t, []string{"w2"}, find(t, db, parse(t, "!(broke|sultan)")))
+	assert.Equal(t, []string{"w3"}, find(t, db, parse(t, "sultan, !sultan(min=5000000)")))
+	assert.Equal(t, []string{"w1", "w4"}, find(t, db, parse(t, "broke|sultan(min=5000000)")))
+}
+
+func TestParseErrors(t *testing.T) {
+	invalid := map[string]error{
+		"kaya":               scopes.ErrUnknownScope,
+		"sultan(max=1)":      scopes.ErrInvalidArgs,
+		"sultan(min=banyak)": scopes.ErrInvalidArgs,
+		"broke|":             scopes.ErrInvalidArgs,
+		"(broke":             scopes.ErrInvalidArgs,
+		"broke; DROP TABLE":  scopes.ErrInvalidArgs,
+	}
+	for expression, expected := range invalid {
+		_, err := scopes.Parse(&Wallet{}, expression)
+		assert.True(t, errors.Is(err, expected), expression)
+	}
+
+	// scope terdaftar per model
+	_, err := scopes.Parse(&belajargolanggorm.User{}, "broke")
+	assert.True(t, errors.Is(err, scopes.ErrUnknownScope))
+}
+
+func TestRegister(t *testing.T) {
+	registry := scopes.NewRegistry()
+	definition := scopes.Definition{
+		Name:  "empty",
+		Build: scopes.Static(belajargolanggorm.BrokeWalletBalance),
+	}
+
+	err := registry.Register(&Wallet{}, definition)
+	assert.Nil(t, err)
+
+	err = registry.Register(Wallet{}, definition)
+	assert.True(t, errors.Is(err, scopes.ErrDuplicateScope))
+
+	err = registry.Register(&Wallet{}, scopes.Definition{Name: "tanpa build"})
+	assert.True(t, errors.Is(err, scopes.ErrInvalidArgs))
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+	snippetLength  = 160
+)
+
+// Highlight membungkus kata yang cocok dengan <mark>, teks yang panjang
+// dipotong di sekitar kata pertama yang cocok. Teks di-escape sehingga
+// hasilnya aman ditampilkan sebagai HTML
+func Highlight(text string, terms []string) string {
+	matches := map[string]bool{}
+	for _, term := range terms {
+		matches[strings.ToLower(term)] = true
+	}
+
+	text = snippet(text, matches)
+
+	var builder strings.Builder
+	for len(text) > 0 {
+		r, size := utf8.DecodeRuneInString(text)
+		if !isWordRune(r) {
+			builder.WriteString(html.EscapeString(text[:size]))
+			text = text[size:]
+			continue
+		}
+
+		end := wordEnd(text)
+		word := html.EscapeString(text[:end])
+		if matches[strings.ToLower(text[:end])] {
+			builder.WriteString(highlightStart)
+			builder.WriteString(word)
+			builder.WriteString(highlightEnd)
+		} else {
+			builder.WriteString(word)
+		}
+		text = text[end:]
+	}
+
+	return builder.String()
+}
+
+func wordEnd(text string) int {
+	end := 0
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(r) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+func snippet(text string, matches map[string]bool) string {
+	runes := []rune(text)
+	if len(runes) <= snippetLength {
+		return text
+	}
+
+	// cari posisi kata pertama yang cocok
+	first := 0
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		if matches[strings.ToLower(string(runes[i:j]))] {
+			first = i
+			break
+		}
+		i = j
+	}
+
+	start := first - snippetLength/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetLength
+	if end > len(runes) {
+		end = len(runes)
+		start = end - snippetLength
+	}
+
+	result := strings.TrimSpace(string(runes[start:end]))
+	if start > 0 {
+		result = "..." + result
+	}
+	if end < len(runes) {
+		result = result + "..."
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// parameter BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type docKey struct {
+	kind string
+	id   int
+}
+
+type document struct {
+	kind   string
+	id     int
+	title  string
+	text   string
+	length int
+}
+
+type scoredDocument struct {
+	document
+	score float64
+}
+
+// invertedIndex dipakai kalau database tidak punya FULLTEXT index (misalnya sqlite)
+type invertedIndex struct {
+	mutex       sync.RWMutex
+	postings    map[string]map[docKey]int
+	docs        map[docKey]document
+	totalLength int
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{
+		postings: map[string]map[docKey]int{},
+		docs:     map[docKey]document{},
+	}
+}
+
+// replaceKind mengganti semua dokumen satu jenis sekaligus, dipakai saat reindex tabel
+func (i *invertedIndex) replaceKind(kind string, docs []document) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for key := range i.docs {
+		if key.kind == kind {
+			i.remove0(key)
+		}
+	}
+	for _, doc := range docs {
+		i.add0(doc)
+	}
+}
+
+func (i *invertedIndex) add0(doc document) {
+	key := docKey{kind: doc.kind, id: doc.id}
+	i.remove0(key)
+
+	tokens := tokenize(doc.text)
+	doc.length = len(tokens)
+	i.docs[key] = doc
+	i.totalLength += doc.length
+
+	for _, token := range tokens {
+		posting, ok := i.postings[token]
+		if !ok {
+			posting = map[docKey]int{}
+			i.postings[token] = posting
+		}
+		posting[key]++
+	}
+}
+
+func (i *invertedIndex) remove0(key docKey) {
+	doc, ok := i.docs[key]
+	if !ok {
+		return
+	}
+
+	for _, token := range tokenize(doc.text) {
+		posting := i.postings[token]
+		delete(posting, key)
+		if len(posting) == 0 {
+			delete(i.postings, token)
+		}
+	}
+	i.totalLength -= doc.length
+	delete(i.docs, key)
+}
+
+func (i *invertedIndex) search(terms []string, kinds map[string]bool) []scoredDocument {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	if len(i.docs) == 0 {
+		return nil
+	}
+
+	total := float64(len(i.docs))
+	averageLength := float64(i.totalLength) / total
+	scores := map[docKey]float64{}
+
+	for _, term := range terms {
+		posting := i.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+
+		df := float64(len(posting))
+		idf := math.Log(1 + (total-df+0.5)/(df+0.5))
+		for key, tf := range posting {
+			if !kinds[key.kind] {
+				continue
+			}
+
+			length := float64(i.docs[key].length)
+			frequency := float64(tf)
+			scores[key] += idf * frequency * (bm25K1 + 1) / (frequency + bm25K1*(1-bm25B+bm25B*length/averageLength))
+		}
+	}
+
+	results := make([]scoredDocument, 0, len(scores))
+	for key, score := range scores {
+		results = append(results, scoredDocument{document: i.docs[key], score: score})
+	}
+
+	return results
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+// queryTerms sama dengan tokenize tapi tanpa duplikat
+func queryTerms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, token := range tokenize(query) {
+		if !seen[token] {
+			seen[token] = true
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,362 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	belajargolanggorm "belajar-golang-gorm"
+
+	"gorm.io/gorm"
+)
+
+const (
+	KindProduct   = "product"
+	KindUser      = "user"
+	KindGuestBook = "guest_book"
+)
+
+const defaultLimit = 20
+
+type Result struct {
+	Kind      string
+	ID        int
+	Title     string
+	Highlight string
+	Score     float64
+}
+
+type Options struct {
+	Kinds []string // kosong berarti semua jenis
+	Limit int
+}
+
+type source struct {
+	kind         string
+	model        interface{}
+	columns      []string // kolom yang dicari
+	titleColumns []string
+	scope        func(*gorm.DB) *gorm.DB // filter data yang boleh muncul di hasil pencarian
+}
+
+var sources = []source{
+	{
+		kind:         KindProduct,
+		model:        &belajargolanggorm.Product{},
+		columns:      []string{"name"},
+		titleColumns: []string{"name"},
+	},
+	{
+		kind:         KindUser,
+		model:        &belajargolanggorm.User{},
+		columns:      []string{"first_name", "middle_name", "last_name"},
+		titleColumns: []string{"first_name", "middle_name", "last_name"},
+	},
+	{
+		kind:         KindGuestBook,
+		model:        &belajargolanggorm.GuestBook{},
+		columns:      []string{"message"},
+		titleColumns: []string{"name"},
+		scope:        belajargolanggorm.ApprovedGuestBook,
+	},
+}
+
+// Searcher memakai FULLTEXT index kalau database-nya MySQL,
+// selain itu data dimuat ke inverted index di memory
+type Searcher struct {
+	db       *gorm.DB
+	fulltext bool
+	index    *invertedIndex
+	mutex    sync.Mutex
+	tables   map[string]string // nama tabel -> kind
+	dirty    map[string]bool
+}
+
+func New(db *gorm.DB) (*Searcher, error) {
+	s := &Searcher{
+		db:       db,
+		fulltext: db.Dialector.Name() == "mysql",
+		index:    newInvertedIndex(),
+		tables:   map[string]string{},
+		dirty:    map[string]bool{},
+	}
+
+	for _, src := range sources {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(src.model); err != nil {
+			return nil, err
+		}
+		s.tables[stmt.Schema.Table] = src.kind
+		s.dirty[src.kind] = true
+	}
+
+	if !s.fulltext {
+		if err := s.registerCallbacks(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Migrate membuat FULLTEXT index di MySQL, untuk database lain tidak melakukan apa-apa
+func (s *Searcher) Migrate() error {
+	if !s.fulltext {
+		return nil
+	}
+
+	for _, src := range sources {
+		stmt := &gorm.Statement{DB: s.db}
+		if err := stmt.Parse(src.model); err != nil {
+			return err
+		}
+
+		name := "idx_" + stmt.Schema.Table + "_fulltext"
+		if s.db.Migrator().HasIndex(src.model, name) {
+			continue
+		}
+
+		err := s.db.Exec(fmt.Sprintf("CREATE FULLTEXT INDEX %s ON %s (%s)", name, stmt.Schema.Table, strings.Join(src.columns, ", "))).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Rebuild memuat ulang seluruh inverted index dari database
+func (s *Searcher) Rebuild(ctx context.Context) error {
+	if s.fulltext {
+		return nil
+	}
+
+	s.mutex.Lock()
+	for _, src := range sources {
+		s.dirty[src.kind] = true
+	}
+	s.mutex.Unlock()
+
+	return s.refresh(ctx, sources)
+}
+
+func (s *Searcher) Search(ctx context.Context, query string, opts Options) ([]Result, error) {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	selected, err := selectSources(opts.Kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []scoredDocument
+	if s.fulltext {
+		for _, src := range selected {
+			docs, err := s.fulltextSearch(ctx, src, query, limit)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, docs...)
+		}
+	} else {
+		if err := s.refresh(ctx, selected); err != nil {
+			return nil, err
+		}
+
+		kinds := map[string]bool{}
+		for _, src := range selected {
+			kinds[src.kind] = true
+		}
+		results = s.index.search(terms, kinds)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		if results[i].kind != results[j].kind {
+			return results[i].kind < results[j].kind
+		}
+		return results[i].id < results[j].id
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	response := make([]Result, 0, len(results))
+	for _, result := range results {
+		response = append(response, Result{
+			Kind:      result.kind,
+			ID:        result.id,
+			Title:     result.title,
+			Highlight: Highlight(result.text, terms),
+			Score:     result.score,
+		})
+	}
+
+	return response, nil
+}
+
+func selectSources(kinds []string) ([]source, error) {
+	if len(kinds) == 0 {
+		return sources, nil
+	}
+
+	var selected []source
+	for _, kind := range kinds {
+		found := false
+		for _, src := range sources {
+			if src.kind == kind {
+				selected = append(selected, src)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("search: unknown kind %q", kind)
+		}
+	}
+	return selected, nil
+}
+
+func (s *Searcher) fulltextQuery(db *gorm.DB, src source, query string, limit int) *gorm.DB {
+	match := fmt.Sprintf("MATCH(%s) AGAINST(? IN NATURAL LANGUAGE MODE)", strings.Join(src.columns, ", "))
+	columns := append([]string{"id"}, uniqueColumns(src)...)
+
+	return db.Model(src.model).
+		Select(strings.Join(columns, ", ")+", "+match+" AS score", query).
+		Where(match, query).
+		Scopes(src.scopes()...).
+		Order("score desc").
+		Limit(limit)
+}
+
+func (s *Searcher) fulltextSearch(ctx context.Context, src source, query string, limit int) ([]scoredDocument, error) {
+	var rows []map[string]interface{}
+	err := s.fulltextQuery(s.db.WithContext(ctx), src, query, limit).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]scoredDocument, 0, len(rows))
+	for _, row := range rows {
+		score, _ := strconv.ParseFloat(toString(row["score"]), 64)
+		docs = append(docs, scoredDocument{document: toDocument(src, row), score: score})
+	}
+	return docs, nil
+}
+
+func (s *Searcher) refresh(ctx context.Context, selected []source) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, src := range selected {
+		if !s.dirty[src.kind] {
+			continue
+		}
+
+		var rows []map[string]interface{}
+		err := s.db.WithContext(ctx).Model(src.model).Select(append([]string{"id"}, uniqueColumns(src)...)).Scopes(src.scopes()...).Find(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		docs := make([]document, 0, len(rows))
+		for _, row := range rows {
+			docs = append(docs, toDocument(src, row))
+		}
+		s.index.replaceKind(src.kind, docs)
+		s.dirty[src.kind] = false
+	}
+
+	return nil
+}
+
+// setiap create/update/delete ke tabel yang diindex menandai index perlu dimuat ulang,
+// reindex baru dijalankan saat Search berikutnya
+func (s *Searcher) registerCallbacks() error {
+	markDirty := func(db *gorm.DB) {
+		if db.Error != nil || db.Statement.Table == "" {
+			return
+		}
+
+		kind, ok := s.tables[db.Statement.Table]
+		if !ok {
+			return
+		}
+
+		s.mutex.Lock()
+		s.dirty[kind] = true
+		s.mutex.Unlock()
+	}
+
+	callback := s.db.Callback()
+	if err := callback.Create().After("gorm:create").Register("search:mark_dirty", markDirty); err != nil {
+		return err
+	}
+	if err := callback.Update().After("gorm:update").Register("search:mark_dirty", markDirty); err != nil {
+		return err
+	}
+	return callback.Delete().After("gorm:delete").Register("search:mark_dirty", markDirty)
+}
+
+func (src source) scopes() []func(*gorm.DB) *gorm.DB {
+	if src.scope == nil {
+		return nil
+	}
+	return []func(*gorm.DB) *gorm.DB{src.scope}
+}
+
+func uniqueColumns(src source) []string {
+	seen := map[string]bool{}
+	var columns []string
+	for _, column := range append(src.titleColumns, src.columns...) {
+		if !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func toDocument(src source, row map[string]interface{}) document {
+	id, _ := strconv.Atoi(toString(row["id"]))
+	return document{
+		kind:  src.kind,
+		id:    id,
+		title: joinColumns(row, src.titleColumns),
+		text:  joinColumns(row, src.columns),
+	}
+}
+
+func joinColumns(row map[string]interface{}, columns []string) string {
+	var values []string
+	for _, column := range columns {
+		if value := toString(row[column]); value != "" {
+			values = append(values, value)
+		}
+	}
+	return strings.Join(values, " ")
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,153 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	belajargolanggorm "belajar-golang-gorm"
+	"belajar-golang-gorm/internal/testdb"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func createFixtures(t *testing.T, db *gorm.DB) {
+	products := []belajargolanggorm.Product{
+		{ID: 1, Name: "Kopi Arabika Gayo", Price: 50000},
+		{ID: 2, Name: "Kopi Robusta", Price: 30000},
+		{ID: 3, Name: "Teh Hijau", Price: 20000},
+	}
+	err := db.Create(&products).Error
+	assert.Nil(t, err)
+
+	user := belajargolanggorm.User{
+		ID:       1,
+		Password: "rahasia",
+		Name: belajargolanggorm.Name{
+			FirstName: "Budi",
+			LastName:  "Teh",
+		},
+	}
+	err = db.Create(&user).Error
+	assert.Nil(t, err)
+
+	guestBooks := []belajargolanggorm.GuestBook{
+		{
+			Name:    "Salman",
+			Email:   "salman@example.com",
+			Message: "Kopi di sini enak, kopi terbaik yang pernah saya minum",
+			Status:  belajargolanggorm.GuestBookApproved,
+		},
+		// belum dimoderasi, tidak boleh muncul di hasil pencarian
+		{
+			Name:    "Pending",
+			Email:   "pending@example.com",
+			Message: "Kopi luwak murah, hubungi saya",
+			Status:  belajargolanggorm.GuestBookPending,
+		},
+		{
+			Name:    "Rejected",
+			Email:   "rejected@example.com",
+			Message: "Kopi palsu",
+			Status:  belajargolanggorm.GuestBookRejected,
+		},
+	}
+	err = db.Create(&guestBooks).Error
+	assert.Nil(t, err)
+}
+
+func TestSearchInvertedIndex(t *testing.T) {
+	db := testdb.Open(t)
+	createFixtures(t, db)
+
+	searcher, err := New(db)
+	assert.Nil(t, err)
+
+	results, err := searcher.Search(context.Background(), "kopi", Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results))
+
+	// dokumen yang lebih pendek mendapat skor lebih tinggi
+	assert.Equal(t, KindProduct, results[0].Kind)
+	assert.Equal(t, 2, results[0].ID)
+	assert.Equal(t, "<mark>Kopi</mark> Robusta", results[0].Highlight)
+	assert.Equal(t, 1, results[1].ID)
+	assert.Equal(t, KindGuestBook, results[2].Kind)
+	assert.Equal(t, "Salman", results[2].Title)
+	assert.Equal(t, "<mark>Kopi</mark> di sini enak, <mark>kopi</mark> terbaik yang pernah saya minum", results[2].Highlight)
+
+	results, err = searcher.Search(context.Background(), "teh", Options{Kinds: []string{KindUser}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "Budi Teh", results[0].Title)
+
+	// guest book yang belum di-approve tidak ikut diindex
+	results, err = searcher.Search(context.Background(), "luwak palsu", Options{Kinds: []string{KindGuestBook}})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+
+	_, err = searcher.Search(context.Background(), "teh", Options{Kinds: []string{"todo"}})
+	assert.NotNil(t, err)
+}
+
+func TestSearchReindexAfterWrite(t *testing.T) {
+	db := testdb.Open(t)
+	createFixtures(t, db)
+
+	searcher, err := New(db)
+	assert.Nil(t, err)
+
+	results, err := searcher.Search(context.Background(), "luwak", Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+
+	err = db.Create(&belajargolanggorm.Product{ID: 4, Name: "Kopi Luwak", Price: 100000}).Error
+	assert.Nil(t, err)
+
+	results, err = searcher.Search(context.Background(), "luwak", Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, 4, results[0].ID)
+
+	err = db.Delete(&belajargolanggorm.Product{}, "id = ?", 4).Error
+	assert.Nil(t, err)
+
+	results, err = searcher.Search(context.Background(), "luwak", Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+}
+
+func TestFulltextQuery(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "root:@tcp(127.0.0.1:3306)/belajar_golang_gorm",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true})
+	assert.Nil(t, err)
+
+	searcher := &Searcher{db: db, fulltext: true}
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var rows []map[string]interface{}
+		return searcher.fulltextQuery(tx, sources[1], "budi", 5).Find(&rows)
+	})
+	assert.Equal(t, "SELECT id, first_name, middle_name, last_name, MATCH(first_name, middle_name, last_name) AGAINST('budi' IN NATURAL LANGUAGE MODE) AS score FROM `users` WHERE MATCH(first_name, middle_name, last_name) AGAINST('budi' IN NATURAL LANGUAGE MODE) ORDER BY score desc LIMIT 5", sql)
+
+	sql = db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var rows []map[string]interface{}
+		return searcher.fulltextQuery(tx, sources[2], "kopi", 5).Find(&rows)
+	})
+	assert.Equal(t, "SELECT id, name, message, MATCH(message) AGAINST('kopi' IN NATURAL LANGUAGE MODE) AS score FROM `guest_books` WHERE MATCH(message) AGAINST('kopi' IN NATURAL LANGUAGE MODE) AND status = 'approved' ORDER BY score desc LIMIT 5", sql)
+}
+
+func TestHighlight(t *testing.T) {
+	assert.Equal(t, "<mark>Kopi</mark> susu, kopinya <mark>enak</mark>", Highlight("Kopi susu, kopinya enak", []string{"kopi", "ENAK"}))
+	assert.Equal(t, "Teh hijau", Highlight("Teh hijau", []string{"kopi"}))
+	assert.Equal(t, "&lt;script&gt;alert(&#34;<mark>kopi</mark>&#34;)&lt;/script&gt; &amp; teh", Highlight(`<script>alert("kopi")</script> & teh`, []string{"kopi"}))
+
+	long := strings.Repeat("lorem ipsum ", 30) + "kopi " + strings.Repeat("dolor sit ", 30)
+	highlight := Highlight(long, []string{"kopi"})
+	assert.True(t, strings.HasPrefix(highlight, "..."))
+	assert.True(t, strings.HasSuffix(highlight, "..."))
+	assert.Contains(t, highlight, "<mark>kopi</mark>")
+}
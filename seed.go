@@ -0,0 +1,61 @@
+package belajargolanggorm
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Seed mengisi data contoh untuk development, aman dijalankan berulang kali
+// karena data yang sudah ada tidak ditimpa
+func Seed(db *gorm.DB) error {
+	users := []User{
+		{ID: 1001, Password: "rahasia", Name: Name{FirstName: "Eko", MiddleName: "Kurniawan", LastName: "Khannedy"}},
+		{ID: 1002, Password: "rahasia", Name: Name{FirstName: "Budi", LastName: "Nugraha"}},
+		{ID: 1003, Password: "rahasia", Name: Name{FirstName: "Joko", LastName: "Morro"}},
+	}
+	wallets := []Wallet{
+		{ID: "seed-1001", UserId: 1001, Balance: 1000000},
+		{ID: "seed-1002", UserId: 1002, Balance: 0},
+		{ID: "seed-1003", UserId: 1003, Balance: 5000000},
+	}
+	addresses := []Address{
+		{ID: 1001, UserId: 1001, Address: "Jalan Belum Ada"},
+		{ID: 1002, UserId: 1002, Address: "Jalan Sudah Ada"},
+	}
+	products := []Product{
+		{ID: 1001, Name: "Contoh Product 1", Price: 100000, Stock: 10},
+		{ID: 1002, Name: "Contoh Product 2", Price: 250000, Stock: 5},
+	}
+	todos := []Todo{
+		{Model: gorm.Model{ID: 1001}, UserId: 1001, Title: "Belajar GORM", Description: "Migration dan seed"},
+		{Model: gorm.Model{ID: 1002}, UserId: 1001, Title: "Belajar CLI", Description: "gormctl"},
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := seedRows(tx, users); err != nil {
+			return err
+		}
+		if err := seedRows(tx, wallets); err != nil {
+			return err
+		}
+		if err := seedRows(tx, addresses); err != nil {
+			return err
+		}
+		if err := seedRows(tx, products); err != nil {
+			return err
+		}
+		return seedRows(tx, todos)
+	})
+}
+
+// seedRows memakai FirstOrCreate berdasarkan primary key supaya hook
+// seperti Product.AfterCreate hanya jalan untuk data yang benar-benar baru
+func seedRows[T any](tx *gorm.DB, rows []T) error {
+	for i := range rows {
+		err := tx.Unscoped().Omit(clause.Associations).FirstOrCreate(&rows[i]).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
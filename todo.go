@@ -1,6 +1,8 @@
 package belajargolanggorm
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -10,3 +12,38 @@ type Todo struct {
 	Title       string `gorm:"column:title"`
 	Description string `gorm:"column:description"`
 }
+
+// TrashTodo melakukan soft delete, todo masih bisa dikembalikan dengan RestoreTodo
+func TrashTodo(db *gorm.DB, id uint) error {
+	result := db.Delete(&Todo{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func RestoreTodo(db *gorm.DB, id uint) error {
+	result := db.Unscoped().Model(&Todo{}).Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func TrashedTodos(db *gorm.DB) ([]Todo, error) {
+	var todos []Todo
+	err := db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at").Find(&todos).Error
+	return todos, err
+}
+
+// PurgeTodos menghapus permanen todo yang sudah di-trash sebelum waktu tertentu
+func PurgeTodos(db *gorm.DB, before time.Time) (int64, error) {
+	result := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&Todo{})
+	return result.RowsAffected, result.Error
+}
@@ -7,15 +7,17 @@ import (
 )
 
 type User struct {
-	ID           int       `gorm:"column:id;primaryKey;<-:create"`
-	Name         Name      `gorm:"embedded"`
-	Password     string    `gorm:"column:password"`
-	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime;<-:create"`
-	UpdatedAt    time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
-	Information  string    `gorm:"-"`
-	Wallet       Wallet    `gorm:"foreignKey:user_id;references:id"` //relasi one to one
-	Addresses    []Address `gorm:"foreignKey:user_id;references:id"` //relasi one to many
-	LikeProducts []Product `gorm:"many2many:user_like_product;foreignKey:id;joinForeignKey:user_id;references:id;joinReferences:product_id"`
+	ID              int        `gorm:"column:id;primaryKey;<-:create"`
+	Name            Name       `gorm:"embedded"`
+	Email           *string    `gorm:"column:email;size:100;uniqueIndex"`
+	EmailVerifiedAt *time.Time `gorm:"column:email_verified_at"`
+	Password        string     `gorm:"column:password"`
+	CreatedAt       time.Time  `gorm:"column:created_at;autoCreateTime;<-:create"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	Information     string     `gorm:"-"`
+	Wallet          Wallet     `gorm:"foreignKey:user_id;references:id"` //relasi one to one
+	Addresses       []Address  `gorm:"foreignKey:user_id;references:id"` //relasi one to many
+	LikeProducts    []Product  `gorm:"many2many:user_like_product;foreignKey:id;joinForeignKey:user_id;references:id;joinReferences:product_id"`
 }
 
 type Name struct {
@@ -29,5 +31,11 @@ func (u *User) BeforeCreate(db *gorm.DB) error {
 	if u.Name.FirstName == "" {
 		u.Name.FirstName = "Test hook - " + time.Now().Format("20250511090001")
 	}
-	return nil
+	return u.normalizeEmail()
+}
+
+// hook before update untuk Save dan Updates. Receiver bukan pointer supaya
+// db.Where(...).Updates(User{...}) tanpa Model tetap bisa dipakai
+func (u User) BeforeUpdate(db *gorm.DB) error {
+	return normalizeUpdatedEmail(db)
 }
@@ -0,0 +1,268 @@
+package belajargolanggorm
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const DefaultEmailVerificationTTL = 24 * time.Hour
+
+var (
+	ErrInvalidEmail            = errors.New("invalid email address")
+	ErrEmailNotSet             = errors.New("user has no email address")
+	ErrVerificationNotFound    = errors.New("email verification not found")
+	ErrVerificationExpired     = errors.New("email verification expired")
+	ErrVerificationAlreadyUsed = errors.New("email verification already used")
+)
+
+// NormalizeEmail memvalidasi format email dan mengubahnya ke huruf kecil,
+// format dengan nama seperti "Salman <salman@example.com>" tidak diterima
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email {
+		return "", ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	domain := email[at+1:]
+	if at < 1 || !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", ErrInvalidEmail
+	}
+
+	return strings.ToLower(email), nil
+}
+
+// dipanggil dari hook BeforeCreate, update lewat normalizeUpdatedEmail
+func (u *User) normalizeEmail() error {
+	if u.Email == nil {
+		return nil
+	}
+
+	email, err := NormalizeEmail(*u.Email)
+	if err != nil {
+		return err
+	}
+	u.Email = &email
+
+	return nil
+}
+
+// normalizeUpdatedEmail menormalisasi email yang diubah lewat Save, Updates atau Update,
+// nilainya ada di Dest yang bisa berbeda dengan model yang menerima hook
+func normalizeUpdatedEmail(tx *gorm.DB) error {
+	switch dest := tx.Statement.Dest.(type) {
+	case map[string]interface{}:
+		for key, value := range dest {
+			if key != "email" && key != "Email" {
+				continue
+			}
+			email, err := normalizeEmailValue(value)
+			if err != nil {
+				return err
+			}
+			dest[key] = email
+		}
+	case *User:
+		return dest.normalizeEmail()
+	case User:
+		if err := dest.normalizeEmail(); err != nil {
+			return err
+		}
+		tx.Statement.Dest = &dest
+	}
+	return nil
+}
+
+// nilai selain string dan *string (misalnya gorm.Expr) dibiarkan
+func normalizeEmailValue(value interface{}) (interface{}, error) {
+	switch email := value.(type) {
+	case string:
+		return NormalizeEmail(email)
+	case *string:
+		if email == nil {
+			return email, nil
+		}
+		normalized, err := NormalizeEmail(*email)
+		if err != nil {
+			return nil, err
+		}
+		return &normalized, nil
+	}
+	return value, nil
+}
+
+// ChangeUserEmail mengganti email user, status verifikasi ikut di-reset
+func ChangeUserEmail(db *gorm.DB, userId int, email string) error {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"email":             email,
+		"email_verified_at": nil,
+	}).Error
+}
+
+type EmailVerification struct {
+	ID          int        `gorm:"column:id;primary_key;autoIncrement"`
+	UserId      int        `gorm:"column:user_id;index"`
+	Email       string     `gorm:"column:email;size:100"`
+	Token       string     `gorm:"-"`                                // token asli, hanya terisi dari Issue
+	TokenHash   string     `gorm:"column:token;size:64;uniqueIndex"` // sha256 dari token
+	ExpiresAt   time.Time  `gorm:"column:expires_at"`
+	ConfirmedAt *time.Time `gorm:"column:confirmed_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime;<-:create"`
+}
+
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message MailMessage) error
+}
+
+// MemoryMailer hanya menyimpan email yang dikirim, dipakai untuk test
+type MemoryMailer struct {
+	mutex    sync.Mutex
+	messages []MailMessage
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, message MailMessage) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+func (m *MemoryMailer) Messages() []MailMessage {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]MailMessage(nil), m.messages...)
+}
+
+type EmailVerifier struct {
+	db     *gorm.DB
+	mailer Mailer
+	TTL    time.Duration
+}
+
+func NewEmailVerifier(db *gorm.DB, mailer Mailer) *EmailVerifier {
+	return &EmailVerifier{
+		db:     db,
+		mailer: mailer,
+		TTL:    DefaultEmailVerificationTTL,
+	}
+}
+
+// Issue membuat token verifikasi baru dan mengirimkannya ke email user
+func (v *EmailVerifier) Issue(ctx context.Context, userId int) (*EmailVerification, error) {
+	var user User
+	err := v.db.WithContext(ctx).Take(&user, "id = ?", userId).Error
+	if err != nil {
+		return nil, err
+	}
+	if user.Email == nil {
+		return nil, ErrEmailNotSet
+	}
+
+	token, err := verificationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	verification := EmailVerification{
+		UserId:    user.ID,
+		Email:     *user.Email,
+		Token:     token,
+		TokenHash: hashVerificationToken(token),
+		ExpiresAt: time.Now().Add(v.TTL),
+	}
+	err = v.db.WithContext(ctx).Create(&verification).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = v.mailer.Send(ctx, MailMessage{
+		To:      verification.Email,
+		Subject: "Verifikasi email",
+		Body:    fmt.Sprintf("Gunakan token berikut untuk verifikasi email kamu: %s\nToken berlaku sampai %s.", token, verification.ExpiresAt.Format(time.RFC1123)),
+	})
+	if err != nil {
+		// token yang tidak pernah terkirim tidak boleh tersisa di database
+		deleteErr := v.db.WithContext(context.WithoutCancel(ctx)).Delete(&verification).Error
+		return nil, errors.Join(err, deleteErr)
+	}
+
+	return &verification, nil
+}
+
+func (v *EmailVerifier) Confirm(ctx context.Context, token string) error {
+	return v.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var verification EmailVerification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&verification, "token = ?", hashVerificationToken(token)).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVerificationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if verification.ConfirmedAt != nil {
+			return ErrVerificationAlreadyUsed
+		}
+		now := time.Now()
+		if now.After(verification.ExpiresAt) {
+			return ErrVerificationExpired
+		}
+
+		// email sudah diganti setelah token dibuat, token lama tidak berlaku
+		result := tx.Model(&User{}).Where("id = ? AND email = ?", verification.UserId, verification.Email).
+			Update("email_verified_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVerificationExpired
+		}
+
+		return tx.Model(&verification).Update("confirmed_at", now).Error
+	})
+}
+
+// PurgeExpired menghapus token yang sudah kadaluarsa dan belum dipakai
+func (v *EmailVerifier) PurgeExpired(ctx context.Context) (int64, error) {
+	result := v.db.WithContext(ctx).Where("confirmed_at IS NULL AND expires_at < ?", time.Now()).Delete(&EmailVerification{})
+	return result.RowsAffected, result.Error
+}
+
+func verificationToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// hanya hash token yang disimpan, isi database saja tidak cukup untuk verifikasi email
+func hashVerificationToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
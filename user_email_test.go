@@ -0,0 +1,182 @@
+package belajargolanggorm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	email, err := NormalizeEmail("  Salman.Seif@Example.COM ")
+	assert.Nil(t, err)
+	assert.Equal(t, "salman.seif@example.com", email)
+
+	for _, invalid := range []string{"", "salman", "salman@", "@example.com", "salman@example", "Salman <salman@example.com>", "salman@example.com."} {
+		_, err = NormalizeEmail(invalid)
+		assert.Equal(t, ErrInvalidEmail, err, invalid)
+	}
+}
+
+func TestUserEmail(t *testing.T) {
+	email := "Salman.33@Example.com"
+	user := User{
+		ID:       33,
+		Password: "Rahasia",
+		Name:     Name{FirstName: "Salman 33"},
+		Email:    &email,
+	}
+	err := db.Create(&user).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "salman.33@example.com", *user.Email)
+
+	invalid := "bukan email"
+	err = db.Create(&User{ID: 34, Password: "Rahasia", Name: Name{FirstName: "Salman 34"}, Email: &invalid}).Error
+	assert.Equal(t, ErrInvalidEmail, err)
+
+	// email unik walaupun beda huruf besar kecil
+	duplicate := "SALMAN.33@example.com"
+	err = db.Create(&User{ID: 35, Password: "Rahasia", Name: Name{FirstName: "Salman 35"}, Email: &duplicate}).Error
+	assert.NotNil(t, err)
+
+	guestBook := GuestBook{Name: "Salman", Email: "Salman.33@Example.com", Message: "Halo dari test email"}
+	err = db.Create(&guestBook).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "salman.33@example.com", guestBook.Email)
+
+	err = db.Create(&GuestBook{Name: "Salman", Email: "salman@", Message: "Email salah"}).Error
+	assert.Equal(t, ErrInvalidEmail, err)
+}
+
+func TestEmailVerification(t *testing.T) {
+	mailer := &MemoryMailer{}
+	verifier := NewEmailVerifier(db, mailer)
+	ctx := context.Background()
+
+	verification, err := verifier.Issue(ctx, 33)
+	assert.Nil(t, err)
+	assert.Equal(t, "salman.33@example.com", verification.Email)
+
+	messages := mailer.Messages()
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, "salman.33@example.com", messages[0].To)
+	assert.True(t, strings.Contains(messages[0].Body, verification.Token))
+
+	err = verifier.Confirm(ctx, verification.Token)
+	assert.Nil(t, err)
+
+	err = verifier.Confirm(ctx, verification.Token)
+	assert.Equal(t, ErrVerificationAlreadyUsed, err)
+
+	err = verifier.Confirm(ctx, "token-salah")
+	assert.Equal(t, ErrVerificationNotFound, err)
+
+	var user User
+	err = db.Take(&user, "id = ?", 33).Error
+	assert.Nil(t, err)
+	assert.NotNil(t, user.EmailVerifiedAt)
+
+	_, err = verifier.Issue(ctx, 28)
+	assert.Equal(t, ErrEmailNotSet, err)
+
+	_, err = verifier.Issue(ctx, 404)
+	assert.NotNil(t, err)
+}
+
+func TestEmailVerificationExpired(t *testing.T) {
+	verifier := NewEmailVerifier(db, &MemoryMailer{})
+	ctx := context.Background()
+
+	verification, err := verifier.Issue(ctx, 33)
+	assert.Nil(t, err)
+
+	err = db.Model(verification).Update("expires_at", time.Now().Add(-time.Minute)).Error
+	assert.Nil(t, err)
+
+	err = verifier.Confirm(ctx, verification.Token)
+	assert.Equal(t, ErrVerificationExpired, err)
+
+	purged, err := verifier.PurgeExpired(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	// token dari email lama tidak berlaku setelah email diganti
+	verification, err = verifier.Issue(ctx, 33)
+	assert.Nil(t, err)
+
+	err = ChangeUserEmail(db, 33, "salman.baru@example.com")
+	assert.Nil(t, err)
+
+	err = verifier.Confirm(ctx, verification.Token)
+	assert.Equal(t, ErrVerificationExpired, err)
+
+	var user User
+	err = db.Take(&user, "id = ?", 33).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "salman.baru@example.com", *user.Email)
+	assert.Nil(t, user.EmailVerifiedAt)
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, message MailMessage) error {
+	return errors.New("smtp tidak bisa dihubungi")
+}
+
+func TestEmailVerificationTokenHash(t *testing.T) {
+	ctx := context.Background()
+
+	verification, err := NewEmailVerifier(db, &MemoryMailer{}).Issue(ctx, 33)
+	assert.Nil(t, err)
+
+	// database hanya menyimpan hash, token asli hanya ada di email
+	var saved EmailVerification
+	err = db.Take(&saved, "id = ?", verification.ID).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "", saved.Token)
+	assert.Equal(t, 64, len(saved.TokenHash))
+	assert.NotEqual(t, verification.Token, saved.TokenHash)
+
+	err = NewEmailVerifier(db, &MemoryMailer{}).Confirm(ctx, saved.TokenHash)
+	assert.Equal(t, ErrVerificationNotFound, err)
+
+	// token yang gagal dikirim dihapus lagi
+	var before, after int64
+	err = db.Model(&EmailVerification{}).Where("user_id = ?", 33).Count(&before).Error
+	assert.Nil(t, err)
+	_, err = NewEmailVerifier(db, failingMailer{}).Issue(ctx, 33)
+	assert.NotNil(t, err)
+	err = db.Model(&EmailVerification{}).Where("user_id = ?", 33).Count(&after).Error
+	assert.Nil(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestUserEmailNormalizedOnUpdate(t *testing.T) {
+	err := db.Model(&User{}).Where("id = ?", 33).Update("email", "Salman.Update@Example.com").Error
+	assert.Nil(t, err)
+	var user User
+	err = db.Take(&user, "id = ?", 33).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "salman.update@example.com", *user.Email)
+
+	email := "Salman.Updates@Example.com"
+	err = db.Model(&User{ID: 33}).Updates(User{Email: &email}).Error
+	assert.Nil(t, err)
+	err = db.Take(&user, "id = ?", 33).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "salman.updates@example.com", *user.Email)
+
+	email = "Salman.Save@Example.com"
+	user.Email = &email
+	err = db.Save(&user).Error
+	assert.Nil(t, err)
+	err = db.Take(&user, "id = ?", 33).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "salman.save@example.com", *user.Email)
+
+	err = db.Model(&User{}).Where("id = ?", 33).Updates(map[string]interface{}{"email": "bukan email"}).Error
+	assert.Equal(t, ErrInvalidEmail, err)
+}
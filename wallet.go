@@ -1,7 +1,20 @@
 package belajargolanggorm
 
 import (
+	"errors"
+	"strconv"
 	"time"
+
+	"belajar-golang-gorm/dbretry"
+	"belajar-golang-gorm/scopes"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidAmount = errors.New("amount must be greater than zero")
+	ErrSameWallet    = errors.New("cannot transfer to the same wallet")
 )
 
 type Wallet struct {
@@ -12,3 +25,133 @@ type Wallet struct {
 	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
 	User      *User     `gorm:"foreignKey:user_id;references:id"` //relasi one to one
 }
+
+const SultanBalance = 1000000
+
+func BrokeWalletBalance(db *gorm.DB) *gorm.DB {
+	return db.Where("balance = ?", 0)
+}
+
+func SultanWalletBalance(db *gorm.DB) *gorm.DB {
+	return db.Where("balance > ?", SultanBalance)
+}
+
+func init() {
+	scopes.MustRegister(&Wallet{}, scopes.Definition{
+		Name:        "broke",
+		Description: "Wallet dengan saldo 0",
+		Build:       scopes.Static(BrokeWalletBalance),
+	})
+	scopes.MustRegister(&Wallet{}, scopes.Definition{
+		Name:        "sultan",
+		Description: "Wallet dengan saldo di atas min",
+		Params: []scopes.Param{
+			{Name: "min", Type: "float", Default: strconv.Itoa(SultanBalance), Description: "batas bawah saldo (eksklusif)"},
+		},
+		Build: func(args scopes.Args) (scopes.Scope, error) {
+			min, err := args.Float("min")
+			if err != nil {
+				return nil, err
+			}
+			return func(db *gorm.DB) *gorm.DB {
+				return db.Where("balance > ?", min)
+			}, nil
+		},
+	})
+}
+
+func FindWallet(db *gorm.DB, userId int) (*Wallet, error) {
+	var wallet Wallet
+	err := db.Where("user_id = ?", userId).Limit(1).Find(&wallet).Error
+	if err != nil {
+		return nil, err
+	}
+	if wallet.ID == "" {
+		return nil, ErrWalletNotFound
+	}
+	return &wallet, nil
+}
+
+// CreditWallet menambah saldo wallet milik user
+func CreditWallet(db *gorm.DB, userId int, amount float64) (*Wallet, error) {
+	if !(amount > 0) {
+		return nil, ErrInvalidAmount
+	}
+	return changeBalance(db, userId, amount)
+}
+
+// DebitWallet mengurangi saldo, gagal dengan ErrInsufficientBalance kalau saldo kurang
+func DebitWallet(db *gorm.DB, userId int, amount float64) (*Wallet, error) {
+	if !(amount > 0) {
+		return nil, ErrInvalidAmount
+	}
+	return changeBalance(db, userId, -amount)
+}
+
+func changeBalance(db *gorm.DB, userId int, delta float64) (*Wallet, error) {
+	var wallet *Wallet
+	err := dbretry.Transaction(db, dbretry.DeadlockPolicy, func(tx *gorm.DB) error {
+		var err error
+		wallet, err = applyBalance(tx, userId, delta)
+		return err
+	})
+	return wallet, err
+}
+
+// applyBalance harus dipanggil di dalam transaction, update bersyarat
+// balance >= amount mencegah saldo minus walaupun ada request bersamaan
+func applyBalance(tx *gorm.DB, userId int, delta float64) (*Wallet, error) {
+	query := tx.Model(&Wallet{}).Where("user_id = ?", userId)
+	if delta < 0 {
+		query = query.Where("balance >= ?", -delta)
+	}
+	result := query.Update("balance", gorm.Expr("balance + ?", delta))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	wallet, err := FindWallet(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInsufficientBalance
+	}
+	return wallet, nil
+}
+
+// TransferBalance memindahkan saldo antar user dalam satu transaction,
+// wallet dikunci berurutan berdasarkan user_id supaya tidak deadlock,
+// kalau tetap kena deadlock atau lock wait timeout seluruh transaction diulang
+func TransferBalance(db *gorm.DB, fromUserId int, toUserId int, amount float64) (*Wallet, *Wallet, error) {
+	if !(amount > 0) {
+		return nil, nil, ErrInvalidAmount
+	}
+	if fromUserId == toUserId {
+		return nil, nil, ErrSameWallet
+	}
+
+	var from, to *Wallet
+	err := dbretry.Transaction(db, dbretry.DeadlockPolicy, func(tx *gorm.DB) error {
+		var wallets []Wallet
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id IN ?", []int{fromUserId, toUserId}).Order("user_id").Find(&wallets).Error
+		if err != nil {
+			return err
+		}
+		if len(wallets) != 2 {
+			return ErrWalletNotFound
+		}
+
+		from, err = applyBalance(tx, fromUserId, -amount)
+		if err != nil {
+			return err
+		}
+		to, err = applyBalance(tx, toUserId, amount)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
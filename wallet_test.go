@@ -0,0 +1,49 @@
+package belajargolanggorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalletCreditDebitTransfer(t *testing.T) {
+	users := []User{
+		{ID: 39, Password: "Rahasia", Name: Name{FirstName: "Salman 39"}, Wallet: Wallet{ID: "39", UserId: 39, Balance: 1000}},
+		{ID: 391, Password: "Rahasia", Name: Name{FirstName: "Salman 391"}, Wallet: Wallet{ID: "391", UserId: 391, Balance: 0}},
+	}
+	err := db.Create(&users).Error
+	assert.Nil(t, err)
+
+	wallet, err := CreditWallet(db, 39, 500)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1500), wallet.Balance)
+
+	wallet, err = DebitWallet(db, 39, 200)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1300), wallet.Balance)
+
+	_, err = DebitWallet(db, 391, 1)
+	assert.Equal(t, ErrInsufficientBalance, err)
+
+	_, err = CreditWallet(db, 39, -5)
+	assert.Equal(t, ErrInvalidAmount, err)
+
+	_, err = CreditWallet(db, 392, 5)
+	assert.Equal(t, ErrWalletNotFound, err)
+
+	from, to, err := TransferBalance(db, 39, 391, 300)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1000), from.Balance)
+	assert.Equal(t, float64(300), to.Balance)
+
+	_, _, err = TransferBalance(db, 391, 39, 301)
+	assert.Equal(t, ErrInsufficientBalance, err)
+
+	_, _, err = TransferBalance(db, 39, 39, 1)
+	assert.Equal(t, ErrSameWallet, err)
+
+	// transfer gagal tidak mengubah saldo
+	wallet, err = FindWallet(db, 391)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(300), wallet.Balance)
+}